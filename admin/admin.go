@@ -3,12 +3,37 @@ package admin
 import "fmt"
 
 const (
-	LoginPath     = "/admin/login"
-	DashboardPath = "/admin/dashboard"
+	LoginPath      = "/admin/login"
+	LogoutPath     = "/admin/logout"
+	DashboardPath  = "/admin/dashboard"
+	MediaPath      = "/admin/media"
+	SettingsPath   = "/admin/settings"
+	UsersPath      = "/admin/settings/users"
+	AuditPath      = "/admin/settings/audit"
+	BlueprintsPath = "/admin/settings/blueprints"
 
 	collectionPathTemplate = "/admin/collections/%s"
+	editPathTemplate       = "/admin/edit/%s/%v"
+	blueprintPathTemplate  = "/admin/settings/blueprints/edit/%s"
+
+	// Used as id in the edit path of singleton collections, whose item is looked up instead
+	SingletonID = "singleton"
 )
 
 func CollectionPath(collectionName string) string {
 	return fmt.Sprintf(collectionPathTemplate, collectionName)
 }
+
+func EditPath(collectionName string, id interface{}) string {
+	return fmt.Sprintf(editPathTemplate, collectionName, id)
+}
+
+// SingletonPath
+// returns the path of the edit page of the only item of a singleton collection
+func SingletonPath(collectionName string) string {
+	return EditPath(collectionName, SingletonID)
+}
+
+func BlueprintPath(collectionName string) string {
+	return fmt.Sprintf(blueprintPathTemplate, collectionName)
+}
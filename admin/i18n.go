@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rangidev/rangi/blueprint"
+	"golang.org/x/text/language"
+)
+
+const (
+	// Messages in templates are written in the default locale and used as keys for the catalogs
+	defaultLocale = "en"
+	// Catalog entry that contains the name of the language in the language itself
+	keyLanguageName     = "_name"
+	defaultLanguageName = "English"
+)
+
+type LocaleOption struct {
+	Locale string
+	Name   string
+}
+
+// Catalogs
+// contains the translated messages of the admin interface per locale.
+// Every file "<locale>.json" in the i18n directory maps English messages to their translation.
+type Catalogs struct {
+	locales  []string
+	messages map[string]map[string]string
+	matcher  language.Matcher
+}
+
+func loadCatalogs(fsys fs.FS) (*Catalogs, error) {
+	c := Catalogs{
+		locales:  []string{defaultLocale},
+		messages: map[string]map[string]string{defaultLocale: {}},
+	}
+	filenames, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("could not list catalogs: %v", err)
+	}
+	for _, filename := range filenames {
+		locale := strings.TrimSuffix(path.Base(filename), ".json")
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog locale %s: %v", locale, err)
+		}
+		data, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, fmt.Errorf("could not read catalog %s: %v", filename, err)
+		}
+		var messages map[string]string
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal catalog %s: %v", filename, err)
+		}
+		if !slices.Contains(c.locales, tag.String()) {
+			c.locales = append(c.locales, tag.String())
+		}
+		c.messages[tag.String()] = messages
+	}
+	tags := make([]language.Tag, 0, len(c.locales))
+	for _, locale := range c.locales {
+		tags = append(tags, language.Make(locale))
+	}
+	c.matcher = language.NewMatcher(tags)
+	return &c, nil
+}
+
+// Locales
+// returns all locales the admin interface is available in
+func (c *Catalogs) Locales() []string {
+	return c.locales
+}
+
+// Options
+// returns all locales together with the names of their languages, e. g. for a language selection
+func (c *Catalogs) Options() []LocaleOption {
+	options := make([]LocaleOption, 0, len(c.locales))
+	for _, locale := range c.locales {
+		name := c.messages[locale][keyLanguageName]
+		if name == "" {
+			name = locale
+		}
+		if locale == defaultLocale {
+			name = defaultLanguageName
+		}
+		options = append(options, LocaleOption{Locale: locale, Name: name})
+	}
+	return options
+}
+
+// IsAvailable
+// returns true if the admin interface is available in the locale
+func (c *Catalogs) IsAvailable(locale string) bool {
+	return slices.Contains(c.locales, locale)
+}
+
+// Locale
+// determines the locale of the admin interface for a request.
+// The locale chosen by the user in the settings takes precedence over the languages of the browser.
+func (c *Catalogs) Locale(r *http.Request) string {
+	if user := UserFromContext(r.Context()); user != nil && c.IsAvailable(user.Locale) {
+		return user.Locale
+	}
+	_, index := language.MatchStrings(c.matcher, r.Header.Get("Accept-Language"))
+	return c.locales[index]
+}
+
+// Translate
+// returns the message in the locale. If arguments are given, the message is used as format string.
+func (c *Catalogs) Translate(locale string, message string, args ...interface{}) string {
+	translated, ok := c.messages[locale][message]
+	if !ok || translated == "" {
+		translated = message
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(translated, args...)
+	}
+	return translated
+}
+
+// Translator
+// returns a translator for labels, see blueprint.Label.Localize
+func (c *Catalogs) Translator() blueprint.Translator {
+	return func(locale string, message string) string {
+		return c.Translate(locale, message)
+	}
+}
+
+// Messages
+// returns all translated messages of the locale, e. g. for use in scripts
+func (c *Catalogs) Messages(locale string) map[string]string {
+	return c.messages[locale]
+}
@@ -1,8 +1,15 @@
 package admin
 
 import (
+	"context"
 	"net/http"
+	"path"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/database"
 )
 
 const (
@@ -10,25 +17,87 @@ const (
 	SessionCookieDuration = 24 * time.Hour
 )
 
-func EnsurePermission(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: check permissions
-		loggedIn := false
-		sessionCookie, err := r.Cookie(SessionCookieName)
-		if err == nil {
-			if sessionCookie.Value == "true" {
-				loggedIn = true
+var (
+	// Paths that can only be accessed by users with the admin role
+	adminOnlyPathPrefixes = []string{
+		UsersPath,
+		AuditPath,
+		BlueprintsPath,
+	}
+	// Paths with path parameters that can only be accessed by users with the admin role, see path.Match
+	adminOnlyPathPatterns = []string{
+		"/admin/export",
+		"/admin/*/export",
+		"/admin/*/import",
+	}
+)
+
+type userContextKey struct{}
+
+// UserFromContext
+// returns the logged in user or nil
+func UserFromContext(ctx context.Context) *database.User {
+	user, _ := ctx.Value(userContextKey{}).(*database.User)
+	return user
+}
+
+// IsAdmin
+// returns true if the logged in user has the admin role
+func IsAdmin(ctx context.Context) bool {
+	user := UserFromContext(ctx)
+	return user != nil && user.Role == database.UserRoleAdmin
+}
+
+// EnsurePermission
+// looks up the user of the session, redirects to the login page if there is none, and checks the role of the user.
+// The user and the actor for the audit log are added to the request context.
+func EnsurePermission(db *database.DB, trustProxyHeaders bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var user *database.User
+			sessionCookie, err := r.Cookie(SessionCookieName)
+			if err == nil {
+				user, err = db.GetSessionUser(sessionCookie.Value)
+				if err != nil {
+					// Unknown or expired session
+					user = nil
+				}
+			}
+			loggedIn := user != nil
+			if !loggedIn && r.URL.Path != LoginPath {
+				// Not logged in and not trying to log in
+				http.Redirect(w, r, LoginPath, http.StatusFound)
+				return
+			} else if loggedIn && r.URL.Path == LoginPath {
+				// Logged in and trying to log in
+				http.Redirect(w, r, DashboardPath, http.StatusFound)
+				return
+			}
+			if isAdminOnlyPath(r.URL.Path) && user.Role != database.UserRoleAdmin {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
 			}
-		}
-		if !loggedIn && r.URL.Path != LoginPath {
-			// Not logged in and not trying to log in
-			http.Redirect(w, r, LoginPath, http.StatusFound)
-			return
-		} else if loggedIn && r.URL.Path == LoginPath {
-			// Logged in and trying to log in
-			http.Redirect(w, r, DashboardPath, http.StatusFound)
-			return
-		}
-		next.ServeHTTP(w, r)
+			actor := audit.Actor{IP: audit.ClientIP(r, trustProxyHeaders)}
+			ctx := r.Context()
+			if loggedIn {
+				actor.UserID = user.ID
+				actor.Email = user.Email
+				ctx = context.WithValue(ctx, userContextKey{}, user)
+			}
+			ctx = audit.WithActor(ctx, actor)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isAdminOnlyPath
+// returns true if only users with the admin role may access the path
+func isAdminOnlyPath(p string) bool {
+	if slices.ContainsFunc(adminOnlyPathPrefixes, func(prefix string) bool { return strings.HasPrefix(p, prefix) }) {
+		return true
+	}
+	return slices.ContainsFunc(adminOnlyPathPatterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, p)
+		return matched
 	})
 }
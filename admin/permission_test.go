@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rangidev/rangi/database"
+)
+
+func TestEnsurePermissionAdminOnlyPaths(t *testing.T) {
+	db, err := database.NewSqlite3Instance(filepath.Join(t.TempDir(), "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	err = db.CreateUserTable()
+	if err != nil {
+		t.Fatalf("could not create user table: %v", err)
+	}
+	tokens := map[database.UserRole]string{}
+	for _, role := range database.AllUserRoles {
+		user := &database.User{Email: string(role) + "@example.com", PasswordHash: "-", Role: role}
+		err = db.CreateUser(user)
+		if err != nil {
+			t.Fatalf("could not create user: %v", err)
+		}
+		tokens[role] = "token-" + string(role)
+		err = db.CreateSession(tokens[role], user.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("could not create session: %v", err)
+		}
+	}
+	handler := EnsurePermission(db, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		method    string
+		path      string
+		adminOnly bool
+	}{
+		{http.MethodGet, "/admin/articles", false},
+		{http.MethodGet, "/admin/articles/new", false},
+		{http.MethodGet, "/admin/articles/export", true},
+		{http.MethodGet, "/admin/export", true},
+		{http.MethodGet, "/admin/articles/import", true},
+		{http.MethodPost, "/admin/articles/import", true},
+		{http.MethodGet, UsersPath, true},
+		{http.MethodGet, AuditPath, true},
+	}
+	for _, test := range tests {
+		for role, token := range tokens {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			want := http.StatusOK
+			if test.adminOnly && role != database.UserRoleAdmin {
+				want = http.StatusForbidden
+			}
+			if recorder.Code != want {
+				t.Errorf("%s %s as %s: got status %d, want %d", test.method, test.path, role, recorder.Code, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Blueprint editor
+const fields = document.getElementById("blueprint-fields");
+const fieldTemplate = document.getElementById("blueprint-field-template");
+
+// Form values are sent as "fields.<index>.<key>", so indices must follow the order of the fields
+function renumberFields() {
+    fields.querySelectorAll(".rangi-blueprint-field").forEach((field, index) => {
+        field.querySelectorAll("[name^='fields.']").forEach((input) => {
+            input.name = input.name.replace(/^fields\.\d+\./, `fields.${index}.`);
+        });
+    });
+}
+
+// Reference and slug settings are only shown for fields of the matching type
+function updateField(field) {
+    const type = field.querySelector(".rangi-blueprint-type").value;
+    field.querySelector(".rangi-blueprint-reference").classList.toggle("d-none", type !== "reference");
+    field.querySelector(".rangi-blueprint-slug").classList.toggle("d-none", type !== "slug");
+    field.querySelector(".rangi-blueprint-component").classList.toggle("d-none", type !== "object" && type !== "array");
+    field.querySelector(".rangi-blueprint-zone").classList.toggle("d-none", type !== "array");
+}
+
+document.getElementById("blueprint-add-field").addEventListener("click", () => {
+    fields.appendChild(fieldTemplate.content.cloneNode(true));
+    const field = fields.lastElementChild;
+    updateField(field);
+    renumberFields();
+    field.querySelector("input").focus();
+});
+
+fields.addEventListener("change", (event) => {
+    if (event.target.classList.contains("rangi-blueprint-type")) {
+        updateField(event.target.closest(".rangi-blueprint-field"));
+    }
+});
+
+fields.addEventListener("click", (event) => {
+    const field = event.target.closest(".rangi-blueprint-field");
+    if (!field) {
+        return;
+    }
+    if (event.target.classList.contains("rangi-blueprint-remove")) {
+        field.remove();
+    } else if (event.target.classList.contains("rangi-blueprint-up") && field.previousElementSibling) {
+        field.previousElementSibling.before(field);
+    } else if (event.target.classList.contains("rangi-blueprint-down") && field.nextElementSibling) {
+        field.nextElementSibling.after(field);
+    } else {
+        return;
+    }
+    renumberFields();
+});
+
+fields.querySelectorAll(".rangi-blueprint-field").forEach(updateField);
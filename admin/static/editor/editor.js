@@ -34,4 +34,475 @@ class RangiReference extends HTMLButtonElement {
         this.classList.add("btn", "btn-lg", "btn-primary");
     }
 }
-customElements.define("rangi-reference", RangiReference, { extends: "button" });
\ No newline at end of file
+customElements.define("rangi-reference", RangiReference, { extends: "button" });
+// Markdown
+class RangiMarkdown extends HTMLElement {
+    constructor() {
+        self = super();
+    }
+
+    connectedCallback() {
+        const source = this.textContent;
+        this.innerHTML = "";
+        this.classList.add("row", "g-2");
+        // Editor
+        const editor = document.createElement("textarea");
+        editor.name = this.getAttribute("name");
+        editor.value = source;
+        editor.rows = 15;
+        editor.classList.add("form-control", "font-monospace");
+        const editorColumn = document.createElement("div");
+        editorColumn.classList.add("col-md-6");
+        editorColumn.appendChild(editor);
+        // Preview
+        const preview = document.createElement("div");
+        preview.classList.add("border", "rounded", "p-2", "h-100", "overflow-auto");
+        const previewColumn = document.createElement("div");
+        previewColumn.classList.add("col-md-6");
+        previewColumn.appendChild(preview);
+        this.append(editorColumn, previewColumn);
+        // Render preview on the server, so that it matches the stored HTML
+        let timeout;
+        const render = () => {
+            const body = new FormData();
+            body.append("markdown", editor.value);
+            fetch("/admin/markdown/preview", { method: "POST", body: body })
+                .then((response) => response.text())
+                .then((html) => { preview.innerHTML = html; });
+        };
+        editor.addEventListener("input", () => {
+            clearTimeout(timeout);
+            timeout = setTimeout(render, 300);
+        });
+        render();
+    }
+}
+customElements.define("rangi-markdown", RangiMarkdown);
+
+// Slug
+class RangiSlug extends HTMLElement {
+    constructor() {
+        self = super();
+    }
+
+    connectedCallback() {
+        const input = document.createElement("input");
+        input.type = "text";
+        input.name = this.getAttribute("name");
+        input.value = this.getAttribute("value");
+        input.readOnly = this.hasAttribute("readonly");
+        input.placeholder = rangiT("Generated from %s if empty").replace("%s", this.getAttribute("source"));
+        input.classList.add("form-control");
+        this.appendChild(input);
+    }
+}
+customElements.define("rangi-slug", RangiSlug);
+
+// Asset
+class RangiAsset extends HTMLElement {
+    constructor() {
+        self = super();
+    }
+
+    connectedCallback() {
+        const input = document.createElement("input");
+        input.type = "hidden";
+        input.name = this.getAttribute("name");
+        input.value = this.getAttribute("value");
+        const preview = document.createElement("div");
+        preview.classList.add("mb-2");
+        const choose = document.createElement("button");
+        choose.type = "button";
+        choose.innerHTML = rangiT("Choose asset");
+        choose.classList.add("btn", "btn-outline-primary", "me-2");
+        const remove = document.createElement("button");
+        remove.type = "button";
+        remove.innerHTML = rangiT("Remove");
+        remove.classList.add("btn", "btn-outline-danger");
+        const library = document.createElement("div");
+        library.classList.add("row", "mt-2");
+        this.append(input, preview, choose, remove, library);
+        const showPreview = () => {
+            preview.innerHTML = "";
+            if (input.value) {
+                const link = document.createElement("a");
+                link.href = "/assets/" + input.value;
+                link.innerHTML = `<img src="/assets/${input.value}" class="img-thumbnail" style="max-height: 150px" alt="">`;
+                preview.appendChild(link);
+            }
+        };
+        choose.addEventListener("click", () => {
+            fetch("/admin/assets?limit=50&offset=0")
+                .then((response) => response.text())
+                .then((html) => { library.innerHTML = html; });
+        });
+        remove.addEventListener("click", () => {
+            input.value = "";
+            showPreview();
+        });
+        // Pick an asset from the media library
+        library.addEventListener("click", (event) => {
+            const card = event.target.closest("[data-asset-uuid]");
+            if (!card) {
+                return;
+            }
+            event.preventDefault();
+            input.value = card.dataset.assetUuid;
+            library.innerHTML = "";
+            showPreview();
+        });
+        showPreview();
+    }
+}
+customElements.define("rangi-asset", RangiAsset);
+
+// Component
+// edits the JSON value of a field that embeds a component (one group of fields or a list of groups) or of a dynamic zone
+class RangiComponent extends HTMLElement {
+    constructor() {
+        self = super();
+    }
+
+    connectedCallback() {
+        let value = null;
+        try {
+            value = JSON.parse(this.getAttribute("value"));
+        } catch (error) {
+            // Empty or invalid values start with an empty component
+        }
+        this.input = document.createElement("input");
+        this.input.type = "hidden";
+        this.input.name = this.getAttribute("name");
+        this.classList.add("d-block", "border", "rounded", "p-3");
+        this.appendChild(this.input);
+        if (this.hasAttribute("components")) {
+            this.root = rangiZoneControl(JSON.parse(this.getAttribute("components")), value);
+        } else {
+            this.root = rangiComponentControl(JSON.parse(this.getAttribute("fields")), this.hasAttribute("repeatable"), value);
+        }
+        this.appendChild(this.root.element);
+        const update = () => { this.input.value = JSON.stringify(this.root.value()); };
+        this.addEventListener("input", update);
+        this.addEventListener("change", update);
+        update();
+        // Asset inputs are changed without events, so the value is collected again when the form is sent
+        document.body.addEventListener("htmx:configRequest", (event) => {
+            if (this.isConnected && event.detail.elt.contains(this)) {
+                event.detail.parameters[this.input.name] = JSON.stringify(this.root.value());
+            }
+        });
+    }
+}
+customElements.define("rangi-component", RangiComponent);
+
+// rangiLabel
+// resolves a blueprint label for the language of the admin interface
+function rangiLabel(label) {
+    if (typeof label === "string") {
+        return label;
+    }
+    const lang = document.documentElement.lang;
+    for (const locale of [lang, lang.split("-")[0], "", "en"]) {
+        if (label && label[locale]) {
+            return label[locale];
+        }
+    }
+    return label ? Object.values(label)[0] || "" : "";
+}
+
+// rangiComponentControl
+// returns the element for a component value and a function that returns the edited value
+function rangiComponentControl(fields, repeatable, value) {
+    if (!repeatable) {
+        return rangiComponentGroup(fields, value);
+    }
+    const list = rangiComponentList((entryValue) => rangiComponentGroup(fields, entryValue), value);
+    const add = document.createElement("button");
+    add.type = "button";
+    add.innerHTML = rangiT("Add entry");
+    add.classList.add("btn", "btn-sm", "btn-outline-primary");
+    add.addEventListener("click", () => list.add(null));
+    list.element.appendChild(add);
+    return list;
+}
+
+// rangiZoneControl
+// returns the element for the value of a dynamic zone, a list of entries that are each one of the components.
+// Every entry stores the name of its component, so that frontends know how to render it.
+function rangiZoneControl(components, value) {
+    const createEntry = (entryValue) => {
+        const component = components.find((c) => c.name === entryValue?._component);
+        const heading = document.createElement("div");
+        heading.classList.add("fw-semibold", "mb-2");
+        if (!component) {
+            // Kept unchanged, so that the server reports the unknown component
+            heading.textContent = rangiT("Unknown component %s").replace("%s", entryValue?._component);
+            return { element: heading, value: () => entryValue };
+        }
+        heading.textContent = rangiLabel(component.display_name);
+        const group = rangiComponentGroup(component.fields, entryValue);
+        const element = document.createElement("div");
+        element.append(heading, group.element);
+        return { element: element, value: () => ({ _component: component.name, ...group.value() }) };
+    };
+    const list = rangiComponentList(createEntry, value);
+    const add = document.createElement("div");
+    add.classList.add("input-group", "input-group-sm", "w-auto", "d-inline-flex");
+    const select = document.createElement("select");
+    select.classList.add("form-select");
+    for (const component of components) {
+        select.add(new Option(rangiLabel(component.display_name), component.name));
+    }
+    const button = document.createElement("button");
+    button.type = "button";
+    button.innerHTML = rangiT("Add entry");
+    button.classList.add("btn", "btn-outline-primary");
+    button.addEventListener("click", () => list.add({ _component: select.value }));
+    add.append(select, button);
+    list.element.appendChild(add);
+    return list;
+}
+
+// rangiComponentList
+// returns a list of entries that can be reordered by drag and drop or with buttons and removed.
+// createEntry returns the element and the value function of an entry.
+function rangiComponentList(createEntry, value) {
+    const element = document.createElement("div");
+    const list = document.createElement("div");
+    element.appendChild(list);
+    const entries = [];
+    let dragged = null;
+    // Entries are changed without input events, so the value of the field has to be updated
+    const changed = () => element.dispatchEvent(new Event("change", { bubbles: true }));
+    const move = (entry, target) => {
+        if (target < 0 || target >= entries.length) {
+            return;
+        }
+        entries.splice(entries.indexOf(entry), 1);
+        entries.splice(target, 0, entry);
+        entries.forEach((e) => list.appendChild(e.card));
+        changed();
+    };
+    const add = (entryValue) => {
+        const entry = createEntry(entryValue);
+        const card = document.createElement("div");
+        card.classList.add("border", "rounded", "p-2", "mb-2", "bg-body");
+        entry.card = card;
+        const buttons = document.createElement("div");
+        buttons.classList.add("btn-group", "btn-group-sm", "mb-2");
+        const button = (html, title, style, onClick) => {
+            const b = document.createElement("button");
+            b.type = "button";
+            b.innerHTML = html;
+            b.title = title;
+            b.classList.add("btn", style);
+            b.addEventListener("click", onClick);
+            buttons.appendChild(b);
+            return b;
+        };
+        // Only the handle starts dragging, so that text in the inputs can still be selected
+        const handle = button("&equiv;", rangiT("Drag to reorder"), "btn-outline-secondary", () => {});
+        handle.style.cursor = "move";
+        handle.addEventListener("mousedown", () => { card.draggable = true; });
+        handle.addEventListener("mouseup", () => { card.draggable = false; });
+        button("&uarr;", rangiT("Move up"), "btn-outline-secondary", () => move(entry, entries.indexOf(entry) - 1));
+        button("&darr;", rangiT("Move down"), "btn-outline-secondary", () => move(entry, entries.indexOf(entry) + 1));
+        button(rangiT("Remove"), "", "btn-outline-danger", () => {
+            entries.splice(entries.indexOf(entry), 1);
+            card.remove();
+            changed();
+        });
+        card.addEventListener("dragstart", (event) => {
+            event.stopPropagation();
+            dragged = entry;
+            event.dataTransfer.effectAllowed = "move";
+            event.dataTransfer.setData("text/plain", "");
+        });
+        card.addEventListener("dragend", () => {
+            dragged = null;
+            card.draggable = false;
+        });
+        // Cards of nested lists ignore entries of other lists, the event reaches the card of this list
+        card.addEventListener("dragover", (event) => {
+            if (dragged && dragged !== entry) {
+                event.preventDefault();
+                event.stopPropagation();
+            }
+        });
+        card.addEventListener("drop", (event) => {
+            if (dragged && dragged !== entry) {
+                event.preventDefault();
+                event.stopPropagation();
+                move(dragged, entries.indexOf(entry));
+            }
+        });
+        card.append(buttons, entry.element);
+        list.appendChild(card);
+        entries.push(entry);
+        changed();
+    };
+    (Array.isArray(value) ? value : []).forEach(add);
+    return { element: element, add: add, value: () => entries.map((e) => e.value()) };
+}
+
+// rangiComponentGroup
+// returns the controls for the fields of one component value
+function rangiComponentGroup(fields, value) {
+    value = value && typeof value === "object" && !Array.isArray(value) ? value : {};
+    const element = document.createElement("div");
+    const getters = {};
+    for (const field of fields) {
+        const fieldValue = value[field.name] ?? null;
+        const wrapper = document.createElement("div");
+        wrapper.classList.add("mb-2");
+        const label = document.createElement("label");
+        label.classList.add("form-label");
+        label.textContent = rangiLabel(field.display_name) + (field.required ? " *" : "");
+        wrapper.appendChild(label);
+        if (field.components || field.fields) {
+            const nested = field.components
+                ? rangiZoneControl(field.components, fieldValue)
+                : rangiComponentControl(field.fields, field.repeatable, fieldValue);
+            nested.element.classList.add("border", "rounded", "p-2");
+            wrapper.appendChild(nested.element);
+            getters[field.name] = nested.value;
+        } else {
+            getters[field.name] = rangiComponentInput(field, fieldValue, wrapper);
+        }
+        element.appendChild(wrapper);
+    }
+    return {
+        element: element,
+        value: () => {
+            const result = {};
+            for (const field of fields) {
+                result[field.name] = getters[field.name]();
+            }
+            return result;
+        },
+    };
+}
+
+// rangiComponentInput
+// adds the input for a single value to the wrapper and returns a function that returns the value
+function rangiComponentInput(field, value, wrapper) {
+    switch (field.type) {
+    case "boolean": {
+        const input = document.createElement("input");
+        input.type = "checkbox";
+        input.checked = value === true;
+        input.classList.add("form-check-input", "ms-2");
+        wrapper.appendChild(input);
+        return () => input.checked;
+    }
+    case "int": {
+        const input = document.createElement("input");
+        input.type = "number";
+        input.step = "1";
+        input.value = value ?? "";
+        input.classList.add("form-control");
+        wrapper.appendChild(input);
+        return () => (input.value === "" ? null : Number(input.value));
+    }
+    case "asset": {
+        // Without a name the hidden input of the asset is not sent with the form
+        const asset = document.createElement("rangi-asset");
+        asset.setAttribute("name", "");
+        asset.setAttribute("value", value ?? "");
+        wrapper.appendChild(asset);
+        return () => asset.querySelector("input")?.value ?? "";
+    }
+    case "array":
+    case "object": {
+        const input = document.createElement("textarea");
+        input.value = value === null ? "" : JSON.stringify(value, null, 2);
+        input.rows = 4;
+        input.classList.add("form-control", "font-monospace");
+        wrapper.appendChild(input);
+        return () => {
+            if (input.value.trim() === "") {
+                return null;
+            }
+            try {
+                return JSON.parse(input.value);
+            } catch (error) {
+                // Sent as text, so that the server reports the invalid value
+                return input.value;
+            }
+        };
+    }
+    default: {
+        const input = document.createElement("input");
+        input.type = "text";
+        input.value = value ?? "";
+        input.classList.add("form-control");
+        wrapper.appendChild(input);
+        return () => input.value;
+    }
+    }
+}
+
+// Conditions
+// show, hide and mark fields as required depending on the values of other fields.
+// The rules are the same as on the server, where they are checked again when the item is written.
+function rangiFieldValue(form, name) {
+    const control = form.elements.namedItem(name);
+    if (control instanceof RadioNodeList) {
+        return control.value;
+    }
+    if (control) {
+        return control.type === "checkbox" ? String(control.checked) : control.value;
+    }
+    // Fields that are shown without an input
+    const element = document.getElementById(name);
+    return element ? element.textContent : "";
+}
+
+function rangiIsEmpty(value) {
+    const trimmed = String(value ?? "").trim();
+    return trimmed === "" || trimmed === "[]" || trimmed === "{}";
+}
+
+function rangiConditionText(value) {
+    return value === null || value === undefined ? "" : String(value);
+}
+
+function rangiMatches(conditions, form) {
+    return conditions.every((condition) => {
+        const value = rangiConditionText(rangiFieldValue(form, condition.field));
+        switch (condition.operator) {
+        case "equals":
+            return value === rangiConditionText(condition.value);
+        case "not_equals":
+            return value !== rangiConditionText(condition.value);
+        case "in":
+            return (condition.value || []).some((v) => rangiConditionText(v) === value);
+        case "not_in":
+            return !(condition.value || []).some((v) => rangiConditionText(v) === value);
+        case "empty":
+            return rangiIsEmpty(value);
+        case "not_empty":
+            return !rangiIsEmpty(value);
+        }
+        return false;
+    });
+}
+
+function rangiApplyConditions(form) {
+    for (const wrapper of form.querySelectorAll("[data-rangi-visible-if]")) {
+        const visibleIf = JSON.parse(wrapper.dataset.rangiVisibleIf) || [];
+        const requiredIf = JSON.parse(wrapper.dataset.rangiRequiredIf);
+        const visible = rangiMatches(visibleIf, form);
+        const required = visible && (wrapper.dataset.rangiRequired === "true" || (requiredIf !== null && rangiMatches(requiredIf, form)));
+        wrapper.classList.toggle("d-none", !visible);
+        wrapper.querySelector(".rangi-required-marker")?.classList.toggle("d-none", !required);
+    }
+}
+
+for (const form of document.querySelectorAll("form")) {
+    if (form.querySelector("[data-rangi-visible-if]")) {
+        form.addEventListener("input", () => rangiApplyConditions(form));
+        form.addEventListener("change", () => rangiApplyConditions(form));
+        rangiApplyConditions(form);
+    }
+}
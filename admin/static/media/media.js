@@ -0,0 +1,29 @@
+// Focal point
+// Clicking on an image stores the relative position as focal point, which is kept visible when images are cropped
+document.addEventListener("click", (event) => {
+    const container = event.target.closest(".rangi-focal-point");
+    if (!container) {
+        return;
+    }
+    const image = container.querySelector("img");
+    const marker = container.querySelector(".rangi-focal-point-marker");
+    // The image is displayed with "object-fit: contain", so we have to determine the area that is covered by the image
+    const scale = Math.min(image.clientWidth / image.naturalWidth, image.clientHeight / image.naturalHeight);
+    const width = image.naturalWidth * scale;
+    const height = image.naturalHeight * scale;
+    const left = (image.clientWidth - width) / 2;
+    const top = (image.clientHeight - height) / 2;
+    const rect = image.getBoundingClientRect();
+    const focalX = Math.min(Math.max((event.clientX - rect.left - left) / width, 0), 1);
+    const focalY = Math.min(Math.max((event.clientY - rect.top - top) / height, 0), 1);
+    const body = new URLSearchParams();
+    body.append("focal_x", focalX.toFixed(4));
+    body.append("focal_y", focalY.toFixed(4));
+    fetch(container.dataset.focalPointUrl, { method: "PUT", body: body })
+        .then((response) => {
+            if (response.ok) {
+                marker.style.left = (left + focalX * width) / image.clientWidth * 100 + "%";
+                marker.style.top = (top + focalY * height) / image.clientHeight * 100 + "%";
+            }
+        });
+});
@@ -8,10 +8,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/rangidev/rangi/blueprint"
 	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/markdown"
 )
 
 const (
@@ -19,17 +21,29 @@ const (
 
 	keyInternalTemplateName   = "_internalTemplateName"
 	keyInternalAllCollections = "_internalAllCollections"
+	keyInternalLocale         = "_internalLocale"
+	keyInternalUser           = "_internalUser"
 )
 
 var (
 	//go:embed template
 	templateFS embed.FS
+	//go:embed i18n
+	i18nFS embed.FS
 
 	TemplateLogin      = &TemplateDefinition{name: "login.html", dependencies: []string{baseTemplateName}}
 	TemplateDashboard  = &TemplateDefinition{name: "dashboard.html", dependencies: []string{baseTemplateName, "navbar.html"}}
 	TemplateCollection = &TemplateDefinition{name: "collection.html", dependencies: []string{baseTemplateName, "navbar.html"}}
 	TemplateEdit       = &TemplateDefinition{name: "edit.html", dependencies: []string{baseTemplateName, "navbar.html"}}
 	TemplateSettings   = &TemplateDefinition{name: "settings.html", dependencies: []string{baseTemplateName, "navbar.html"}}
+	TemplateMedia      = &TemplateDefinition{name: "media.html", dependencies: []string{baseTemplateName, "navbar.html"}}
+	TemplateAudit      = &TemplateDefinition{name: "audit.html", dependencies: []string{baseTemplateName, "navbar.html"}}
+	TemplateBlueprints = &TemplateDefinition{name: "blueprints.html", dependencies: []string{baseTemplateName, "navbar.html"}}
+	TemplateBlueprint  = &TemplateDefinition{name: "blueprint.html", dependencies: []string{baseTemplateName, "navbar.html"}}
+
+	templateFuncs = template.FuncMap{
+		"markdown": markdown.ItemHTML,
+	}
 )
 
 type TemplateDefinition struct {
@@ -39,8 +53,18 @@ type TemplateDefinition struct {
 
 type Templates struct {
 	config     *config.Config
-	templates  map[string]*template.Template
 	templateFS fs.FS
+	i18nFS     fs.FS
+	// Guards templates and catalogs, which are replaced on every render in development mode
+	mutex sync.RWMutex
+	// Parsed templates per template name and locale, because the functions are bound to the locale
+	templates map[templateKey]*template.Template
+	catalogs  *Catalogs
+}
+
+type templateKey struct {
+	name   string
+	locale string
 }
 
 type TemplateData map[string]interface{}
@@ -53,11 +77,12 @@ type TemplateDataCollection struct {
 func NewTemplates(config *config.Config) (*Templates, error) {
 	t := Templates{
 		config:    config,
-		templates: make(map[string]*template.Template),
+		templates: make(map[templateKey]*template.Template),
 	}
 	if t.config.EnableTemplateDevelopment {
 		// In development mode, we want to read from filesystem
 		t.templateFS = os.DirFS(filepath.Join(config.ExecutableDir, "admin/template"))
+		t.i18nFS = os.DirFS(filepath.Join(config.ExecutableDir, "admin/i18n"))
 	} else {
 		// Read embedded templates
 		templateSubFS, err := fs.Sub(templateFS, "template")
@@ -65,21 +90,49 @@ func NewTemplates(config *config.Config) (*Templates, error) {
 			return nil, fmt.Errorf("could not get filesystem sub directory: %v", err)
 		}
 		t.templateFS = templateSubFS
+		i18nSubFS, err := fs.Sub(i18nFS, "i18n")
+		if err != nil {
+			return nil, fmt.Errorf("could not get filesystem sub directory: %v", err)
+		}
+		t.i18nFS = i18nSubFS
 	}
+	catalogs, err := loadCatalogs(t.i18nFS)
+	if err != nil {
+		return nil, fmt.Errorf("could not load message catalogs: %v", err)
+	}
+	t.catalogs = catalogs
 	return &t, nil
 }
 
+// EmbeddedCatalogs
+// returns the message catalogs that are built into the binary, e. g. for commands that run without a server
+func EmbeddedCatalogs() (*Catalogs, error) {
+	i18nSubFS, err := fs.Sub(i18nFS, "i18n")
+	if err != nil {
+		return nil, fmt.Errorf("could not get filesystem sub directory: %v", err)
+	}
+	return loadCatalogs(i18nSubFS)
+}
+
+// Catalogs
+// returns the message catalogs of the admin interface
+func (t *Templates) Catalogs() *Catalogs {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.catalogs
+}
+
 // Render
 // subTemplateName defines the sub template that should be executed (e. g. a "block" defined in the template string to render only a part of the original template). May be an empty string.
-func (t *Templates) Render(w http.ResponseWriter, data TemplateData, templateDef *TemplateDefinition, collectionLoader *blueprint.CollectionLoader, subTemplateName string) error {
-	// Always re-read templates in development mode
+// Messages and display names are translated into the locale of the admin interface that is determined from the request.
+func (t *Templates) Render(w http.ResponseWriter, r *http.Request, data TemplateData, templateDef *TemplateDefinition, collectionLoader *blueprint.CollectionLoader, subTemplateName string) error {
+	// Always re-read templates and catalogs in development mode
 	// Otherwise cache the templates
-	if _, ok := t.templates[templateDef.name]; !ok || t.config.EnableTemplateDevelopment {
-		tmpl, err := template.New("").Funcs(sprig.FuncMap()).ParseFS(t.templateFS, append(templateDef.dependencies, templateDef.name)...)
+	if t.config.EnableTemplateDevelopment {
+		err := t.reload()
 		if err != nil {
-			return fmt.Errorf("could not parse template %s: %v", templateDef.name, err)
+			return err
 		}
-		t.templates[templateDef.name] = tmpl
 	}
 	if data == nil {
 		data = TemplateData{}
@@ -88,10 +141,65 @@ func (t *Templates) Render(w http.ResponseWriter, data TemplateData, templateDef
 	if err != nil {
 		return fmt.Errorf("could not get all collections: %v", err)
 	}
+	locale := t.Catalogs().Locale(r)
+	tmpl, err := t.template(templateDef, locale)
+	if err != nil {
+		return err
+	}
 	data[keyInternalAllCollections] = allCollections
 	data[keyInternalTemplateName] = templateDef.name
+	data[keyInternalLocale] = locale
+	data[keyInternalUser] = UserFromContext(r.Context())
 	if subTemplateName != "" {
-		return t.templates[templateDef.name].ExecuteTemplate(w, subTemplateName, data)
+		return tmpl.ExecuteTemplate(w, subTemplateName, data)
+	}
+	return tmpl.ExecuteTemplate(w, baseTemplateName, data)
+}
+
+// reload
+// reads the catalogs again and drops all parsed templates
+func (t *Templates) reload() error {
+	catalogs, err := loadCatalogs(t.i18nFS)
+	if err != nil {
+		return fmt.Errorf("could not load message catalogs: %v", err)
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.catalogs = catalogs
+	clear(t.templates)
+	return nil
+}
+
+// template
+// returns the template with the functions bound to the locale. Templates are parsed once per locale.
+func (t *Templates) template(templateDef *TemplateDefinition, locale string) (*template.Template, error) {
+	key := templateKey{name: templateDef.name, locale: locale}
+	t.mutex.RLock()
+	tmpl, ok := t.templates[key]
+	t.mutex.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("").Funcs(sprig.FuncMap()).Funcs(templateFuncs).Funcs(t.localeFuncs(locale)).ParseFS(t.templateFS, append(templateDef.dependencies, templateDef.name)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %v", templateDef.name, err)
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.templates[key] = tmpl
+	return tmpl, nil
+}
+
+func (t *Templates) localeFuncs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(message string, args ...interface{}) string {
+			return t.Catalogs().Translate(locale, message, args...)
+		},
+		"label": func(label blueprint.Label) string {
+			return label.Localize(locale, t.Catalogs().Translator())
+		},
+		"messages": func() map[string]string {
+			return t.Catalogs().Messages(locale)
+		},
 	}
-	return t.templates[templateDef.name].ExecuteTemplate(w, baseTemplateName, data)
 }
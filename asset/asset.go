@@ -0,0 +1,106 @@
+package asset
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+const (
+	PublicPathPrefix = "/assets/"
+)
+
+// Asset
+// describes an uploaded file. The file itself is kept in a Storage under StorageKey.
+type Asset struct {
+	ID         int64   `db:"id" json:"id"`
+	UUID       string  `db:"uuid" json:"uuid"`
+	Filename   string  `db:"filename" json:"filename"`
+	StorageKey string  `db:"storage_key" json:"storage_key"`
+	MimeType   string  `db:"mime_type" json:"mime_type"`
+	Size       int64   `db:"size" json:"size"`
+	Width      int     `db:"width" json:"width"`   // 0 if the asset is not an image
+	Height     int     `db:"height" json:"height"` // 0 if the asset is not an image
+	AltText    string  `db:"alt_text" json:"alt_text"`
+	FocalX     float64 `db:"focal_x" json:"focal_x"` // Relative position between 0 and 1 of the most important part of an image
+	FocalY     float64 `db:"focal_y" json:"focal_y"`
+	CreatedAt  int64   `db:"created_at" json:"created_at"`
+}
+
+// New
+// inspects the file and returns an asset with the determined metadata.
+// The file is rewound afterwards, so that it can be stored.
+func New(filename string, file io.ReadSeeker) (*Asset, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	mime, err := mimetype.DetectReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not detect mime type: %v", err)
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine file size: %v", err)
+	}
+	asset := Asset{
+		UUID:       id.String(),
+		Filename:   path.Base(filename),
+		StorageKey: id.String() + mime.Extension(),
+		MimeType:   mime.String(),
+		Size:       size,
+		FocalX:     0.5,
+		FocalY:     0.5,
+	}
+	if asset.IsImage() {
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, fmt.Errorf("could not rewind file: %v", err)
+		}
+		config, _, err := image.DecodeConfig(file)
+		if err == nil {
+			// Dimensions are only available for formats with a registered decoder
+			asset.Width = config.Width
+			asset.Height = config.Height
+		}
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("could not rewind file: %v", err)
+	}
+	return &asset, nil
+}
+
+func (a Asset) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
+
+// IsInline
+// returns true if browsers may display the asset directly.
+// Other assets are served as attachments, so that uploaded HTML or scripts are never executed in the context of the site.
+func (a Asset) IsInline() bool {
+	switch {
+	case a.MimeType == "image/svg+xml":
+		return false
+	case a.IsImage(), strings.HasPrefix(a.MimeType, "video/"), strings.HasPrefix(a.MimeType, "audio/"):
+		return true
+	case strings.HasPrefix(a.MimeType, "application/pdf"):
+		return true
+	default:
+		return false
+	}
+}
+
+// PublicPath
+// returns the path the asset is served under
+func (a Asset) PublicPath() string {
+	return PublicPathPrefix + a.UUID
+}
@@ -0,0 +1,78 @@
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage
+// stores assets in a directory on the local filesystem
+type LocalStorage struct {
+	path string
+}
+
+func NewLocalStorage(path string) (*LocalStorage, error) {
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not make directory for assets: %v", err)
+	}
+	return &LocalStorage{path: path}, nil
+}
+
+func (ls *LocalStorage) Put(key string, r io.Reader, size int64) error {
+	filename, err := ls.filename(key)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that readers never see partial files
+	file, err := os.CreateTemp(ls.path, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not write file: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("could not close file: %v", err)
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+func (ls *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	filename, err := ls.filename(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrorNotFound
+	}
+	return file, err
+}
+
+func (ls *LocalStorage) Delete(key string) error {
+	filename, err := ls.filename(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (ls *LocalStorage) filename(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid storage key %s", key)
+	}
+	return filepath.Join(ls.path, key), nil
+}
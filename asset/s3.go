@@ -0,0 +1,275 @@
+package asset
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service        = "s3"
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3DateFormat     = "20060102T150405Z"
+	s3ShortDate      = "20060102"
+	s3UnsignedHash   = "UNSIGNED-PAYLOAD"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+)
+
+// S3Config
+// contains everything that is needed to connect to an S3-compatible object storage
+type S3Config struct {
+	Endpoint        string // e. g. "https://s3.eu-central-1.amazonaws.com" or "http://localhost:9000"
+	Region          string
+	Bucket          string
+	Prefix          string // Prepended to all storage keys
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool // Address the bucket as part of the path instead of the host name, as required by most self-hosted servers
+}
+
+// S3Storage
+// stores assets in an S3-compatible object storage.
+// Requests are signed with AWS Signature Version 4.
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Storage(config S3Config, client *http.Client) (*S3Storage, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse endpoint: %v", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint scheme %q", endpoint.Scheme)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("missing bucket")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("missing region")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+func (s3 *S3Storage) Put(key string, r io.Reader, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, s3.objectURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	return s3.do(req, http.StatusOK)
+}
+
+func (s3 *S3Storage) Get(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s3.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s3.sign(req, s3UnsignedHash)
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrorNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+func (s3 *S3Storage) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s3.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	return s3.do(req, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// PresignedURL
+// returns a URL that grants temporary read access to the object without credentials.
+// responseHeaders may contain overrides for "Content-Type" and "Content-Disposition".
+func (s3 *S3Storage) PresignedURL(key string, expires time.Duration, responseHeaders map[string]string) (string, error) {
+	if expires <= 0 || expires > s3MaxPresignTime {
+		return "", fmt.Errorf("invalid expiry %s", expires)
+	}
+	now := s3.now().UTC()
+	objectURL := s3.objectURL(key)
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s3.config.AccessKeyID+"/"+s3.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires.Seconds()), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	for header, value := range responseHeaders {
+		query.Set("response-"+strings.ToLower(header), value)
+	}
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		s3EncodePath(objectURL.Path),
+		s3CanonicalQuery(query),
+		"host:" + objectURL.Host + "\n",
+		"host",
+		s3UnsignedHash,
+	}, "\n")
+	query.Set("X-Amz-Signature", s3.signature(now, canonicalRequest))
+	objectURL.RawQuery = s3CanonicalQuery(query)
+	return objectURL.String(), nil
+}
+
+func (s3 *S3Storage) do(req *http.Request, expectedStatusCodes ...int) error {
+	s3.sign(req, s3UnsignedHash)
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, statusCode := range expectedStatusCodes {
+		if resp.StatusCode == statusCode {
+			return nil
+		}
+	}
+	return responseError(resp)
+}
+
+func (s3 *S3Storage) objectURL(key string) *url.URL {
+	objectURL := *s3.endpoint
+	objectKey := strings.TrimPrefix(path.Join(s3.config.Prefix, key), "/")
+	if s3.config.UsePathStyle {
+		objectURL.Path = path.Join("/", objectURL.Path, s3.config.Bucket, objectKey)
+	} else {
+		objectURL.Host = s3.config.Bucket + "." + objectURL.Host
+		objectURL.Path = path.Join("/", objectURL.Path, objectKey)
+	}
+	return &objectURL
+}
+
+// sign
+// adds an authorization header to the request
+func (s3 *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := s3.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	// Collect headers that are part of the signature
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EncodePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", s3Algorithm, s3.config.AccessKeyID, s3.scope(now), signedHeaders, s3.signature(now, canonicalRequest)))
+}
+
+func (s3 *S3Storage) scope(t time.Time) string {
+	return strings.Join([]string{t.Format(s3ShortDate), s3.config.Region, s3Service, "aws4_request"}, "/")
+}
+
+func (s3 *S3Storage) signature(t time.Time, canonicalRequest string) string {
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3DateFormat),
+		s3.scope(t),
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s3.config.SecretAccessKey), t.Format(s3ShortDate))
+	key = hmacSHA256(key, s3.config.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EncodePath
+// encodes every path segment according to RFC 3986, keeping the slashes
+func s3EncodePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for index, segment := range segments {
+		segments[index] = s3Encode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Encode(key)+"="+s3Encode(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Encode
+// percent-encodes everything except unreserved characters
+func s3Encode(s string) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			buf.WriteByte(b)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
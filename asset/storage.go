@@ -0,0 +1,25 @@
+package asset
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrorNotFound = errors.New("asset not found in storage")
+)
+
+// Storage
+// stores the files of assets. Keys are generated by Rangi and are safe to use as file names.
+type Storage interface {
+	Put(key string, r io.Reader, size int64) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Presigner
+// is implemented by storages that can hand out temporary URLs, so that clients can download files directly from the storage
+type Presigner interface {
+	PresignedURL(key string, expires time.Duration, responseHeaders map[string]string) (string, error)
+}
@@ -0,0 +1,254 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+type Action string
+
+const (
+	ActionLogin            = Action("login")
+	ActionLoginFailed      = Action("login.failed")
+	ActionLogout           = Action("logout")
+	ActionItemCreate       = Action("item.create")
+	ActionItemUpdate       = Action("item.update")
+	ActionItemDelete       = Action("item.delete")
+	ActionItemPublish      = Action("item.publish")
+	ActionBlueprintsReload = Action("blueprints.reload")
+	ActionBlueprintSave    = Action("blueprint.save")
+	ActionPermissionChange = Action("permission.change")
+	ActionPasswordReset    = Action("password.reset")
+
+	// Used if an action is not triggered by a user, e. g. on startup
+	ActorSystem = "system"
+
+	exportBatchSize = 500
+)
+
+var (
+	AllActions = []Action{
+		ActionLogin,
+		ActionLoginFailed,
+		ActionLogout,
+		ActionItemCreate,
+		ActionItemUpdate,
+		ActionItemDelete,
+		ActionItemPublish,
+		ActionBlueprintsReload,
+		ActionBlueprintSave,
+		ActionPermissionChange,
+		ActionPasswordReset,
+	}
+)
+
+type contextKey struct{}
+
+// Actor
+// is the user who triggers an action
+type Actor struct {
+	UserID int64
+	Email  string
+	IP     string
+}
+
+// WithActor
+// returns a context that carries the actor to the audit log
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(contextKey{}).(Actor)
+	return actor, ok
+}
+
+// ClientIP
+// returns the IP address of the client. Proxy headers are only used if they are trusted.
+func ClientIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			// The first address is the original client
+			first, _, _ := strings.Cut(forwardedFor, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+			return realIP
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Diff
+// maps changed fields to their old and new value
+type Diff map[string][2]any
+
+// ItemDiff
+// compares two versions of an item. Pass nil as old item for created items and nil as new item for deleted items.
+// Only fields of the new item are compared, derived fields and updated_at are left out to keep the diff compact.
+func ItemDiff(bp *blueprint.Blueprint, oldItem blueprint.Item, newItem blueprint.Item) Diff {
+	diff := Diff{}
+	for _, field := range bp.Fields {
+		if field.Name == blueprint.KeyUpdatedAt || bp.IsDerivedField(field.Name) {
+			continue
+		}
+		oldValue, oldOk := oldItem[field.Name]
+		newValue, newOk := newItem[field.Name]
+		if newItem != nil && !newOk {
+			// Field has not been written
+			continue
+		}
+		oldValue = normalize(oldValue)
+		newValue = normalize(newValue)
+		if oldOk && newOk && fmt.Sprint(oldValue) == fmt.Sprint(newValue) {
+			continue
+		}
+		if isEmpty(oldValue) && isEmpty(newValue) {
+			continue
+		}
+		diff[field.Name] = [2]any{oldValue, newValue}
+	}
+	return diff
+}
+
+// BlueprintDiff
+// compares the fields of two versions of a blueprint. Pass nil as old blueprint for new collections.
+// Default and derived fields are left out, because they can not be changed.
+func BlueprintDiff(oldBlueprint *blueprint.Blueprint, newBlueprint *blueprint.Blueprint) Diff {
+	diff := Diff{}
+	oldFields := make(map[string]blueprint.BlueprintField)
+	if oldBlueprint != nil {
+		for _, field := range oldBlueprint.CustomFields() {
+			oldFields[field.Name] = field
+		}
+	}
+	for _, field := range newBlueprint.CustomFields() {
+		oldField, ok := oldFields[field.Name]
+		delete(oldFields, field.Name)
+		if ok && reflect.DeepEqual(oldField, field) {
+			continue
+		}
+		var oldValue any
+		if ok {
+			oldValue = oldField
+		}
+		diff[field.Name] = [2]any{oldValue, field}
+	}
+	// Removed fields
+	for name, field := range oldFields {
+		diff[name] = [2]any{field, nil}
+	}
+	return diff
+}
+
+func normalize(value any) any {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+func isEmpty(value any) bool {
+	return value == nil || value == ""
+}
+
+// Log
+// writes entries to the append-only audit log in the database
+type Log struct {
+	db     *database.DB
+	logger *slog.Logger
+}
+
+func New(db *database.DB, logger *slog.Logger) *Log {
+	return &Log{db: db, logger: logger}
+}
+
+// Record
+// describes an action for the audit log
+type Record struct {
+	Action     Action
+	Collection string
+	Target     string
+	Diff       Diff
+}
+
+// Add
+// appends the record to the audit log. The actor is taken from the context.
+// Errors are logged, so that a failing audit log does not break the action that has already been executed.
+func (l *Log) Add(ctx context.Context, record Record) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		actor = Actor{Email: ActorSystem}
+	}
+	entry := &database.AuditEntry{
+		CreatedAt:  time.Now().Unix(),
+		Action:     string(record.Action),
+		ActorID:    actor.UserID,
+		Actor:      actor.Email,
+		IP:         actor.IP,
+		Collection: record.Collection,
+		Target:     record.Target,
+	}
+	if len(record.Diff) > 0 {
+		diff, err := json.Marshal(record.Diff)
+		if err != nil {
+			l.logger.Error("Could not marshal audit diff", "action", record.Action, "error", err)
+		} else {
+			entry.Diff = string(diff)
+		}
+	}
+	// Written in the transaction of the context, if there is one, so that it is rolled back with the change
+	err := database.FromContext(ctx, l.db).InsertAuditEntry(entry)
+	if err != nil {
+		l.logger.Error("Could not write audit log", "action", record.Action, "actor", actor.Email, "error", err)
+	}
+}
+
+// exportEntry
+// embeds the diff as JSON object instead of a string
+type exportEntry struct {
+	database.AuditEntry
+	Diff json.RawMessage `json:"diff,omitempty"`
+}
+
+// Export
+// writes all matching entries as JSON lines, newest first
+func (l *Log) Export(w io.Writer, filter database.AuditFilter) error {
+	encoder := json.NewEncoder(w)
+	for {
+		entries, err := l.db.GetAuditEntries(filter, exportBatchSize, 0)
+		if err != nil {
+			return fmt.Errorf("could not get audit entries: %v", err)
+		}
+		for _, entry := range entries {
+			e := exportEntry{AuditEntry: entry}
+			if entry.Diff != "" {
+				e.Diff = json.RawMessage(entry.Diff)
+			}
+			err = encoder.Encode(&e)
+			if err != nil {
+				return fmt.Errorf("could not encode audit entry: %v", err)
+			}
+		}
+		if len(entries) < exportBatchSize {
+			return nil
+		}
+		// Continue below the last entry, so that new entries do not shift the pages
+		filter.BeforeID = entries[len(entries)-1].ID
+	}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+func TestItemDiff(t *testing.T) {
+	bp := &blueprint.Blueprint{Fields: []blueprint.BlueprintField{
+		{Name: blueprint.KeyTitle, Type: blueprint.TypeString},
+		{Name: blueprint.KeyUpdatedAt, Type: blueprint.TypeInt},
+		{Name: "body", Type: blueprint.TypeMarkdown},
+		{Name: blueprint.MarkdownHTMLFieldName("body"), Type: blueprint.TypeString},
+		{Name: "subtitle", Type: blueprint.TypeString},
+	}}
+	oldItem := blueprint.Item{blueprint.KeyTitle: []byte("Old"), blueprint.KeyUpdatedAt: int64(1), "body": "Text", "body_html": "<p>Text</p>", "subtitle": nil}
+	tests := []struct {
+		name    string
+		oldItem blueprint.Item
+		newItem blueprint.Item
+		want    Diff
+	}{
+		{"created", nil, blueprint.Item{blueprint.KeyTitle: "New", "subtitle": ""}, Diff{blueprint.KeyTitle: {nil, "New"}}},
+		{"updated", oldItem, blueprint.Item{blueprint.KeyTitle: "New", blueprint.KeyUpdatedAt: int64(2), "body": "Text", "body_html": "<p>Text</p>", "subtitle": ""}, Diff{blueprint.KeyTitle: {"Old", "New"}}},
+		{"unchanged", oldItem, blueprint.Item{blueprint.KeyTitle: "Old"}, Diff{}},
+		{"deleted", oldItem, nil, Diff{blueprint.KeyTitle: {"Old", nil}, "body": {"Text", nil}}},
+	}
+	for _, test := range tests {
+		got := ItemDiff(bp, test.oldItem, test.newItem)
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got diff %v, want %v", test.name, got, test.want)
+			continue
+		}
+		for name, values := range test.want {
+			if got[name] != values {
+				t.Errorf("%s: got %v for field %s, want %v", test.name, got[name], name, values)
+			}
+		}
+	}
+}
+
+func TestAddUsesTransaction(t *testing.T) {
+	db, err := database.NewSqlite3Instance(filepath.Join(t.TempDir(), "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	err = db.CreateAuditLogTable()
+	if err != nil {
+		t.Fatalf("could not create audit log table: %v", err)
+	}
+	log := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := WithActor(context.Background(), Actor{UserID: 1, Email: "admin@example.com", IP: "192.0.2.1"})
+	errorRollback := errors.New("rollback")
+	err = db.Transaction(func(tx *database.DB) error {
+		log.Add(database.ContextWithTransaction(ctx, tx), Record{Action: ActionItemDelete, Collection: "articles", Target: "1"})
+		return errorRollback
+	})
+	if !errors.Is(err, errorRollback) {
+		t.Fatalf("got error %v, want rollback", err)
+	}
+	log.Add(ctx, Record{Action: ActionItemUpdate, Collection: "articles", Target: "1", Diff: Diff{"title": {"Old", "New"}}})
+	// Actions without a user are recorded for the system
+	log.Add(context.Background(), Record{Action: ActionBlueprintsReload})
+	entries, err := db.GetAuditEntries(database.AuditFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("could not get audit entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 without the rolled back one", len(entries))
+	}
+	if entries[0].Actor != ActorSystem || entries[0].Action != string(ActionBlueprintsReload) {
+		t.Errorf("got entry %+v, want a system entry", entries[0])
+	}
+	if entries[1].Actor != "admin@example.com" || entries[1].ActorID != 1 || entries[1].IP != "192.0.2.1" || entries[1].Diff != `{"title":["Old","New"]}` {
+		t.Errorf("got entry %+v, want the actor of the context and the diff", entries[1])
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		forwardedFor string
+		trusted      bool
+		want         string
+	}{
+		{"", false, "192.0.2.1"},
+		{"198.51.100.7, 10.0.0.1", false, "192.0.2.1"},
+		{"198.51.100.7, 10.0.0.1", true, "198.51.100.7"},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		if test.forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+		if got := ClientIP(request, test.trusted); got != test.want {
+			t.Errorf("ClientIP with X-Forwarded-For %q, trusted %v: got %s, want %s", test.forwardedFor, test.trusted, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	MinPasswordLength = 8
+	sessionTokenBytes = 32
+	passwordBytes     = 12
+)
+
+var (
+	ErrorInvalidCredentials = errors.New("invalid email or password")
+
+	// Compared against if a user does not exist, so that the response time does not reveal which users exist
+	dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("rangi-dummy-password"), bcrypt.DefaultCost)
+)
+
+// HashPassword
+// returns the bcrypt hash of the password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must have at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("could not hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+// NormalizeEmail
+// validates the email address and returns it in the form that is stored
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", fmt.Errorf("invalid email address %q", email)
+	}
+	return email, nil
+}
+
+// CreateUser
+// validates the input and stores a new user
+func CreateUser(db *database.DB, email string, password string, role database.UserRole) (*database.User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(database.AllUserRoles, role) {
+		return nil, fmt.Errorf("unknown role %s", role)
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &database.User{Email: email, PasswordHash: passwordHash, Role: role}
+	err = db.CreateUser(user)
+	if err != nil {
+		return nil, fmt.Errorf("could not create user: %v", err)
+	}
+	return user, nil
+}
+
+// ResetPassword
+// sets a new password for the user with the email address and ends all of their sessions
+func ResetPassword(db *database.DB, email string, password string) (*database.User, error) {
+	user, err := db.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no user with email address %q", email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get user: %v", err)
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	err = db.UpdateUserPassword(user.ID, passwordHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not update password: %v", err)
+	}
+	return user, nil
+}
+
+// Authenticate
+// returns the user if the password is correct, otherwise ErrorInvalidCredentials
+func Authenticate(db *database.DB, email string, password string) (*database.User, error) {
+	user, err := db.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrorInvalidCredentials
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		return nil, ErrorInvalidCredentials
+	}
+	return user, nil
+}
+
+// NewSessionToken
+// returns a random token that identifies a session
+func NewSessionToken() (string, error) {
+	b := make([]byte, sessionTokenBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("could not read random bytes: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewPassword
+// returns a random password that can be handed out to a user
+func NewPassword() (string, error) {
+	b := make([]byte, passwordBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("could not read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rangidev/rangi/database"
+)
+
+func newTestDatabase(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.NewSqlite3Instance(filepath.Join(t.TempDir(), "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = db.CreateUserTable()
+	if err != nil {
+		t.Fatalf("could not create user table: %v", err)
+	}
+	return db
+}
+
+func TestCreateUser(t *testing.T) {
+	db := newTestDatabase(t)
+	tests := []struct {
+		email    string
+		password string
+		role     database.UserRole
+		valid    bool
+	}{
+		{" Editor@Example.com ", "password", database.UserRoleEditor, true},
+		{"editor@example.com", "password", database.UserRoleEditor, false}, // Exists already
+		{"Name <name@example.com>", "password", database.UserRoleEditor, false},
+		{"short@example.com", "short", database.UserRoleEditor, false},
+		{"owner@example.com", "password", database.UserRole("owner"), false},
+	}
+	for _, test := range tests {
+		user, err := CreateUser(db, test.email, test.password, test.role)
+		if (err == nil) != test.valid {
+			t.Errorf("CreateUser(%q) returned error %v, want valid %v", test.email, err, test.valid)
+		}
+		if err == nil && user.Email != "editor@example.com" {
+			t.Errorf("got email %q, want a normalized email", user.Email)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	db := newTestDatabase(t)
+	_, err := CreateUser(db, "admin@example.com", "password", database.UserRoleAdmin)
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	tests := []struct {
+		email    string
+		password string
+		valid    bool
+	}{
+		{"admin@example.com", "password", true},
+		{" ADMIN@example.com", "password", true},
+		{"admin@example.com", "Password", false},
+		{"unknown@example.com", "password", false},
+	}
+	for _, test := range tests {
+		user, err := Authenticate(db, test.email, test.password)
+		if test.valid && (err != nil || user.Email != "admin@example.com") {
+			t.Errorf("could not authenticate %q: %v", test.email, err)
+		}
+		// Unknown users and wrong passwords can not be told apart
+		if !test.valid && !errors.Is(err, ErrorInvalidCredentials) {
+			t.Errorf("Authenticate(%q, %q) returned error %v, want %v", test.email, test.password, err, ErrorInvalidCredentials)
+		}
+	}
+}
+
+func TestResetPasswordEndsSessions(t *testing.T) {
+	db := newTestDatabase(t)
+	user, err := CreateUser(db, "admin@example.com", "password", database.UserRoleAdmin)
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	token, err := NewSessionToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CreateSession(token, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("could not create session: %v", err)
+	}
+	_, err = ResetPassword(db, "Admin@example.com", "new password")
+	if err != nil {
+		t.Fatalf("could not reset password: %v", err)
+	}
+	if _, err := db.GetSessionUser(token); err == nil {
+		t.Errorf("session is still valid after the password has been reset")
+	}
+	if _, err := Authenticate(db, "admin@example.com", "password"); !errors.Is(err, ErrorInvalidCredentials) {
+		t.Errorf("old password is still valid: %v", err)
+	}
+	if _, err := Authenticate(db, "admin@example.com", "new password"); err != nil {
+		t.Errorf("could not authenticate with the new password: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+)
+
+const (
+	// FormatVersion is increased whenever the layout of archives changes
+	FormatVersion = 1
+
+	manifestName     = "manifest.json"
+	blueprintsPrefix = "content/blueprints/"
+	databaseName     = "content/sqlite3/rangi.db"
+	assetsPrefix     = "content/assets/"
+)
+
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	DatabaseType  string    `json:"database_type"`
+	// Maps collection names to the versions of their blueprints, see BlueprintVersion
+	Blueprints map[string]string `json:"blueprints"`
+	// Assets are only included for the local storage
+	AssetsIncluded bool   `json:"assets_included"`
+	Files          []File `json:"files"`
+}
+
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BlueprintVersion
+// returns a checksum of the blueprint file of the collection and of the component files it uses, so that changes to a blueprint can be detected.
+// Default fields are not part of the files, so that updates of Rangi do not change the versions.
+func BlueprintVersion(collectionLoader *blueprint.CollectionLoader, collectionName string) (string, error) {
+	files, err := collectionLoader.Files(collectionName)
+	if err != nil {
+		return "", fmt.Errorf("could not read blueprint files: %v", err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(files[name])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Create
+// writes a gzipped tar archive with the blueprints, a consistent copy of the sqlite3 database and the local assets.
+// The server can keep running while the backup is created.
+func Create(ctx context.Context, config *config.Config, w io.Writer) (*Manifest, error) {
+	if config.DatabaseType != "sqlite3" {
+		return nil, fmt.Errorf("backups are only supported for sqlite3 databases")
+	}
+	manifest := &Manifest{
+		FormatVersion:  FormatVersion,
+		CreatedAt:      time.Now().UTC(),
+		DatabaseType:   config.DatabaseType,
+		Blueprints:     make(map[string]string),
+		AssetsIncluded: config.StorageType == "local",
+	}
+	collectionLoader := blueprint.NewCollectionLoader(config.BlueprintsPath)
+	collections, err := collectionLoader.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections: %v", err)
+	}
+	for _, collection := range collections {
+		version, err := BlueprintVersion(collectionLoader, collection.Blueprint.CollectionName)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Blueprints[collection.Blueprint.CollectionName] = version
+	}
+	// Copy the database first, so that the archive is never written for a failed backup
+	tempDir, err := os.MkdirTemp("", "rangi-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	databaseCopy := filepath.Join(tempDir, "rangi.db")
+	err = config.DatabaseInstance.Backup(ctx, databaseCopy)
+	if err != nil {
+		return nil, fmt.Errorf("could not back up database: %v", err)
+	}
+	// Write archive
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err = addDirectory(tarWriter, manifest, config.BlueprintsPath, blueprintsPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not add blueprints: %v", err)
+	}
+	err = addFile(tarWriter, manifest, databaseCopy, databaseName)
+	if err != nil {
+		return nil, fmt.Errorf("could not add database: %v", err)
+	}
+	if manifest.AssetsIncluded {
+		err = addDirectory(tarWriter, manifest, config.AssetsPath, assetsPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("could not add assets: %v", err)
+		}
+	}
+	// The manifest is written last, because it contains the checksums of all files
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal manifest: %v", err)
+	}
+	err = tarWriter.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not write manifest header: %v", err)
+	}
+	_, err = tarWriter.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not write manifest: %v", err)
+	}
+	err = tarWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not close archive: %v", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not close archive: %v", err)
+	}
+	return manifest, nil
+}
+
+// addDirectory
+// adds all regular files below dir. A missing directory is treated as empty.
+// Hidden files are skipped, e. g. uploads that are still being written.
+func addDirectory(tarWriter *tar.Writer, manifest *Manifest, dir string, prefix string) error {
+	_, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		relativeName, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		return addFile(tarWriter, manifest, filename, prefix+filepath.ToSlash(relativeName))
+	})
+}
+
+// addFile
+// adds a file to the archive and its checksum to the manifest
+func addFile(tarWriter *tar.Writer, manifest *Manifest, filename string, name string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	err = tarWriter.WriteHeader(&tar.Header{
+		Name:    path.Clean(name),
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tarWriter, hash), file)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, File{
+		Name:   path.Clean(name),
+		Size:   written,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+	return nil
+}
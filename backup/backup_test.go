@@ -0,0 +1,264 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	testBlueprint = `{
+	"collection_display_name": {"en": "Pages"},
+	"collection_name": "pages",
+	"fields": [
+		{"name": "seo", "display_name": "SEO", "type": "object", "component": "seo"}
+	]
+}`
+	testComponent = `{
+	"component_name": "seo",
+	"fields": [
+		{"name": "meta_title", "display_name": "Meta title", "type": "string"}
+	]
+}`
+)
+
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	conf := &config.Config{
+		DatabaseType:        "sqlite3",
+		StorageType:         "local",
+		BlueprintsPath:      filepath.Join(dir, "blueprints"),
+		AssetsPath:          filepath.Join(dir, "assets"),
+		Sqlite3DatabaseFile: filepath.Join(dir, "sqlite3", "rangi.db"),
+	}
+	files := map[string]string{
+		filepath.Join(conf.BlueprintsPath, "pages.json"):                              testBlueprint,
+		filepath.Join(conf.BlueprintsPath, blueprint.ComponentsDirectory, "seo.json"): testComponent,
+		filepath.Join(conf.AssetsPath, "image.png"):                                   "image",
+	}
+	for filename, content := range files {
+		writeTestFile(t, filename, content)
+	}
+	err := os.MkdirAll(filepath.Dir(conf.Sqlite3DatabaseFile), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.DatabaseInstance = openTestDatabase(t, conf)
+	collectionLoader := blueprint.NewCollectionLoader(conf.BlueprintsPath)
+	collections, err := collectionLoader.GetAll()
+	if err != nil {
+		t.Fatalf("could not load collections: %v", err)
+	}
+	err = conf.DatabaseInstance.CreateTables(collections, collectionLoader)
+	if err != nil {
+		t.Fatalf("could not create tables: %v", err)
+	}
+	createTestItem(t, conf, "Home")
+	return conf
+}
+
+func openTestDatabase(t *testing.T, conf *config.Config) *database.DB {
+	t.Helper()
+	db, err := database.NewSqlite3Instance(conf.Sqlite3DatabaseFile)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func writeTestFile(t *testing.T, filename string, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err == nil {
+		err = os.WriteFile(filename, []byte(content), 0o644)
+	}
+	if err != nil {
+		t.Fatalf("could not write %s: %v", filename, err)
+	}
+}
+
+func createTestItem(t *testing.T, conf *config.Config, title string) {
+	t.Helper()
+	collection, err := blueprint.NewCollectionLoader(conf.BlueprintsPath).Get("pages")
+	if err != nil {
+		t.Fatalf("could not load collection: %v", err)
+	}
+	item, err := blueprint.NewItem(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item[blueprint.KeyTitle] = title
+	err = conf.DatabaseInstance.CreateItem(collection, item)
+	if err != nil {
+		t.Fatalf("could not create item: %v", err)
+	}
+}
+
+func countTestItems(t *testing.T, conf *config.Config) int {
+	t.Helper()
+	collection, err := blueprint.NewCollectionLoader(conf.BlueprintsPath).Get("pages")
+	if err != nil {
+		t.Fatalf("could not load collection: %v", err)
+	}
+	// Restoring fails while the database is open
+	db, err := database.NewSqlite3Instance(conf.Sqlite3DatabaseFile)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	items, err := db.GetItems(collection, 100, 0)
+	if err != nil {
+		t.Fatalf("could not get items: %v", err)
+	}
+	return len(items)
+}
+
+func createTestBackup(t *testing.T, conf *config.Config) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	manifest, err := Create(context.Background(), conf, &archive)
+	if err != nil {
+		t.Fatalf("could not create backup: %v", err)
+	}
+	if manifest.FormatVersion != FormatVersion || !manifest.AssetsIncluded || manifest.Blueprints["pages"] == "" {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	return archive.Bytes()
+}
+
+// rewriteArchive
+// returns a copy of the archive in which the content of the entry name is replaced by change
+func rewriteArchive(t *testing.T, archive []byte, name string, change func([]byte) []byte) []byte {
+	t.Helper()
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	var rewritten bytes.Buffer
+	gzipWriter := gzip.NewWriter(&rewritten)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == name {
+			data = change(data)
+			header.Size = int64(len(data))
+		}
+		err = tarWriter.WriteHeader(header)
+		if err == nil {
+			_, err = tarWriter.Write(data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = tarWriter.Close()
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rewritten.Bytes()
+}
+
+func TestRestore(t *testing.T) {
+	conf := newTestConfig(t)
+	archive := createTestBackup(t, conf)
+	createTestItem(t, conf, "About")
+	writeTestFile(t, filepath.Join(conf.AssetsPath, "new.png"), "new")
+	result, err := Restore(conf, bytes.NewReader(archive), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("could not restore: %v", err)
+	}
+	if len(result.ChangedBlueprints) != 0 || len(result.PreviousPaths) == 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if count := countTestItems(t, conf); count != 1 {
+		t.Errorf("got %d items after restore, want 1", count)
+	}
+	if _, err := os.Stat(filepath.Join(conf.AssetsPath, "new.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("asset that was uploaded after the backup has not been replaced: %v", err)
+	}
+	for _, previousPath := range result.PreviousPaths {
+		if _, err := os.Stat(previousPath); err != nil {
+			t.Errorf("previous data is missing: %v", err)
+		}
+	}
+}
+
+func TestRestoreChangedBlueprints(t *testing.T) {
+	conf := newTestConfig(t)
+	archive := createTestBackup(t, conf)
+	// Components are part of the version of the blueprints that use them
+	writeTestFile(t, filepath.Join(conf.BlueprintsPath, blueprint.ComponentsDirectory, "seo.json"), `{"component_name": "seo", "fields": [{"name": "meta_description", "display_name": "Meta description", "type": "string"}]}`)
+	createTestItem(t, conf, "About")
+	result, err := Restore(conf, bytes.NewReader(archive), RestoreOptions{})
+	if !errors.Is(err, ErrorChangedBlueprints) {
+		t.Fatalf("got error %v, want %v", err, ErrorChangedBlueprints)
+	}
+	if len(result.ChangedBlueprints) != 1 || result.ChangedBlueprints[0] != "pages" {
+		t.Fatalf("got changed blueprints %v, want pages", result.ChangedBlueprints)
+	}
+	if count := countTestItems(t, conf); count != 2 {
+		t.Fatalf("got %d items after a rejected restore, want 2", count)
+	}
+	_, err = Restore(conf, bytes.NewReader(archive), RestoreOptions{AllowChangedBlueprints: true})
+	if err != nil {
+		t.Fatalf("could not restore: %v", err)
+	}
+	if count := countTestItems(t, conf); count != 1 {
+		t.Errorf("got %d items after restore, want 1", count)
+	}
+}
+
+func TestRestoreInvalidArchive(t *testing.T) {
+	conf := newTestConfig(t)
+	archive := createTestBackup(t, conf)
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"truncated", archive[:len(archive)/2]},
+		{"changed file", rewriteArchive(t, archive, blueprintsPrefix+"pages.json", func(data []byte) []byte {
+			return bytes.Replace(data, []byte("Pages"), []byte("Posts"), 1)
+		})},
+		{"unsupported format version", rewriteArchive(t, archive, manifestName, func(data []byte) []byte {
+			return bytes.Replace(data, []byte(`"format_version": 1`), []byte(`"format_version": 2`), 1)
+		})},
+	}
+	for _, test := range tests {
+		_, err := Restore(conf, bytes.NewReader(test.archive), RestoreOptions{})
+		if err == nil {
+			t.Errorf("%s: restored an invalid archive", test.name)
+		}
+		if test.name != "truncated" && !errors.Is(err, ErrorInvalidArchive) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, ErrorInvalidArchive)
+		}
+	}
+	if count := countTestItems(t, conf); count != 1 {
+		t.Fatalf("got %d items after invalid restores, want 1", count)
+	}
+}
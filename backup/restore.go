@@ -0,0 +1,418 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/database"
+)
+
+var (
+	ErrorInvalidArchive    = errors.New("invalid archive")
+	ErrorChangedBlueprints = errors.New("blueprints differ from the ones in use")
+	ErrorRestoreRolledBack = errors.New("restore failed, the previous data has been put back")
+)
+
+type RestoreOptions struct {
+	// Restore even if blueprints differ from the ones in use, see RestoreResult.ChangedBlueprints
+	AllowChangedBlueprints bool
+}
+
+// replacement
+// moves the staged data to the target path. The target is only moved aside if staged is empty.
+type replacement struct {
+	staged string
+	target string
+}
+
+type RestoreResult struct {
+	Manifest *Manifest `json:"manifest"`
+	// Collections whose blueprints differ from the ones that were in use before the restore
+	ChangedBlueprints []string `json:"changed_blueprints"`
+	// The replaced files and directories were moved to these paths
+	PreviousPaths []string `json:"previous_paths"`
+	// Assets of the archive are not restored if the asset storage is not local
+	AssetsSkipped bool `json:"assets_skipped"`
+}
+
+// Restore
+// validates the archive and replaces the blueprints, the sqlite3 database and the local assets with its content.
+// Nothing is replaced if the archive is invalid, or if blueprints have changed and options do not allow it.
+// The replaced data is kept next to its original path, and put back if replacing fails.
+// The database instance of the config is closed. Restoring fails if another process, e. g. a running server, uses the database.
+func Restore(config *config.Config, r io.Reader, options RestoreOptions) (*RestoreResult, error) {
+	if config.DatabaseType != "sqlite3" {
+		return nil, fmt.Errorf("backups are only supported for sqlite3 databases")
+	}
+	// Extract next to the database, so that files can usually be renamed into place
+	stagingDir, err := os.MkdirTemp(filepath.Dir(config.Sqlite3DatabaseFile), ".rangi-restore-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create staging directory: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+	extracted, err := extract(r, stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract archive: %v", err)
+	}
+	manifest, err := verifyFiles(stagingDir, extracted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidArchive, err)
+	}
+	if manifest.DatabaseType != config.DatabaseType {
+		return nil, fmt.Errorf("%w: database type %s does not match the configured type %s", ErrorInvalidArchive, manifest.DatabaseType, config.DatabaseType)
+	}
+	// Blueprints
+	stagedBlueprintsPath := filepath.Join(stagingDir, filepath.FromSlash(blueprintsPrefix))
+	collections, err := verifyBlueprints(manifest, stagedBlueprintsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidArchive, err)
+	}
+	// Database
+	stagedDatabaseFile := filepath.Join(stagingDir, filepath.FromSlash(databaseName))
+	err = verifyDatabase(stagedDatabaseFile, collections)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidArchive, err)
+	}
+	result := &RestoreResult{
+		Manifest:      manifest,
+		AssetsSkipped: manifest.AssetsIncluded && config.StorageType != "local",
+	}
+	currentLoader := blueprint.NewCollectionLoader(config.BlueprintsPath)
+	for _, collection := range collections {
+		currentVersion, err := BlueprintVersion(currentLoader, collection.Blueprint.CollectionName)
+		if err == nil && currentVersion == manifest.Blueprints[collection.Blueprint.CollectionName] {
+			continue
+		}
+		result.ChangedBlueprints = append(result.ChangedBlueprints, collection.Blueprint.CollectionName)
+	}
+	if len(result.ChangedBlueprints) > 0 && !options.AllowChangedBlueprints {
+		return result, fmt.Errorf("%w: %s", ErrorChangedBlueprints, strings.Join(result.ChangedBlueprints, ", "))
+	}
+	// Replace data
+	err = config.DatabaseInstance.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not close database: %v", err)
+	}
+	lock, err := database.LockSqlite3(config.Sqlite3DatabaseFile, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not lock database, stop the server first: %v", err)
+	}
+	defer lock.Release()
+	suffix := ".before-restore-" + time.Now().UTC().Format("20060102T150405Z")
+	replacements := []replacement{
+		{staged: stagedBlueprintsPath, target: config.BlueprintsPath},
+		{staged: stagedDatabaseFile, target: config.Sqlite3DatabaseFile},
+		// Write-ahead log files of the old database would be applied to the restored database
+		{target: config.Sqlite3DatabaseFile + "-wal"},
+		{target: config.Sqlite3DatabaseFile + "-shm"},
+	}
+	if manifest.AssetsIncluded && config.StorageType == "local" {
+		replacements = append(replacements, replacement{staged: filepath.Join(stagingDir, filepath.FromSlash(assetsPrefix)), target: config.AssetsPath})
+	}
+	var completed []completedReplacement
+	for _, replacement := range replacements {
+		done, err := replacement.apply(suffix)
+		if done.previousPath != "" || done.restored {
+			completed = append(completed, done)
+		}
+		if err != nil {
+			rollbackErr := rollback(completed)
+			if rollbackErr != nil {
+				return result, fmt.Errorf("%v, and the previous data could not be put back: %v", err, rollbackErr)
+			}
+			return result, fmt.Errorf("%w: %v", ErrorRestoreRolledBack, err)
+		}
+	}
+	for _, done := range completed {
+		if done.previousPath != "" {
+			result.PreviousPaths = append(result.PreviousPaths, done.previousPath)
+		}
+	}
+	return result, nil
+}
+
+// completedReplacement
+// records what has been done for a replacement, so that it can be undone
+type completedReplacement struct {
+	target       string
+	previousPath string // Empty if the target did not exist
+	restored     bool   // True if the target has been created from the archive
+}
+
+// apply
+// moves the target aside and the staged data into its place
+func (r replacement) apply(suffix string) (completedReplacement, error) {
+	done := completedReplacement{target: r.target}
+	_, err := os.Lstat(r.target)
+	if err == nil {
+		previousPath := r.target + suffix
+		err = os.Rename(r.target, previousPath)
+		if err != nil {
+			return done, fmt.Errorf("could not move %s aside: %v", r.target, err)
+		}
+		done.previousPath = previousPath
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return done, fmt.Errorf("could not stat %s: %v", r.target, err)
+	}
+	if r.staged == "" {
+		return done, nil
+	}
+	done.restored = true
+	_, err = os.Stat(r.staged)
+	if errors.Is(err, fs.ErrNotExist) {
+		// The archive has no files in this directory
+		err = os.MkdirAll(r.target, os.ModePerm)
+		if err != nil {
+			return done, fmt.Errorf("could not create %s: %v", r.target, err)
+		}
+		return done, nil
+	}
+	err = move(r.staged, r.target)
+	if err != nil {
+		return done, fmt.Errorf("could not move restored data to %s: %v", r.target, err)
+	}
+	return done, nil
+}
+
+// rollback
+// removes the restored data and moves the previous data back, in reverse order
+func rollback(completed []completedReplacement) error {
+	var errs []error
+	for index := len(completed) - 1; index >= 0; index-- {
+		done := completed[index]
+		if done.restored {
+			err := os.RemoveAll(done.target)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not remove %s: %v", done.target, err))
+				continue
+			}
+		}
+		if done.previousPath != "" {
+			err := os.Rename(done.previousPath, done.target)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not move %s back: %v", done.previousPath, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// extract
+// writes the regular files of the archive to dir and returns their names
+func extract(r io.Reader, dir string) (map[string]bool, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	names := make(map[string]bool)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unsupported entry %s", header.Name)
+		}
+		// Do not allow entries to escape the staging directory
+		if !filepath.IsLocal(header.Name) || (header.Name != manifestName && !strings.HasPrefix(header.Name, "content/")) {
+			return nil, fmt.Errorf("unexpected entry %s", header.Name)
+		}
+		if names[header.Name] {
+			return nil, fmt.Errorf("duplicate entry %s", header.Name)
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(header.Name))
+		err = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+		err = writeFile(filename, tarReader)
+		if err != nil {
+			return nil, err
+		}
+		names[header.Name] = true
+	}
+	return names, nil
+}
+
+// verifyFiles
+// reads the manifest and compares it to the extracted files
+func verifyFiles(dir string, extracted map[string]bool) (*Manifest, error) {
+	if !extracted[manifestName] {
+		return nil, fmt.Errorf("missing %s", manifestName)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %v", err)
+	}
+	var manifest Manifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal manifest: %v", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported format version %d", manifest.FormatVersion)
+	}
+	if !slices.ContainsFunc(manifest.Files, func(f File) bool { return f.Name == databaseName }) {
+		return nil, fmt.Errorf("missing %s", databaseName)
+	}
+	listed := map[string]bool{manifestName: true}
+	for _, file := range manifest.Files {
+		if !extracted[file.Name] {
+			return nil, fmt.Errorf("missing %s", file.Name)
+		}
+		listed[file.Name] = true
+		size, checksum, err := checksumFile(filepath.Join(dir, filepath.FromSlash(file.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("could not compute checksum of %s: %v", file.Name, err)
+		}
+		if size != file.Size || checksum != file.SHA256 {
+			return nil, fmt.Errorf("checksum of %s does not match", file.Name)
+		}
+	}
+	for name := range extracted {
+		if !listed[name] {
+			return nil, fmt.Errorf("%s is not listed in the manifest", name)
+		}
+	}
+	return &manifest, nil
+}
+
+// verifyBlueprints
+// loads the blueprints of the archive and compares them to the versions in the manifest
+func verifyBlueprints(manifest *Manifest, blueprintsPath string) ([]blueprint.Collection, error) {
+	loader := blueprint.NewCollectionLoader(blueprintsPath)
+	collections, err := loader.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not load blueprints: %v", err)
+	}
+	names := make([]string, 0, len(manifest.Blueprints))
+	for name := range manifest.Blueprints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		version, err := BlueprintVersion(loader, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not load blueprint %s: %v", name, err)
+		}
+		if version != manifest.Blueprints[name] {
+			return nil, fmt.Errorf("blueprint %s does not match the version it had when the backup was created", name)
+		}
+	}
+	for _, collection := range collections {
+		if _, ok := manifest.Blueprints[collection.Blueprint.CollectionName]; !ok {
+			return nil, fmt.Errorf("blueprint %s is not listed in the manifest", collection.Blueprint.CollectionName)
+		}
+	}
+	return collections, nil
+}
+
+// verifyDatabase
+// checks the integrity of the database and that it has a column for every field of the blueprints
+func verifyDatabase(filename string, collections []blueprint.Collection) error {
+	db, err := database.NewSqlite3Instance(filename)
+	if err != nil {
+		return fmt.Errorf("could not open database: %v", err)
+	}
+	defer db.Close()
+	err = db.CheckIntegrity()
+	if err != nil {
+		return err
+	}
+	for index := range collections {
+		collection := &collections[index]
+		missing, err := db.MissingColumns(collection)
+		if err != nil {
+			return fmt.Errorf("database does not match blueprint %s: %v", collection.Blueprint.CollectionName, err)
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("database does not match blueprint %s: missing columns %s", collection.Blueprint.CollectionName, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+func checksumFile(filename string) (int64, string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeFile(filename string, r io.Reader) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// move
+// renames a file or directory and falls back to copying if both paths are on different filesystems
+func move(src string, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	err = filepath.WalkDir(src, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativeName, err := filepath.Rel(src, filename)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relativeName)
+		if entry.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeFile(target, file)
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
@@ -2,51 +2,55 @@ package blueprint
 
 import (
 	"embed"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
-
-	"github.com/rangidev/rangi/sql"
 )
 
 var (
 	//go:embed blueprint
 	blueprintFS embed.FS
 
+	// Display names are English and translated with the message catalogs of the admin interface, see Label.Localize
 	defaultBlueprintFields = []BlueprintField{
 		{
 			Name:        KeyID,
-			DisplayName: "ID",
+			DisplayName: NewLabel("ID"),
 			Type:        TypeID,
 			Required:    true,
 			Hidden:      true,
 		},
 		{
 			Name:        KeyUUID,
-			DisplayName: "UUID",
+			DisplayName: NewLabel("UUID"),
 			Type:        TypeUUID,
 			Required:    true,
 			Hidden:      true,
 		},
 		{
 			Name:        KeyCollection,
-			DisplayName: "Collection",
+			DisplayName: NewLabel("Collection"),
 			Type:        TypeString,
 			Required:    true,
 			Hidden:      true,
 		},
 		{
 			Name:        KeyUpdatedAt,
-			DisplayName: "Updated at",
+			DisplayName: NewLabel("Updated at"),
 			Type:        TypeInt,
 			Required:    true,
 			Hidden:      true,
 		},
+		{
+			Name:        KeyPublishedAt,
+			DisplayName: NewLabel("Published at"),
+			Type:        TypeInt,
+			Hidden:      true,
+		},
 		{
 			Name:        KeyTitle,
-			DisplayName: "Title",
+			DisplayName: NewLabel("Title"),
 			Type:        TypeString,
 			Required:    true,
 		},
@@ -56,18 +60,32 @@ var (
 // Blueprint
 // "CollectionName" and map keys for "Fields" are vetted so that they can be safely used inside SQL statements
 type Blueprint struct {
+	Schema                string           `json:"$schema,omitempty"` // Optional URL of the JSON Schema of blueprint files, used by editors
 	CollectionName        string           `json:"collection_name"`
-	CollectionDisplayName string           `json:"collection_display_name"`
+	CollectionDisplayName Label            `json:"collection_display_name"`
+	Singleton             bool             `json:"singleton"`       // The collection has exactly one item, e. g. the settings of a site
+	LocalizedTitle        bool             `json:"localized_title"` // The default field "title" can be translated
 	Fields                []BlueprintField `json:"fields"`
+	Feed                  *BlueprintFeed   `json:"feed,omitempty"`        // Only for collections with RSS and Atom feeds
+	PreviewURL            string           `json:"preview_url,omitempty"` // URL of the frontend that shows unpublished items, see ExpandPreviewURL
 }
 
 type BlueprintField struct {
-	Name        string             `json:"name"` // Name will be used for field name in SQL tables
-	DisplayName string             `json:"display_name"`
-	Type        Type               `json:"type"`
-	Required    bool               `json:"required"`
-	Hidden      bool               `json:"hidden"`
-	Reference   BlueprintReference `json:"reference"`
+	Name        string               `json:"name"` // Name will be used for field name in SQL tables
+	DisplayName Label                `json:"display_name"`
+	Type        Type                 `json:"type"`
+	Required    bool                 `json:"required"`
+	Hidden      bool                 `json:"hidden"`
+	Localized   bool                 `json:"localized"` // Values can be translated into all configured locales
+	Reference   BlueprintReference   `json:"reference"`
+	Slug        BlueprintSlug        `json:"slug"`
+	Computed    *BlueprintComputed   `json:"computed,omitempty"`    // Only for computed fields
+	VisibleIf   []BlueprintCondition `json:"visible_if,omitempty"`  // The field is only shown if all conditions match. Required fields are only required while they are shown.
+	RequiredIf  []BlueprintCondition `json:"required_if,omitempty"` // The field is required if all conditions match
+	Component   string               `json:"component,omitempty"`   // Name of a component, only for fields of type object (one group) and array (list of groups)
+	Components  []string             `json:"components,omitempty"`  // Names of the allowed components of a dynamic zone, only for fields of type array
+	Fields      []BlueprintField     `json:"-"`                     // Fields of the component, set when the blueprint is loaded
+	Zone        []Component          `json:"-"`                     // Components of the dynamic zone, set when the blueprint is loaded
 }
 
 type BlueprintReference struct {
@@ -75,33 +93,98 @@ type BlueprintReference struct {
 	MaxReferences int    `json:"max_references"` // -1 means infinite references allowed
 }
 
+type BlueprintSlug struct {
+	Source           string `json:"source"`             // Name of the field the slug is generated from
+	LockAfterPublish bool   `json:"lock_after_publish"` // The slug can not be changed anymore once the item has been published
+}
+
+// IsDerivedField
+// returns true if the field is generated from another field, e. g. the HTML of a markdown field
+func (b *Blueprint) IsDerivedField(name string) bool {
+	for _, field := range b.Fields {
+		if field.Type == TypeMarkdown && MarkdownHTMLFieldName(field.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalizedFields
+// returns all fields whose values can be translated
+func (b *Blueprint) LocalizedFields() []BlueprintField {
+	var fields []BlueprintField
+	for _, field := range b.Fields {
+		if field.Localized {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SlugFields
+// returns all fields of type slug
+func (b *Blueprint) SlugFields() []BlueprintField {
+	var fields []BlueprintField
+	for _, field := range b.Fields {
+		if field.Type == TypeSlug {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// LoadBlueprint
+// reads and checks the blueprint of the collection. All problems of the file are returned as Problems.
 func LoadBlueprint(collectionName string, blueprintsPath string) (*Blueprint, error) {
+	data, file, err := readBlueprintFile(collectionName, blueprintsPath)
+	if err != nil {
+		return nil, err
+	}
+	blueprint, problems := parseBlueprint(collectionName, file, data)
+	if blueprint != nil {
+		problems = append(problems, resolveComponents(blueprint.Fields, file, blueprintsPath, nil)...)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return blueprint, nil
+}
+
+// readBlueprintFile
+// returns the content of the blueprint file and its location
+func readBlueprintFile(collectionName string, blueprintsPath string) ([]byte, string, error) {
 	filename := collectionName + ".json"
 	// Try directory on disk first
 	// If found, it will overwrite the embedded blueprint
-	data, err := os.ReadFile(filepath.Join(blueprintsPath, filename))
-	if err != nil {
-		// Try embed FS
-		data, err = blueprintFS.ReadFile(path.Join("blueprint", filename))
-		if err != nil {
-			return nil, fmt.Errorf("could not read blueprint data: %v", err)
-		}
+	file := filepath.Join(blueprintsPath, filename)
+	data, err := os.ReadFile(file)
+	if err == nil {
+		return data, file, nil
 	}
-	var blueprint Blueprint
-	err = json.Unmarshal(data, &blueprint)
+	// Try embed FS
+	file = path.Join("blueprint", filename)
+	data, err = blueprintFS.ReadFile(file)
 	if err != nil {
-		return nil, fmt.Errorf("could not unmarshal json data: %v", err)
+		return nil, filename, fmt.Errorf("could not read blueprint data: %v", err)
 	}
-	// Add default fields
-	blueprint.Fields = append(defaultBlueprintFields, blueprint.Fields...)
-	// Vet field names to prevent SQL injections
-	if !sql.AllowedFieldAndTableNameRegex.MatchString(blueprint.CollectionName) {
-		return nil, fmt.Errorf("invalid collection name %s", blueprint.CollectionName)
-	}
-	for _, field := range blueprint.Fields {
-		if !sql.AllowedFieldAndTableNameRegex.MatchString(field.Name) {
-			return nil, fmt.Errorf("invalid field name %s", field.Name)
+	return data, "embedded:" + file, nil
+}
+
+// addDerivedFields
+// adds hidden fields whose values are computed from other fields (e. g. rendered HTML for markdown fields)
+func addDerivedFields(fields []BlueprintField) []BlueprintField {
+	var result []BlueprintField
+	for _, field := range fields {
+		result = append(result, field)
+		if field.Type == TypeMarkdown {
+			result = append(result, BlueprintField{
+				Name:        MarkdownHTMLFieldName(field.Name),
+				DisplayName: field.DisplayName.WithSuffix(" (HTML)"),
+				Type:        TypeString,
+				Hidden:      true,
+				Localized:   field.Localized,
+			})
 		}
 	}
-	return &blueprint, nil
+	return result
 }
@@ -1,5 +1,16 @@
 package blueprint
 
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
 var (
 	defaultCollections = []string{
 		"articles",
@@ -20,21 +31,42 @@ func NewCollectionLoader(blueprintsPath string) *CollectionLoader {
 }
 
 // GetAll
-// returns all configured collections
+// returns all configured collections: the default collections and all collections in the blueprints directory
 func (cl *CollectionLoader) GetAll() ([]Collection, error) {
 	// TODO: Cache collections
+	names, err := cl.Names()
+	if err != nil {
+		return nil, err
+	}
 	var collections []Collection
-	for _, defaultCollection := range defaultCollections {
-		blueprint, err := LoadBlueprint(defaultCollection, cl.blueprintsPath)
+	for _, name := range names {
+		blueprint, err := LoadBlueprint(name, cl.blueprintsPath)
 		if err != nil {
 			return nil, err
 		}
 		collections = append(collections, Collection{Blueprint: blueprint})
 	}
-	// TODO: Load blueprints from disk
 	return collections, nil
 }
 
+// Names
+// returns the names of the default collections and of all blueprint files in the blueprints directory
+func (cl *CollectionLoader) Names() ([]string, error) {
+	names := slices.Clone(defaultCollections)
+	entries, err := os.ReadDir(cl.blueprintsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read blueprints directory: %v", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
 // Get
 // returns the named collection
 func (cl *CollectionLoader) Get(name string) (*Collection, error) {
@@ -42,6 +74,59 @@ func (cl *CollectionLoader) Get(name string) (*Collection, error) {
 	return getCollection(name, cl.blueprintsPath)
 }
 
+// File
+// returns the path of the blueprint file of the collection, as reported in problems.
+// Default collections without a file are reported with the location of their embedded blueprint.
+func (cl *CollectionLoader) File(name string) string {
+	file := filepath.Join(cl.blueprintsPath, name+".json")
+	if _, err := os.Stat(file); err != nil && slices.Contains(defaultCollections, name) {
+		return "embedded:" + path.Join("blueprint", name+".json")
+	}
+	return file
+}
+
+// Files
+// returns the contents of the blueprint file of the collection and of all component files it uses, by their names
+// relative to the blueprints directory. Embedded blueprints of default collections are returned if there is no file.
+func (cl *CollectionLoader) Files(name string) (map[string][]byte, error) {
+	collection, err := cl.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := readBlueprintFile(name, cl.blueprintsPath)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{name + ".json": data}
+	for _, componentName := range usedComponents(collection.Blueprint.Fields) {
+		filename := filepath.Join(ComponentsDirectory, componentName+".json")
+		data, err := os.ReadFile(filepath.Join(cl.blueprintsPath, filename))
+		if err != nil {
+			return nil, fmt.Errorf("could not read component: %v", err)
+		}
+		files[filepath.ToSlash(filename)] = data
+	}
+	return files, nil
+}
+
+// usedComponents
+// returns the names of the components of the fields, including nested components
+func usedComponents(fields []BlueprintField) []string {
+	var names []string
+	for _, field := range fields {
+		if field.Component != "" {
+			names = append(names, field.Component)
+		}
+		names = append(names, field.Components...)
+		names = append(names, usedComponents(field.Fields)...)
+		for _, component := range field.Zone {
+			names = append(names, usedComponents(component.Fields)...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
 func getCollection(name string, blueprintsPath string) (*Collection, error) {
 	blueprint, err := LoadBlueprint(name, blueprintsPath)
 	if err != nil {
@@ -0,0 +1,234 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rangidev/rangi/sql"
+)
+
+const (
+	// Directory in the blueprints directory that contains the component files
+	ComponentsDirectory = "components"
+	// Key of the component name in the entries of dynamic zones, so that frontends know how to render an entry
+	ComponentKey = "_component"
+)
+
+var (
+	// Types that can not be used in components, because their values are not stored in the JSON of the field
+	ComponentUnsupportedTypes = []Type{TypeID, TypeUUID, TypeReference, TypeSlug, TypeMarkdown}
+)
+
+// Component
+// is a named group of fields that can be embedded in blueprints, e. g. SEO fields or an address.
+// Values are stored as JSON object in the column of the embedding field.
+type Component struct {
+	Schema        string           `json:"$schema,omitempty"` // Optional URL of the JSON Schema of component files, used by editors
+	ComponentName string           `json:"component_name"`
+	DisplayName   Label            `json:"display_name"`
+	Fields        []BlueprintField `json:"fields"`
+}
+
+// IsRepeatable
+// returns true if the field stores a list of component values instead of a single one
+func (f *BlueprintField) IsRepeatable() bool {
+	return f.Component != "" && f.Type == TypeArray
+}
+
+// IsDynamicZone
+// returns true if the field stores a list of entries that are each one of the allowed components
+func (f *BlueprintField) IsDynamicZone() bool {
+	return len(f.Components) > 0
+}
+
+// ComponentNames
+// returns the names of all component files in the components directory
+func (cl *CollectionLoader) ComponentNames() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(cl.blueprintsPath, ComponentsDirectory))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read components directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// GetComponent
+// returns the named component with the fields of nested components
+func (cl *CollectionLoader) GetComponent(name string) (*Component, error) {
+	component, problems := loadComponent(name, cl.blueprintsPath, nil)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return component, nil
+}
+
+// loadComponent
+// reads and checks the component and resolves nested components. parents are the names of the embedding components, to detect cycles.
+func loadComponent(name string, blueprintsPath string, parents []string) (*Component, Problems) {
+	file := filepath.Join(blueprintsPath, ComponentsDirectory, name+".json")
+	if !sql.AllowedFieldAndTableNameRegex.MatchString(name) {
+		return nil, Problems{{File: file, Message: fmt.Sprintf("invalid component name %q", name)}}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, Problems{{File: file, Message: fmt.Sprintf("could not read component: %v", err)}}
+	}
+	component, problems := parseComponent(name, file, data)
+	if component == nil {
+		return nil, problems
+	}
+	problems = append(problems, resolveComponents(component.Fields, file, blueprintsPath, append(parents, name))...)
+	return component, problems
+}
+
+// resolveComponents
+// sets the fields of all fields that embed a component and the components of dynamic zones
+func resolveComponents(fields []BlueprintField, file string, blueprintsPath string, parents []string) Problems {
+	var problems Problems
+	resolve := func(field *BlueprintField, name string) *Component {
+		if slices.Contains(parents, name) {
+			problems = append(problems, Problem{File: file, Field: field.Name, Message: fmt.Sprintf("component %q contains itself", name)})
+			return nil
+		}
+		component, componentProblems := loadComponent(name, blueprintsPath, parents)
+		if component == nil {
+			problems = append(problems, Problem{File: file, Field: field.Name, Message: fmt.Sprintf("component %q does not exist or is invalid", name)})
+			return nil
+		}
+		problems = append(problems, componentProblems...)
+		return component
+	}
+	for index := range fields {
+		field := &fields[index]
+		if field.Component != "" {
+			if component := resolve(field, field.Component); component != nil {
+				field.Fields = component.Fields
+			}
+		}
+		field.Zone = nil
+		for _, name := range field.Components {
+			if component := resolve(field, name); component != nil {
+				field.Zone = append(field.Zone, *component)
+			}
+		}
+	}
+	return problems
+}
+
+// parseComponent
+// unmarshals and checks a component file. The component is nil if the file could not be unmarshaled.
+func parseComponent(componentName string, file string, data []byte) (*Component, Problems) {
+	var component Component
+	err := json.Unmarshal(data, &component)
+	if err != nil {
+		return nil, Problems{unmarshalProblem(file, data, err)}
+	}
+	var problems Problems
+	report := func(field string, format string, a ...any) {
+		problems = append(problems, Problem{File: file, Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+	for _, key := range unknownKeys(data, reflect.TypeOf(component), "") {
+		report("", "unknown key %q", key)
+	}
+	var rawFields struct {
+		Fields []json.RawMessage `json:"fields"`
+	}
+	_ = json.Unmarshal(data, &rawFields)
+	for index, rawField := range rawFields.Fields {
+		for _, key := range unknownKeys(rawField, reflect.TypeOf(BlueprintField{}), "") {
+			report(fieldLocation(component.Fields[index], index), "unknown key %q", key)
+		}
+	}
+	if component.ComponentName != componentName {
+		report("", "component name %q does not match the file name", component.ComponentName)
+	}
+	if len(component.Fields) == 0 {
+		report("", "component has no fields")
+	}
+	seen := make(map[string]bool)
+	for index, field := range component.Fields {
+		location := fieldLocation(field, index)
+		switch {
+		case field.Name == "":
+			report(location, "name is missing")
+		case !sql.AllowedFieldAndTableNameRegex.MatchString(field.Name):
+			report(location, "invalid field name")
+		case field.Name == ComponentKey:
+			report(location, "name is reserved for the component of entries of dynamic zones")
+		case seen[field.Name]:
+			report(location, "name is used more than once")
+		}
+		seen[field.Name] = true
+		if field.Type == "" {
+			report(location, "type is missing")
+		} else if !slices.Contains(AllTypes, field.Type) {
+			report(location, "unknown type %q", field.Type)
+		} else if slices.Contains(ComponentUnsupportedTypes, field.Type) {
+			report(location, "type %s can not be used in components", field.Type)
+		}
+		if field.Localized {
+			report(location, "fields of components can not be localized, localize the embedding field instead")
+		}
+		if field.Reference != (BlueprintReference{}) {
+			report(location, "reference is only allowed for fields of type reference")
+		}
+		if field.Slug != (BlueprintSlug{}) {
+			report(location, "slug is only allowed for fields of type slug")
+		}
+		if field.IsComputed() {
+			report(location, "fields of components can not be computed")
+		}
+		if field.IsConditional() {
+			report(location, "fields of components can not have conditions")
+		}
+		problems = append(problems, componentFieldProblems(field, file, location)...)
+		// Sub-fields are labeled in the edit form, so they need a display name
+		if field.DisplayName == nil {
+			component.Fields[index].DisplayName = NewLabel(field.Name)
+		}
+	}
+	return &component, problems
+}
+
+// componentFieldProblems
+// checks the component and components settings of a field
+func componentFieldProblems(field BlueprintField, file string, location string) Problems {
+	var problems Problems
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{File: file, Field: location, Message: fmt.Sprintf(format, a...)})
+	}
+	if field.Component != "" && field.Type != TypeObject && field.Type != TypeArray {
+		report("component is only allowed for fields of type object and array")
+	}
+	if field.Components == nil {
+		return problems
+	}
+	if field.Type != TypeArray {
+		report("components are only allowed for fields of type array")
+	}
+	if field.Component != "" {
+		report("component and components can not be used together")
+	}
+	if len(field.Components) == 0 {
+		report("components must not be empty")
+	}
+	for index, name := range field.Components {
+		if slices.Contains(field.Components[:index], name) {
+			report("component %q is allowed more than once", name)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,90 @@
+package blueprint
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+const (
+	// Words per minute for the readingTime function of expressions
+	readingWordsPerMinute = 200
+)
+
+var (
+	// Types of computed fields. Other types are either set by Rangi or need input from the user.
+	computedTypes = []Type{TypeString, TypeInt, TypeBoolean, TypeArray, TypeObject}
+	// Functions of expressions in addition to the hermetic sprig functions
+	expressionFuncs = template.FuncMap{
+		"wordCount":   wordCount,
+		"readingTime": readingTime,
+	}
+)
+
+// BlueprintComputed
+// defines how the value of a computed field is derived from the other fields of the item.
+// Either an expression or the name of a registered Go function is set.
+type BlueprintComputed struct {
+	Expression string `json:"expression,omitempty"` // Go template with the hermetic sprig functions, wordCount and readingTime. The item is passed as data.
+	Function   string `json:"function,omitempty"`   // Name of a function that has been registered at the server
+}
+
+// IsComputed
+// returns true if the value of the field is computed when the item is written
+func (f *BlueprintField) IsComputed() bool {
+	return f.Computed != nil
+}
+
+// ParseExpression
+// parses the expression of a computed field. Only the hermetic sprig functions, wordCount and readingTime are available,
+// so expressions that use e. g. env or now fail to parse.
+func ParseExpression(expression string) (*template.Template, error) {
+	return template.New("expression").Funcs(sprig.HermeticTxtFuncMap()).Funcs(expressionFuncs).Parse(expression)
+}
+
+// computedFieldProblems
+// checks the computed setting of a field
+func computedFieldProblems(field BlueprintField, file string, location string) Problems {
+	if !field.IsComputed() {
+		return nil
+	}
+	var problems Problems
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{File: file, Field: location, Message: fmt.Sprintf(format, a...)})
+	}
+	if (field.Computed.Expression == "") == (field.Computed.Function == "") {
+		report("computed needs either an expression or a function")
+	}
+	if field.Computed.Expression != "" {
+		_, err := ParseExpression(field.Computed.Expression)
+		if err != nil {
+			report("invalid expression: %v", err)
+		}
+	}
+	if !slices.Contains(computedTypes, field.Type) {
+		report("fields of type %s can not be computed", field.Type)
+	}
+	if field.Localized {
+		report("computed fields can not be localized")
+	}
+	if field.Component != "" || field.Components != nil {
+		report("computed fields can not embed components")
+	}
+	return problems
+}
+
+// wordCount
+// returns the number of words of the text
+func wordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// readingTime
+// returns the minutes that are needed to read the text, rounded up
+func readingTime(text string) int {
+	return int(math.Ceil(float64(wordCount(text)) / readingWordsPerMinute))
+}
@@ -0,0 +1,26 @@
+package blueprint
+
+import (
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		valid      bool
+	}{
+		{`{{ .title | upper }}`, true},
+		{`{{ readingTime .body }} min`, true},
+		{`{{ if .published_at }}{{ len .tags }}{{ end }}`, true},
+		{`{{ env "HOME" }}`, false},
+		{`{{ now | date "2006" }}`, false},
+		{`{{ if true }}{{ randAlpha 4 }}{{ end }}`, false},
+		{`{{ .title `, false},
+	}
+	for _, test := range tests {
+		_, err := ParseExpression(test.expression)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseExpression(%q) returned error %v, want valid %v", test.expression, err, test.valid)
+		}
+	}
+}
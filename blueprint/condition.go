@@ -0,0 +1,210 @@
+package blueprint
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type Operator string
+
+const (
+	OperatorEquals    = Operator("equals")
+	OperatorNotEquals = Operator("not_equals")
+	OperatorIn        = Operator("in")     // The value is an array
+	OperatorNotIn     = Operator("not_in") // The value is an array
+	OperatorEmpty     = Operator("empty")
+	OperatorNotEmpty  = Operator("not_empty")
+)
+
+var (
+	AllOperators = []Operator{OperatorEquals, OperatorNotEquals, OperatorIn, OperatorNotIn, OperatorEmpty, OperatorNotEmpty}
+)
+
+// BlueprintCondition
+// compares the value of another field of the item. Values are compared as text, so that
+// the edit form can evaluate conditions with the values of its inputs, e. g. "true" for checked checkboxes.
+type BlueprintCondition struct {
+	Field    string   `json:"field"`
+	Operator Operator `json:"operator"`
+	Value    any      `json:"value,omitempty"` // Not used by empty and not_empty
+}
+
+// Matches
+// returns true if the value of the field matches the condition
+func (c BlueprintCondition) Matches(value any) bool {
+	text := conditionText(value)
+	switch c.Operator {
+	case OperatorEquals:
+		return text == conditionText(c.Value)
+	case OperatorNotEquals:
+		return text != conditionText(c.Value)
+	case OperatorIn, OperatorNotIn:
+		values, _ := c.Value.([]any)
+		in := slices.ContainsFunc(values, func(v any) bool { return conditionText(v) == text })
+		return in == (c.Operator == OperatorIn)
+	case OperatorEmpty:
+		return IsEmptyValue(value)
+	case OperatorNotEmpty:
+		return !IsEmptyValue(value)
+	}
+	return false
+}
+
+// IsVisible
+// returns true if the field is shown in the edit form for the values of the item.
+// ok is false if the values do not contain all fields of the conditions.
+func (f *BlueprintField) IsVisible(values Item) (visible bool, ok bool) {
+	return matchAll(f.VisibleIf, values)
+}
+
+// IsRequired
+// returns true if the field needs a value for the values of the item. Fields that are not visible are never required.
+// ok is false if the values do not contain all fields of the conditions.
+func (f *BlueprintField) IsRequired(values Item) (required bool, ok bool) {
+	visible, ok := f.IsVisible(values)
+	if !ok || !visible {
+		return false, ok
+	}
+	if f.Required {
+		return true, true
+	}
+	if f.RequiredIf == nil {
+		return false, true
+	}
+	return matchAll(f.RequiredIf, values)
+}
+
+// IsConditional
+// returns true if the visibility or the requirement of the field depend on other fields
+func (f *BlueprintField) IsConditional() bool {
+	return f.VisibleIf != nil || f.RequiredIf != nil
+}
+
+// MissingRequiredValues
+// returns the names of all fields that are required for the values, but have no value, see IsEmptyValue.
+// values are all values of the item, e. g. the stored item with the written values applied. Missing values count as empty.
+// Fields whose values are set by Rangi are skipped, see IsGeneratedField.
+func (b *Blueprint) MissingRequiredValues(values Item) []string {
+	complete := maps.Clone(values)
+	if complete == nil {
+		complete = Item{}
+	}
+	for _, field := range b.Fields {
+		if _, ok := complete[field.Name]; !ok {
+			complete[field.Name] = nil
+		}
+	}
+	var names []string
+	for _, field := range b.Fields {
+		if b.IsGeneratedField(field) {
+			continue
+		}
+		required, _ := field.IsRequired(complete)
+		if required && IsEmptyValue(complete[field.Name]) {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// IsGeneratedField
+// returns true if the value of the field is set by Rangi when an item is written, e. g. identifiers, timestamps,
+// slugs that are generated from their source and computed values. References are not stored with the item.
+func (b *Blueprint) IsGeneratedField(field BlueprintField) bool {
+	switch field.Type {
+	case TypeID, TypeUUID, TypeSlug, TypeReference:
+		return true
+	}
+	return field.Name == KeyCollection || field.Name == KeyUpdatedAt || b.IsDerivedField(field.Name) || field.IsComputed()
+}
+
+// IsEmptyValue
+// returns true for missing values, blank texts and empty arrays and objects
+func IsEmptyValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		trimmed := strings.TrimSpace(v)
+		return trimmed == "" || trimmed == "[]" || trimmed == "{}"
+	case []byte:
+		return IsEmptyValue(string(v))
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+func matchAll(conditions []BlueprintCondition, values Item) (bool, bool) {
+	for _, condition := range conditions {
+		value, ok := values[condition.Field]
+		if !ok {
+			return false, false
+		}
+		if !condition.Matches(value) {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// conditionText
+// returns the text that values are compared by. Missing values are empty texts like empty inputs.
+func conditionText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// conditionProblems
+// checks the conditions of a field. fields are all fields of the blueprint.
+func conditionProblems(field BlueprintField, fields []BlueprintField, file string, location string) Problems {
+	var problems Problems
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{File: file, Field: location, Message: fmt.Sprintf(format, a...)})
+	}
+	check := func(key string, conditions []BlueprintCondition) {
+		if conditions != nil && len(conditions) == 0 {
+			report("%s must not be empty", key)
+		}
+		for index, condition := range conditions {
+			prefix := fmt.Sprintf("%s #%d", key, index+1)
+			switch {
+			case condition.Field == field.Name:
+				report("%s: a field can not depend on itself", prefix)
+			case !slices.ContainsFunc(fields, func(f BlueprintField) bool { return f.Name == condition.Field }):
+				report("%s: unknown field %q", prefix, condition.Field)
+			}
+			switch condition.Operator {
+			case OperatorEquals, OperatorNotEquals:
+				if _, ok := condition.Value.([]any); ok {
+					report("%s: value of operator %s can not be an array", prefix, condition.Operator)
+				}
+			case OperatorIn, OperatorNotIn:
+				if _, ok := condition.Value.([]any); !ok {
+					report("%s: value of operator %s must be an array", prefix, condition.Operator)
+				}
+			case OperatorEmpty, OperatorNotEmpty:
+				if condition.Value != nil {
+					report("%s: operator %s has no value", prefix, condition.Operator)
+				}
+			default:
+				report("%s: unknown operator %q", prefix, condition.Operator)
+			}
+		}
+	}
+	check("visible_if", field.VisibleIf)
+	check("required_if", field.RequiredIf)
+	if field.RequiredIf != nil && field.Required {
+		report("required_if has no effect on required fields")
+	}
+	return problems
+}
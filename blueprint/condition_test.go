@@ -0,0 +1,36 @@
+package blueprint
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMissingRequiredValues(t *testing.T) {
+	bp := &Blueprint{
+		CollectionName: "articles",
+		Fields: append(slices.Clone(defaultBlueprintFields),
+			BlueprintField{Name: "subtitle", Type: TypeString},
+			BlueprintField{Name: "kind", Type: TypeString, Required: true},
+			BlueprintField{Name: "url", Type: TypeString, RequiredIf: []BlueprintCondition{{Field: "kind", Operator: OperatorEquals, Value: "link"}}},
+			BlueprintField{Name: "body", Type: TypeMarkdown, Required: true, VisibleIf: []BlueprintCondition{{Field: "kind", Operator: OperatorEquals, Value: "text"}}},
+			BlueprintField{Name: "slug", Type: TypeSlug, Required: true, Slug: BlueprintSlug{Source: KeyTitle}},
+		),
+	}
+	tests := []struct {
+		name   string
+		values Item
+		want   []string
+	}{
+		{"complete", Item{KeyTitle: "Title", "kind": "news"}, nil},
+		{"missing", Item{}, []string{KeyTitle, "kind"}},
+		{"blank", Item{KeyTitle: " ", "kind": ""}, []string{KeyTitle, "kind"}},
+		{"required if", Item{KeyTitle: "Title", "kind": "link"}, []string{"url"}},
+		{"required while visible", Item{KeyTitle: "Title", "kind": "text", "body": ""}, []string{"body"}},
+	}
+	for _, test := range tests {
+		got := bp.MissingRequiredValues(test.values)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
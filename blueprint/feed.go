@@ -0,0 +1,63 @@
+package blueprint
+
+import (
+	"fmt"
+	"slices"
+)
+
+// BlueprintFeed
+// enables RSS and Atom feeds of the published items of a collection. The values are names of fields that are mapped to the entries of the feeds.
+type BlueprintFeed struct {
+	Title   string `json:"title,omitempty"`   // Defaults to title
+	Summary string `json:"summary,omitempty"` // Optional, markdown fields are used as rendered HTML
+	Date    string `json:"date,omitempty"`    // Field with a Unix timestamp, defaults to published_at
+	Author  string `json:"author,omitempty"`  // Optional
+}
+
+// TitleField
+// returns the name of the field that is used as title of feed entries
+func (f *BlueprintFeed) TitleField() string {
+	if f.Title == "" {
+		return KeyTitle
+	}
+	return f.Title
+}
+
+// DateField
+// returns the name of the field that is used as publication date of feed entries
+func (f *BlueprintFeed) DateField() string {
+	if f.Date == "" {
+		return KeyPublishedAt
+	}
+	return f.Date
+}
+
+// feedProblems
+// checks that the fields of the feed exist and have a suitable type
+func feedProblems(blueprint *Blueprint, fields []BlueprintField, file string) Problems {
+	if blueprint.Feed == nil {
+		return nil
+	}
+	var problems Problems
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, a...)})
+	}
+	if blueprint.Singleton {
+		report("singleton collections can not have a feed")
+	}
+	check := func(key string, name string, types ...Type) {
+		index := slices.IndexFunc(fields, func(f BlueprintField) bool { return f.Name == name })
+		switch {
+		case name == "":
+		case index < 0:
+			report("feed.%s: field %q does not exist", key, name)
+		case !slices.Contains(types, fields[index].Type):
+			report("feed.%s: field %q must be of type %v", key, name, types)
+		}
+	}
+	check("title", blueprint.Feed.Title, TypeString, TypeSlug)
+	check("summary", blueprint.Feed.Summary, TypeString, TypeMarkdown)
+	check("date", blueprint.Feed.Date, TypeInt)
+	check("author", blueprint.Feed.Author, TypeString)
+	return problems
+}
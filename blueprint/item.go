@@ -16,3 +16,50 @@ func NewItem(col *Collection) (Item, error) {
 		KeyCollection: col.Blueprint.CollectionName,
 	}, nil
 }
+
+// IsPublished
+// returns true if the item has a publication timestamp
+func (i Item) IsPublished() bool {
+	switch publishedAt := i[KeyPublishedAt].(type) {
+	case int64:
+		return publishedAt > 0
+	case int:
+		return publishedAt > 0
+	default:
+		return false
+	}
+}
+
+// NewSingletonItem
+// returns the initial values of the item of a singleton collection, with which it is created on first access.
+// The title is the display name of the collection and required fields are set to empty values.
+func NewSingletonItem(col *Collection) (Item, error) {
+	item, err := NewItem(col)
+	if err != nil {
+		return nil, err
+	}
+	item[KeyTitle] = col.Blueprint.CollectionDisplayName.String()
+	if item[KeyTitle] == "" {
+		item[KeyTitle] = col.Blueprint.CollectionName
+	}
+	for _, field := range col.Blueprint.CustomFields() {
+		if !field.Required {
+			continue
+		}
+		switch field.Type {
+		case TypeBoolean:
+			item[field.Name] = false
+		case TypeInt:
+			item[field.Name] = int64(0)
+		case TypeArray:
+			item[field.Name] = "[]"
+		case TypeObject:
+			item[field.Name] = "{}"
+		case TypeReference:
+			// References are not stored in the table of the collection
+		default:
+			item[field.Name] = ""
+		}
+	}
+	return item, nil
+}
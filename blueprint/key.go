@@ -1,9 +1,10 @@
 package blueprint
 
 const (
-	KeyID         = "id"
-	KeyUUID       = "uuid"
-	KeyCollection = "collection"
-	KeyTitle      = "title"
-	KeyUpdatedAt  = "updated_at"
+	KeyID          = "id"
+	KeyUUID        = "uuid"
+	KeyCollection  = "collection"
+	KeyTitle       = "title"
+	KeyUpdatedAt   = "updated_at"
+	KeyPublishedAt = "published_at" // 0 or NULL means the item has not been published
 )
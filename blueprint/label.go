@@ -0,0 +1,111 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Locale used for display names that are given as a plain string
+	labelLocaleAny = ""
+	// Locale used if there is no label for the requested locale
+	labelFallbackLocale = "en"
+)
+
+// Translator
+// returns the translation of an English message into the locale, e. g. from the message catalogs of the admin interface
+type Translator func(locale string, message string) string
+
+// Label
+// is a display name that can be translated.
+// In blueprint files it is given either as a plain string or as a map of locale to label.
+type Label map[string]string
+
+func NewLabel(s string) Label {
+	return Label{labelLocaleAny: s}
+}
+
+func (l *Label) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = NewLabel(s)
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("label must be a string or a map of locale to string")
+	}
+	*l = Label(m)
+	return nil
+}
+
+func (l Label) MarshalJSON() ([]byte, error) {
+	if s, ok := l[labelLocaleAny]; ok && len(l) == 1 {
+		return json.Marshal(s)
+	}
+	return json.Marshal(map[string]string(l))
+}
+
+// Resolve
+// returns the label for the locale, falling back to the base language (e. g. "de" for "de-AT"), English and finally any label
+func (l Label) Resolve(locale string) string {
+	candidates := []string{locale}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, labelLocaleAny, labelFallbackLocale)
+	for _, candidate := range candidates {
+		if s, ok := l[candidate]; ok {
+			return s
+		}
+	}
+	// Use the first label in alphabetical order of locales to be deterministic
+	locales := make([]string, 0, len(l))
+	for locale := range l {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	if len(locales) > 0 {
+		return l[locales[0]]
+	}
+	return ""
+}
+
+// Localize
+// returns the label for the locale like Resolve. If the label has no value for the locale or its base language,
+// e. g. the English labels of the default fields, the English label is translated instead. translate may be nil.
+func (l Label) Localize(locale string, translate Translator) string {
+	if translate == nil || l.has(locale) {
+		return l.Resolve(locale)
+	}
+	return translate(locale, l.String())
+}
+
+// has
+// returns true if the label has a value for the locale or its base language
+func (l Label) has(locale string) bool {
+	if _, ok := l[locale]; ok {
+		return true
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	_, ok := l[base]
+	return ok
+}
+
+// String
+// returns the label in the fallback locale
+func (l Label) String() string {
+	return l.Resolve(labelFallbackLocale)
+}
+
+// WithSuffix
+// returns a copy of the label with the suffix appended in all locales
+func (l Label) WithSuffix(suffix string) Label {
+	result := make(Label, len(l))
+	for locale, s := range l {
+		result[locale] = s + suffix
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// Placeholder of preview URLs that is replaced by the preview token
+	PreviewTokenPlaceholder = "token"
+)
+
+var (
+	previewPlaceholderRegex = regexp.MustCompile(`\{([^}]*)\}`)
+)
+
+// ExpandPreviewURL
+// returns the preview URL of the blueprint for the item. {token} is replaced by the token,
+// other placeholders by the escaped values of the fields with that name, e. g. {slug}.
+func (b *Blueprint) ExpandPreviewURL(item Item, token string) string {
+	return previewPlaceholderRegex.ReplaceAllStringFunc(b.PreviewURL, func(placeholder string) string {
+		name := strings.Trim(placeholder, "{}")
+		if name == PreviewTokenPlaceholder {
+			return url.QueryEscape(token)
+		}
+		var value string
+		switch v := item[name].(type) {
+		case nil:
+		case []byte:
+			value = string(v)
+		default:
+			value = fmt.Sprint(v)
+		}
+		return url.PathEscape(value)
+	})
+}
+
+// previewURLProblems
+// checks that the preview URL is an absolute URL or a path, contains the token and only refers to existing fields
+func previewURLProblems(blueprint *Blueprint, fields []BlueprintField, file string) Problems {
+	if blueprint.PreviewURL == "" {
+		return nil
+	}
+	var problems Problems
+	report := func(format string, a ...any) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, a...)})
+	}
+	hasToken := false
+	for _, match := range previewPlaceholderRegex.FindAllStringSubmatch(blueprint.PreviewURL, -1) {
+		switch {
+		case match[1] == PreviewTokenPlaceholder:
+			hasToken = true
+		case !slices.ContainsFunc(fields, func(f BlueprintField) bool { return f.Name == match[1] }):
+			report("preview_url: field %q does not exist", match[1])
+		}
+	}
+	if !hasToken {
+		report("preview_url: {%s} is missing", PreviewTokenPlaceholder)
+	}
+	u, err := url.Parse(previewPlaceholderRegex.ReplaceAllString(blueprint.PreviewURL, "x"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && !strings.HasPrefix(blueprint.PreviewURL, "/")) {
+		report("preview_url: must be an http(s) URL or a path that starts with /")
+	}
+	return problems
+}
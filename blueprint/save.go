@@ -0,0 +1,160 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// fileBlueprint
+// is the format of blueprint files. Empty settings are left out to keep the files readable.
+type fileBlueprint struct {
+	Schema                string         `json:"$schema,omitempty"`
+	CollectionDisplayName Label          `json:"collection_display_name,omitempty"`
+	CollectionName        string         `json:"collection_name"`
+	Singleton             bool           `json:"singleton,omitempty"`
+	LocalizedTitle        bool           `json:"localized_title,omitempty"`
+	Fields                []fileField    `json:"fields"`
+	Feed                  *BlueprintFeed `json:"feed,omitempty"`
+	PreviewURL            string         `json:"preview_url,omitempty"`
+}
+
+type fileField struct {
+	Name        string               `json:"name"`
+	DisplayName Label                `json:"display_name,omitempty"`
+	Type        Type                 `json:"type"`
+	Required    bool                 `json:"required,omitempty"`
+	Hidden      bool                 `json:"hidden,omitempty"`
+	Localized   bool                 `json:"localized,omitempty"`
+	Reference   *BlueprintReference  `json:"reference,omitempty"`
+	Slug        *BlueprintSlug       `json:"slug,omitempty"`
+	Computed    *BlueprintComputed   `json:"computed,omitempty"`
+	VisibleIf   []BlueprintCondition `json:"visible_if,omitempty"`
+	RequiredIf  []BlueprintCondition `json:"required_if,omitempty"`
+	Component   string               `json:"component,omitempty"`
+	Components  []string             `json:"components,omitempty"`
+}
+
+// IsDefaultField
+// returns true if every blueprint has the field, e. g. "id" or "title"
+func IsDefaultField(name string) bool {
+	return slices.ContainsFunc(defaultBlueprintFields, func(f BlueprintField) bool { return f.Name == name })
+}
+
+// CustomFields
+// returns the fields that are defined in the blueprint file, without default and derived fields
+func (b *Blueprint) CustomFields() []BlueprintField {
+	var fields []BlueprintField
+	for _, field := range b.Fields {
+		if !IsDefaultField(field.Name) && !b.IsDerivedField(field.Name) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// MarshalFile
+// returns the content of the blueprint file. The blueprint must only contain the fields of the file,
+// use CustomFields to remove default and derived fields from a loaded blueprint.
+func (b *Blueprint) MarshalFile() ([]byte, error) {
+	file := fileBlueprint{
+		Schema:                b.Schema,
+		CollectionDisplayName: b.CollectionDisplayName,
+		CollectionName:        b.CollectionName,
+		Singleton:             b.Singleton,
+		LocalizedTitle:        b.LocalizedTitle,
+		Fields:                []fileField{},
+		Feed:                  b.Feed,
+		PreviewURL:            b.PreviewURL,
+	}
+	for _, field := range b.Fields {
+		f := fileField{
+			Name:        field.Name,
+			DisplayName: field.DisplayName,
+			Type:        field.Type,
+			Required:    field.Required,
+			Hidden:      field.Hidden,
+			Localized:   field.Localized,
+			Computed:    field.Computed,
+			VisibleIf:   field.VisibleIf,
+			RequiredIf:  field.RequiredIf,
+			Component:   field.Component,
+			Components:  field.Components,
+		}
+		if field.Reference != (BlueprintReference{}) {
+			reference := field.Reference
+			f.Reference = &reference
+		}
+		if field.Slug != (BlueprintSlug{}) {
+			slug := field.Slug
+			f.Slug = &slug
+		}
+		file.Fields = append(file.Fields, f)
+	}
+	data, err := json.MarshalIndent(file, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Check
+// validates the blueprint as if it was saved and returns it with default and derived fields.
+// References must point to existing collections or to the blueprint itself.
+func (cl *CollectionLoader) Check(b *Blueprint) (*Blueprint, Problems) {
+	file := filepath.Join(cl.blueprintsPath, b.CollectionName+".json")
+	data, err := b.MarshalFile()
+	if err != nil {
+		return nil, Problems{{File: file, Message: fmt.Sprintf("could not marshal blueprint: %v", err)}}
+	}
+	parsed, problems := parseBlueprint(b.CollectionName, file, data)
+	if parsed == nil {
+		return nil, problems
+	}
+	names, err := cl.Names()
+	if err != nil {
+		return nil, append(problems, Problem{File: cl.blueprintsPath, Message: err.Error()})
+	}
+	if !slices.Contains(names, b.CollectionName) {
+		names = append(names, b.CollectionName)
+	}
+	problems = append(problems, referenceProblems(parsed, file, names)...)
+	problems = append(problems, resolveComponents(parsed.Fields, file, cl.blueprintsPath, nil)...)
+	return parsed, problems
+}
+
+// Save
+// validates the blueprint and writes it to the blueprints directory, see MarshalFile.
+// Nothing is written if the blueprint is invalid, the problems are returned as error in that case.
+func (cl *CollectionLoader) Save(b *Blueprint) error {
+	_, problems := cl.Check(b)
+	if len(problems) > 0 {
+		return problems
+	}
+	data, err := b.MarshalFile()
+	if err != nil {
+		return fmt.Errorf("could not marshal blueprint: %v", err)
+	}
+	err = os.MkdirAll(cl.blueprintsPath, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not make directory for blueprints: %v", err)
+	}
+	// Write to a temporary file first, so that the blueprint is never read partially
+	file, err := os.CreateTemp(cl.blueprintsPath, ".blueprint-*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not write blueprint: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("could not close blueprint: %v", err)
+	}
+	return os.Rename(file.Name(), filepath.Join(cl.blueprintsPath, b.CollectionName+".json"))
+}
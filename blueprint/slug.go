@@ -0,0 +1,59 @@
+package blueprint
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// Letters that can not be decomposed into a base letter and a combining mark
+	slugTransliterations = map[rune]string{
+		'ß': "ss",
+		'æ': "ae",
+		'Æ': "ae",
+		'œ': "oe",
+		'Œ': "oe",
+		'ø': "o",
+		'Ø': "o",
+		'ł': "l",
+		'Ł': "l",
+		'đ': "d",
+		'Đ': "d",
+		'ð': "d",
+		'Ð': "d",
+		'þ': "th",
+		'Þ': "th",
+		'ı': "i",
+	}
+)
+
+// Slugify
+// converts a string into a lowercase, URL safe representation that only contains ASCII letters, digits and dashes
+func Slugify(s string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			// Drop combining marks (accents) left over by the decomposition
+			continue
+		}
+		if transliteration, ok := slugTransliterations[r]; ok {
+			builder.WriteString(transliteration)
+			dash = false
+			continue
+		}
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && builder.Len() > 0 {
+			builder.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(builder.String(), "-")
+}
@@ -1,6 +1,7 @@
 package blueprint
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
 )
@@ -16,13 +17,158 @@ const (
 	TypeArray     = Type("array")
 	TypeObject    = Type("object")
 	TypeReference = Type("reference")
+	TypeMarkdown  = Type("markdown")
+	TypeSlug      = Type("slug")
+	TypeAsset     = Type("asset") // Stores the UUID of an uploaded asset
+
+	markdownHTMLFieldSuffix = "_html"
+)
+
+var (
+	AllTypes = []Type{
+		TypeID,
+		TypeUUID,
+		TypeString,
+		TypeBoolean,
+		TypeInt,
+		TypeArray,
+		TypeObject,
+		TypeReference,
+		TypeMarkdown,
+		TypeSlug,
+		TypeAsset,
+	}
 )
 
+// MarkdownHTMLFieldName
+// returns the name of the derived field that stores the rendered HTML of a markdown field
+func MarkdownHTMLFieldName(fieldName string) string {
+	return fieldName + markdownHTMLFieldSuffix
+}
+
+// IsLocalizable
+// returns true if values of the type can be translated.
+// Identifiers, references and slugs are shared between all locales.
+func (t Type) IsLocalizable() bool {
+	switch t {
+	case TypeID, TypeUUID, TypeReference, TypeSlug:
+		return false
+	default:
+		return true
+	}
+}
+
 // HTMLInputType
 // used in templates to determine the WebComponent for the edit form
 func (t Type) EditComponent(blueprintField *BlueprintField, item Item) template.HTML {
 	webComponent := determinewebComponentName(blueprintField)
-	return template.HTML(fmt.Sprintf(`<%s id="%s">%v</%s>`, webComponent, blueprintField.Name, item[blueprintField.Name], webComponent))
+	if blueprintField.IsComputed() {
+		// Computed values are shown, but not sent, because they are computed again when the item is written
+		value := ""
+		if item[blueprintField.Name] != nil {
+			value = fmt.Sprintf("%v", item[blueprintField.Name])
+		}
+		if i, ok := item[blueprintField.Name].(int64); ok && blueprintField.Type == TypeBoolean {
+			// Booleans are stored as integers in sqlite3
+			value = fmt.Sprintf("%v", i != 0)
+		}
+		return template.HTML(fmt.Sprintf(`<input type="text" class="form-control" id="%s" value="%s" placeholder="%s" readonly>`, blueprintField.Name, template.HTMLEscapeString(value), blueprintField.Name))
+	}
+	if blueprintField.Component != "" || blueprintField.IsDynamicZone() {
+		// Values of components are stored as JSON text
+		var value string
+		switch v := item[blueprintField.Name].(type) {
+		case string:
+			value = v
+		case []byte:
+			value = string(v)
+		}
+		attributes := ""
+		if blueprintField.IsDynamicZone() {
+			components, _ := json.Marshal(componentViews(blueprintField.Zone))
+			attributes = fmt.Sprintf(` components="%s"`, template.HTMLEscapeString(string(components)))
+		} else {
+			fields, _ := json.Marshal(componentFieldViews(blueprintField.Fields))
+			attributes = fmt.Sprintf(` fields="%s"`, template.HTMLEscapeString(string(fields)))
+			if blueprintField.IsRepeatable() {
+				attributes += " repeatable"
+			}
+		}
+		return template.HTML(fmt.Sprintf(`<%s id="%s" name="%s" value="%s"%s></%s>`, webComponent, blueprintField.Name, blueprintField.Name, template.HTMLEscapeString(value), attributes, webComponent))
+	}
+	switch blueprintField.Type {
+	case TypeMarkdown:
+		// Markdown is edited as raw text and must not be interpreted by the browser
+		value := ""
+		if item[blueprintField.Name] != nil {
+			value = template.HTMLEscapeString(fmt.Sprintf("%v", item[blueprintField.Name]))
+		}
+		return template.HTML(fmt.Sprintf(`<%s id="%s" name="%s">%s</%s>`, webComponent, blueprintField.Name, blueprintField.Name, value, webComponent))
+	case TypeSlug:
+		value, _ := item[blueprintField.Name].(string)
+		readonly := ""
+		if blueprintField.Slug.LockAfterPublish && item.IsPublished() {
+			readonly = " readonly"
+		}
+		return template.HTML(fmt.Sprintf(`<%s id="%s" name="%s" source="%s" value="%s"%s></%s>`, webComponent, blueprintField.Name, blueprintField.Name, blueprintField.Slug.Source, template.HTMLEscapeString(value), readonly, webComponent))
+	case TypeAsset:
+		value, _ := item[blueprintField.Name].(string)
+		return template.HTML(fmt.Sprintf(`<%s id="%s" name="%s" value="%s"></%s>`, webComponent, blueprintField.Name, blueprintField.Name, template.HTMLEscapeString(value), webComponent))
+	}
+	value := item[blueprintField.Name]
+	if value == nil {
+		// Missing values, e. g. of new items or untranslated fields
+		value = ""
+	}
+	return template.HTML(fmt.Sprintf(`<%s id="%s">%v</%s>`, webComponent, blueprintField.Name, value, webComponent))
+}
+
+// componentFieldView
+// describes a field of a component for the rangi-component WebComponent
+type componentFieldView struct {
+	Name        string               `json:"name"`
+	DisplayName Label                `json:"display_name"`
+	Type        Type                 `json:"type"`
+	Required    bool                 `json:"required"`
+	Repeatable  bool                 `json:"repeatable,omitempty"`
+	Fields      []componentFieldView `json:"fields,omitempty"`     // Fields of a nested component
+	Components  []componentView      `json:"components,omitempty"` // Components of a nested dynamic zone
+}
+
+// componentView
+// describes a component of a dynamic zone for the rangi-component WebComponent
+type componentView struct {
+	Name        string               `json:"name"`
+	DisplayName Label                `json:"display_name"`
+	Fields      []componentFieldView `json:"fields"`
+}
+
+func componentFieldViews(fields []BlueprintField) []componentFieldView {
+	views := make([]componentFieldView, len(fields))
+	for index, field := range fields {
+		views[index] = componentFieldView{
+			Name:        field.Name,
+			DisplayName: field.DisplayName,
+			Type:        field.Type,
+			Required:    field.Required,
+			Repeatable:  field.IsRepeatable(),
+			Fields:      componentFieldViews(field.Fields),
+			Components:  componentViews(field.Zone),
+		}
+	}
+	return views
+}
+
+func componentViews(components []Component) []componentView {
+	views := make([]componentView, len(components))
+	for index, component := range components {
+		displayName := component.DisplayName
+		if displayName == nil {
+			displayName = NewLabel(component.ComponentName)
+		}
+		views[index] = componentView{Name: component.ComponentName, DisplayName: displayName, Fields: componentFieldViews(component.Fields)}
+	}
+	return views
 }
 
 func determinewebComponentName(blueprintField *BlueprintField) string {
@@ -31,6 +177,9 @@ func determinewebComponentName(blueprintField *BlueprintField) string {
 	case KeyTitle:
 		return "rangi-title"
 	}
+	if blueprintField.Component != "" || blueprintField.IsDynamicZone() {
+		return "rangi-component"
+	}
 	// Determine by Type
 	switch blueprintField.Type {
 	case TypeID:
@@ -49,6 +198,12 @@ func determinewebComponentName(blueprintField *BlueprintField) string {
 		return "rangi-text"
 	case TypeReference:
 		return "rangi-reference"
+	case TypeMarkdown:
+		return "rangi-markdown"
+	case TypeSlug:
+		return "rangi-slug"
+	case TypeAsset:
+		return "rangi-asset"
 	}
 	return "rangi-text"
 }
@@ -0,0 +1,286 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rangidev/rangi/sql"
+)
+
+// Problem
+// describes a mistake in a blueprint file
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`  // Only known for syntax and type errors
+	Field   string `json:"field,omitempty"` // Name of the field, or its position if it has no name
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	location := p.File
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, p.Line)
+	}
+	if p.Field != "" {
+		location = fmt.Sprintf("%s: field %s", location, p.Field)
+	}
+	return location + ": " + p.Message
+}
+
+// Problems
+// can be returned as error that lists all problems
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for index, problem := range p {
+		lines[index] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate
+// checks all blueprints and components and returns every problem that has been found,
+// including references to missing collections and components
+func (cl *CollectionLoader) Validate() Problems {
+	names, err := cl.Names()
+	if err != nil {
+		return Problems{{File: cl.blueprintsPath, Message: err.Error()}}
+	}
+	componentNames, err := cl.ComponentNames()
+	if err != nil {
+		return Problems{{File: cl.blueprintsPath, Message: err.Error()}}
+	}
+	var problems Problems
+	for _, name := range componentNames {
+		file := filepath.Join(cl.blueprintsPath, ComponentsDirectory, name+".json")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			problems = append(problems, Problem{File: file, Message: err.Error()})
+			continue
+		}
+		component, componentProblems := parseComponent(name, file, data)
+		problems = append(problems, componentProblems...)
+		if component != nil {
+			problems = append(problems, resolveComponents(component.Fields, file, cl.blueprintsPath, []string{name})...)
+		}
+	}
+	for _, name := range names {
+		data, file, err := readBlueprintFile(name, cl.blueprintsPath)
+		if err != nil {
+			problems = append(problems, Problem{File: file, Message: err.Error()})
+			continue
+		}
+		blueprint, fileProblems := parseBlueprint(name, file, data)
+		problems = append(problems, fileProblems...)
+		if blueprint == nil {
+			continue
+		}
+		problems = append(problems, referenceProblems(blueprint, file, names)...)
+		problems = append(problems, resolveComponents(blueprint.Fields, file, cl.blueprintsPath, nil)...)
+	}
+	// Problems of components are found again for every blueprint that embeds them
+	var unique Problems
+	for _, problem := range problems {
+		if !slices.Contains(unique, problem) {
+			unique = append(unique, problem)
+		}
+	}
+	return unique
+}
+
+// referenceProblems
+// reports references to collections that are not in names
+func referenceProblems(blueprint *Blueprint, file string, names []string) Problems {
+	var problems Problems
+	for _, field := range blueprint.Fields {
+		if field.Type == TypeReference && field.Reference.Collection != "" && !slices.Contains(names, field.Reference.Collection) {
+			problems = append(problems, Problem{File: file, Field: field.Name, Message: fmt.Sprintf("referenced collection %q does not exist", field.Reference.Collection)})
+		}
+	}
+	return problems
+}
+
+// parseBlueprint
+// unmarshals and checks a blueprint file and adds the default and derived fields.
+// The blueprint is nil if the file could not be unmarshaled.
+func parseBlueprint(collectionName string, file string, data []byte) (*Blueprint, Problems) {
+	var blueprint Blueprint
+	err := json.Unmarshal(data, &blueprint)
+	if err != nil {
+		return nil, Problems{unmarshalProblem(file, data, err)}
+	}
+	var problems Problems
+	report := func(field string, format string, a ...any) {
+		problems = append(problems, Problem{File: file, Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+	// Unknown keys are usually typos, which would otherwise be ignored silently
+	for _, key := range unknownKeys(data, reflect.TypeOf(blueprint), "") {
+		report("", "unknown key %q", key)
+	}
+	var rawFields struct {
+		Fields []json.RawMessage `json:"fields"`
+	}
+	_ = json.Unmarshal(data, &rawFields)
+	for index, rawField := range rawFields.Fields {
+		for _, key := range unknownKeys(rawField, reflect.TypeOf(BlueprintField{}), "") {
+			report(fieldLocation(blueprint.Fields[index], index), "unknown key %q", key)
+		}
+	}
+	// Collection
+	if !sql.AllowedFieldAndTableNameRegex.MatchString(blueprint.CollectionName) {
+		report("", "invalid collection name %q", blueprint.CollectionName)
+	} else if blueprint.CollectionName != collectionName {
+		report("", "collection name %q does not match the file name", blueprint.CollectionName)
+	}
+	// Fields
+	defaultFields := slices.Clone(defaultBlueprintFields)
+	for index := range defaultFields {
+		if defaultFields[index].Name == KeyTitle {
+			defaultFields[index].Localized = blueprint.LocalizedTitle
+		}
+	}
+	allFields := addDerivedFields(append(defaultFields, blueprint.Fields...))
+	seen := make(map[string]bool)
+	for _, field := range defaultBlueprintFields {
+		seen[field.Name] = true
+	}
+	for index, field := range blueprint.Fields {
+		location := fieldLocation(field, index)
+		switch {
+		case field.Name == "":
+			report(location, "name is missing")
+		case !sql.AllowedFieldAndTableNameRegex.MatchString(field.Name):
+			report(location, "invalid field name")
+		case slices.ContainsFunc(defaultBlueprintFields, func(f BlueprintField) bool { return f.Name == field.Name }):
+			report(location, "name is reserved for a default field")
+		case seen[field.Name]:
+			report(location, "name is used more than once")
+		}
+		seen[field.Name] = true
+		if field.Type == "" {
+			report(location, "type is missing")
+		} else if !slices.Contains(AllTypes, field.Type) {
+			report(location, "unknown type %q", field.Type)
+		} else if field.Type == TypeID || field.Type == TypeUUID {
+			report(location, "type %s is reserved for default fields", field.Type)
+		}
+		if field.Localized && !field.Type.IsLocalizable() {
+			report(location, "field of type %s can not be localized", field.Type)
+		}
+		// Reference
+		if field.Type == TypeReference {
+			if field.Reference.Collection == "" {
+				report(location, "reference.collection is missing")
+			}
+			if field.Reference.MaxReferences == 0 || field.Reference.MaxReferences < -1 {
+				report(location, "reference.max_references must be -1 (unlimited) or at least 1")
+			}
+		} else if field.Reference != (BlueprintReference{}) {
+			report(location, "reference is only allowed for fields of type reference")
+		}
+		// Slug
+		if field.Type == TypeSlug {
+			if !slices.ContainsFunc(allFields, func(f BlueprintField) bool { return f.Name == field.Slug.Source }) {
+				report(location, "invalid slug source %q", field.Slug.Source)
+			}
+		} else if field.Slug != (BlueprintSlug{}) {
+			report(location, "slug is only allowed for fields of type slug")
+		}
+		problems = append(problems, componentFieldProblems(field, file, location)...)
+		problems = append(problems, computedFieldProblems(field, file, location)...)
+		problems = append(problems, conditionProblems(field, allFields, file, location)...)
+	}
+	problems = append(problems, feedProblems(&blueprint, allFields, file)...)
+	problems = append(problems, previewURLProblems(&blueprint, allFields, file)...)
+	// Derived fields must not collide with other fields
+	for _, field := range blueprint.Fields {
+		if field.Type == TypeMarkdown && seen[MarkdownHTMLFieldName(field.Name)] {
+			report(field.Name, "name of the derived field %s is already used", MarkdownHTMLFieldName(field.Name))
+		}
+	}
+	blueprint.Fields = allFields
+	return &blueprint, problems
+}
+
+// unmarshalProblem
+// describes an error of json.Unmarshal, including the line for syntax and type errors
+func unmarshalProblem(file string, data []byte, err error) Problem {
+	problem := Problem{File: file, Message: fmt.Sprintf("could not unmarshal json data: %v", err)}
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &syntaxError) {
+		problem.Line = lineOfOffset(data, syntaxError.Offset)
+	} else if errors.As(err, &typeError) {
+		problem.Line = lineOfOffset(data, typeError.Offset)
+	}
+	return problem
+}
+
+// unknownKeys
+// returns the keys of the JSON object that do not belong to the struct type, including keys of nested objects
+func unknownKeys(data []byte, typ reflect.Type, prefix string) []string {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(data, &object) != nil {
+		return nil
+	}
+	var keys []string
+	for key, value := range object {
+		field, ok := fieldByJSONName(typ, key)
+		if !ok {
+			keys = append(keys, prefix+key)
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			keys = append(keys, unknownKeys(value, fieldType, prefix+key+".")...)
+		}
+		if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct && fieldType.Elem() != reflect.TypeOf(BlueprintField{}) {
+			// Lists of objects, e. g. conditions. Fields of blueprints are checked separately.
+			var elements []json.RawMessage
+			_ = json.Unmarshal(value, &elements)
+			for index, element := range elements {
+				keys = append(keys, unknownKeys(element, fieldType.Elem(), fmt.Sprintf("%s%s.%d.", prefix, key, index))...)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fieldByJSONName(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for index := range typ.NumField() {
+		field := typ.Field(index)
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldLocation
+// returns the name of the field or its position, if the name is missing
+func fieldLocation(field BlueprintField, index int) string {
+	if field.Name != "" {
+		return field.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+func lineOfOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return strings.Count(string(data[:offset]), "\n") + 1
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rangidev/rangi/backup"
+	"github.com/rangidev/rangi/config"
+)
+
+// runBackup
+// writes an archive with the blueprints, the database and the local assets and prints its filename.
+// The server can keep running during the backup.
+func runBackup(flags *flag.FlagSet, args []string) error {
+	output := flags.String("output", "", "Archive file, rangi-backup-<time>.tar.gz in the current directory if empty, stdout if \"-\"")
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	if *output == "-" {
+		_, err := backup.Create(context.Background(), config, os.Stdout)
+		return err
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("rangi-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+	// Write to a temporary file first, so that a failed backup does not leave a partial archive
+	file, err := os.CreateTemp(filepath.Dir(*output), ".rangi-backup-*")
+	if err != nil {
+		return fmt.Errorf("could not create archive: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = backup.Create(context.Background(), config, file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not create backup: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("could not close archive: %v", err)
+	}
+	err = os.Rename(file.Name(), *output)
+	if err != nil {
+		return fmt.Errorf("could not move archive: %v", err)
+	}
+	fmt.Println(*output)
+	return nil
+}
+
+// runRestore
+// replaces the blueprints, the database and the local assets with the content of an archive and prints the result as JSON.
+// The server has to be stopped before restoring.
+func runRestore(flags *flag.FlagSet, args []string) error {
+	allowChangedBlueprints := flags.Bool("allow-changed-blueprints", false, "Restore even if blueprints differ from the ones in use")
+	err := parseFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not open archive: %v", err)
+	}
+	defer file.Close()
+	config := config.New()
+	result, err := backup.Restore(config, file, backup.RestoreOptions{AllowChangedBlueprints: *allowChangedBlueprints})
+	if result != nil {
+		printJSON(result)
+	}
+	if errors.Is(err, backup.ErrorInvalidArchive) || errors.Is(err, backup.ErrorChangedBlueprints) {
+		return newInvalidError("could not restore backup: %v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("could not restore backup: %v", err)
+	}
+	return nil
+}
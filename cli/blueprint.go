@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/jsonschema"
+)
+
+// runBlueprintValidate
+// checks all blueprints and prints every problem with its location
+func runBlueprintValidate(flags *flag.FlagSet, args []string) error {
+	jsonOutput := flags.Bool("json", false, "Print the problems as JSON array")
+	err := parseFlags(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	blueprintsPath := flags.Arg(0)
+	if blueprintsPath == "" {
+		blueprintsPath = config.New().BlueprintsPath
+	}
+	problems := blueprint.NewCollectionLoader(blueprintsPath).Validate()
+	if *jsonOutput {
+		if problems == nil {
+			problems = blueprint.Problems{}
+		}
+		printJSON(problems)
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	}
+	if len(problems) > 0 {
+		return newInvalidError("found %d problems", len(problems))
+	}
+	return nil
+}
+
+// runBlueprintSchema
+// prints the JSON Schema of blueprint files or of the items of a collection
+func runBlueprintSchema(flags *flag.FlagSet, args []string) error {
+	locale := flags.String("locale", "en", "Locale of titles in the schema of a collection")
+	component := flags.Bool("component", false, "Print the JSON Schema of component files")
+	err := parseFlags(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if *component {
+		printJSON(jsonschema.Component())
+		return nil
+	}
+	if flags.NArg() == 0 {
+		printJSON(jsonschema.Blueprint())
+		return nil
+	}
+	collection, err := blueprint.NewCollectionLoader(config.New().BlueprintsPath).Get(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not get collection: %v", err)
+	}
+	catalogs, err := admin.EmbeddedCatalogs()
+	if err != nil {
+		return fmt.Errorf("could not load message catalogs: %v", err)
+	}
+	printJSON(jsonschema.Collection(collection, *locale, catalogs.Translator()))
+	return nil
+}
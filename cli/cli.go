@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes
+const (
+	ExitOK = 0
+	// The command failed
+	ExitError = 1
+	// The command was called with invalid arguments
+	ExitUsage = 2
+	// The command ran, but its input is invalid, e. g. blueprints with errors or import rows that could not be imported
+	ExitInvalid = 3
+)
+
+// Command
+// is a subcommand of rangi. Commands write their results to stdout and everything else to stderr.
+type Command struct {
+	Name        string
+	Args        string // Shown in the usage after the flags
+	Description string
+	Run         func(flags *flag.FlagSet, args []string) error
+	Subcommands []Command
+}
+
+var (
+	errorUsage = errors.New("invalid usage")
+)
+
+// invalidError
+// signals that the input of a command is invalid, see ExitInvalid
+type invalidError struct {
+	message string
+}
+
+func (e *invalidError) Error() string {
+	return e.message
+}
+
+func newInvalidError(format string, a ...any) error {
+	return &invalidError{message: fmt.Sprintf(format, a...)}
+}
+
+// Commands
+// returns all commands. Running rangi without a command is the same as "rangi serve".
+func Commands() []Command {
+	return []Command{
+		{Name: "serve", Description: "Start the server", Run: runServe},
+		{Name: "migrate", Description: "Create and update the database tables and print the migrated collections", Run: runMigrate},
+		{Name: "user", Description: "Manage users", Subcommands: []Command{
+			{Name: "create", Description: "Create a user and print the generated password", Run: runUserCreate},
+			{Name: "reset-password", Args: "<email>", Description: "Set a new password, end all sessions of the user and print the generated password", Run: runUserResetPassword},
+		}},
+		{Name: "blueprint", Description: "Work with blueprints", Subcommands: []Command{
+			{Name: "validate", Args: "[blueprints path]", Description: "Check all blueprints and print every problem", Run: runBlueprintValidate},
+			{Name: "schema", Args: "[collection]", Description: "Print the JSON Schema of blueprint files, component files or the items of a collection", Run: runBlueprintSchema},
+		}},
+		{Name: "build", Description: "Render the site of the configured theme into static files and print the result as JSON", Run: runBuild},
+		{Name: "export", Description: "Export items of one or all collections", Run: runExport},
+		{Name: "import", Args: "<file>", Description: "Import items and print the report as JSON", Run: runImport},
+		{Name: "backup", Description: "Create an archive with the blueprints, the database and the local assets and print its filename", Run: runBackup},
+		{Name: "restore", Args: "<archive>", Description: "Replace the blueprints, the database and the local assets with the content of an archive. Stop the server first.", Run: runRestore},
+	}
+}
+
+// Run
+// executes the command given by the arguments (without the program name) and returns the exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	commands := Commands()
+	path := []string{"rangi"}
+	for {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			printUsage(os.Stdout, path, commands)
+			return ExitOK
+		}
+		command, ok := findCommand(commands, args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", strings.Join(path, " "), args[0])
+			printUsage(os.Stderr, path, commands)
+			return ExitUsage
+		}
+		path = append(path, command.Name)
+		args = args[1:]
+		if command.Subcommands == nil {
+			name := strings.Join(path[1:], " ")
+			return exitCode(name, command.Run(newFlagSet(name, command), args))
+		}
+		if len(args) == 0 {
+			printUsage(os.Stderr, path, command.Subcommands)
+			return ExitUsage
+		}
+		commands = command.Subcommands
+	}
+}
+
+func findCommand(commands []Command, name string) (Command, bool) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+func printUsage(w io.Writer, path []string, commands []Command) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", strings.Join(path, " "))
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", command.Name, command.Description)
+	}
+	fmt.Fprintf(w, "\nRun \"%s <command> -h\" for the flags of a command.\n", strings.Join(path, " "))
+}
+
+func exitCode(name string, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	if errors.Is(err, errorUsage) {
+		return ExitUsage
+	}
+	fmt.Fprintf(os.Stderr, "rangi %s: %v\n", name, err)
+	var invalid *invalidError
+	if errors.As(err, &invalid) {
+		return ExitInvalid
+	}
+	return ExitError
+}
+
+// newFlagSet
+// returns a flag set that prints the usage of the command to stderr
+func newFlagSet(name string, command Command) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: rangi %s [flags] %s\n\n%s\n", name, command.Args, command.Description)
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(os.Stderr, "\nFlags:")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+// parseFlags
+// parses the arguments and checks the number of positional arguments
+func parseFlags(flags *flag.FlagSet, args []string, minArgs int, maxArgs int) error {
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errorUsage
+	}
+	if flags.NArg() < minArgs || flags.NArg() > maxArgs {
+		fmt.Fprintf(os.Stderr, "rangi %s: unexpected number of arguments\n", flags.Name())
+		flags.Usage()
+		return errorUsage
+	}
+	return nil
+}
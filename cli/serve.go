@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/server"
+)
+
+// runServe
+// starts the server and shuts it down gracefully on SIGINT or SIGTERM
+func runServe(flags *flag.FlagSet, args []string) error {
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	server, err := server.New(config)
+	if err != nil {
+		return fmt.Errorf("could not create server: %v", err)
+	}
+	// Implement graceful shutdown
+	idleConnsClosed := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		// We received a signal, shut down.
+		if err := server.Shutdown(context.Background()); err != nil {
+			config.Logger.Error("Could not shutdown server", "error", err)
+		}
+		close(idleConnsClosed)
+	}()
+	// Start server
+	err = server.Start()
+	if err != nil {
+		return fmt.Errorf("could not start server: %v", err)
+	}
+	<-idleConnsClosed
+	return nil
+}
+
+// runMigrate
+// creates and updates the tables without starting the server
+func runMigrate(flags *flag.FlagSet, args []string) error {
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	collections, err := server.Migrate(config)
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		fmt.Println(collection.Blueprint.CollectionName)
+	}
+	return nil
+}
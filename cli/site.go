@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/server"
+)
+
+// runBuild
+// renders the site of the configured theme into a directory of static files and prints the result as JSON
+func runBuild(flags *flag.FlagSet, args []string) error {
+	output := flags.String("output", "public", "Output directory")
+	full := flags.Bool("full", false, "Render all pages and copy all assets, instead of only the ones that changed since the last build")
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	if config.Theme == "" {
+		return errors.New("no theme configured, set RANGI_THEME")
+	}
+	server, err := server.New(config)
+	if err != nil {
+		return fmt.Errorf("could not create server: %v", err)
+	}
+	result, err := server.Site().Build(*output, *full)
+	if err != nil {
+		return fmt.Errorf("could not build site: %v", err)
+	}
+	printJSON(result)
+	return nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/server"
+	"github.com/rangidev/rangi/transfer"
+)
+
+// runExport
+// writes the items of one or all collections to a file or stdout
+func runExport(flags *flag.FlagSet, args []string) error {
+	collectionName := flags.String("collection", "", "Collection to export, all collections if empty")
+	formatName := flags.String("format", string(transfer.FormatJSONLines), "Format: jsonl or csv (a ZIP archive if all collections are exported)")
+	output := flags.String("output", "", "Output file, stdout if empty")
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	server, err := server.New(config)
+	if err != nil {
+		return fmt.Errorf("could not create server: %v", err)
+	}
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("could not create output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if *collectionName == "" {
+		return server.Exporter().ExportAll(w, format)
+	}
+	collection, err := blueprint.NewCollectionLoader(config.BlueprintsPath).Get(*collectionName)
+	if err != nil {
+		return fmt.Errorf("could not get collection: %v", err)
+	}
+	return server.Exporter().Export(w, collection, format)
+}
+
+// runImport
+// imports items from a file and prints the report as JSON
+func runImport(flags *flag.FlagSet, args []string) error {
+	collectionName := flags.String("collection", "", "Collection to import into, taken from the \"collection\" column of each row if empty")
+	formatName := flags.String("format", "", "Format: jsonl or csv, detected from the file extension if empty")
+	dryRun := flags.Bool("dry-run", false, "Validate all rows without writing anything")
+	err := parseFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	filename := flags.Arg(0)
+	if *formatName == "" {
+		*formatName = filename
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	server, err := server.New(config)
+	if err != nil {
+		return fmt.Errorf("could not create server: %v", err)
+	}
+	var collection *blueprint.Collection
+	if *collectionName != "" {
+		collection, err = blueprint.NewCollectionLoader(config.BlueprintsPath).Get(*collectionName)
+		if err != nil {
+			return fmt.Errorf("could not get collection: %v", err)
+		}
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("could not open file: %v", err)
+	}
+	defer file.Close()
+	report, err := server.Importer().Import(context.Background(), file, collection, format, transfer.Options{DryRun: *dryRun})
+	if report != nil {
+		printJSON(report)
+	}
+	if err != nil {
+		return fmt.Errorf("could not import file: %v", err)
+	}
+	if report.Failed > 0 {
+		return newInvalidError("%d of %d rows could not be imported", report.Failed, report.Rows)
+	}
+	return nil
+}
+
+// printJSON
+// writes the value as indented JSON to stdout
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
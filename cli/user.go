@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/auth"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/database"
+	"github.com/rangidev/rangi/server"
+)
+
+// runUserCreate
+// creates a user. The password is read from stdin or generated and printed.
+func runUserCreate(flags *flag.FlagSet, args []string) error {
+	email := flags.String("email", "", "Email address of the user (required)")
+	role := flags.String("role", string(database.UserRoleEditor), "Role: admin or editor")
+	passwordStdin := flags.Bool("password-stdin", false, "Read the password from the first line of stdin instead of generating one")
+	err := parseFlags(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "rangi user create: -email is required")
+		flags.Usage()
+		return errorUsage
+	}
+	password, generated, err := readOrGeneratePassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	_, err = server.Migrate(config)
+	if err != nil {
+		return err
+	}
+	user, err := auth.CreateUser(config.DatabaseInstance, *email, password, database.UserRole(*role))
+	if err != nil {
+		return err
+	}
+	audit.New(config.DatabaseInstance, config.Logger).Add(context.Background(), audit.Record{
+		Action: audit.ActionPermissionChange,
+		Target: user.Email,
+		Diff:   audit.Diff{"role": {nil, user.Role}},
+	})
+	if generated {
+		fmt.Println(password)
+	}
+	return nil
+}
+
+// runUserResetPassword
+// sets a new password for a user. The password is read from stdin or generated and printed.
+func runUserResetPassword(flags *flag.FlagSet, args []string) error {
+	passwordStdin := flags.Bool("password-stdin", false, "Read the password from the first line of stdin instead of generating one")
+	err := parseFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	password, generated, err := readOrGeneratePassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	config := config.New()
+	_, err = server.Migrate(config)
+	if err != nil {
+		return err
+	}
+	user, err := auth.ResetPassword(config.DatabaseInstance, flags.Arg(0), password)
+	if err != nil {
+		return err
+	}
+	audit.New(config.DatabaseInstance, config.Logger).Add(context.Background(), audit.Record{
+		Action: audit.ActionPasswordReset,
+		Target: user.Email,
+	})
+	if generated {
+		fmt.Println(password)
+	}
+	return nil
+}
+
+// readOrGeneratePassword
+// returns the first line of stdin or a random password. The second return value is true if the password has been generated.
+func readOrGeneratePassword(fromStdin bool) (string, bool, error) {
+	if !fromStdin {
+		password, err := auth.NewPassword()
+		return password, true, err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, fmt.Errorf("could not read password from stdin: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), false, nil
+}
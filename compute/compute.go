@@ -0,0 +1,184 @@
+package compute
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/hook"
+)
+
+// Func
+// computes the value of a field from the values of the item. Return a value that matches the type of the field,
+// e. g. a string or an int. Values of array and object fields may also be returned as Go values, they are stored as JSON.
+type Func func(item blueprint.Item) (any, error)
+
+// Registry
+// stores the Go functions of computed fields and caches the parsed expressions
+type Registry struct {
+	mutex       sync.RWMutex
+	functions   map[string]Func
+	expressions sync.Map // Maps expressions to *template.Template
+}
+
+func NewRegistry() *Registry {
+	return &Registry{functions: make(map[string]Func)}
+}
+
+// Register
+// adds a function that can be used by the name in the computed setting of blueprint fields
+func (r *Registry) Register(name string, function Func) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.functions[name] = function
+}
+
+// Problems
+// reports computed fields of the blueprint whose function has not been registered,
+// so that a misspelled name is found when the blueprint is loaded instead of on every write.
+// file is the path of the blueprint file, see blueprint.CollectionLoader.File.
+func (r *Registry) Problems(bp *blueprint.Blueprint, file string) blueprint.Problems {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var problems blueprint.Problems
+	for _, field := range bp.Fields {
+		if !field.IsComputed() || field.Computed.Function == "" {
+			continue
+		}
+		if _, ok := r.functions[field.Computed.Function]; !ok {
+			problems = append(problems, blueprint.Problem{
+				File:    file,
+				Field:   field.Name,
+				Message: fmt.Sprintf("function %s has not been registered", field.Computed.Function),
+			})
+		}
+	}
+	return problems
+}
+
+// Compute
+// returns the values of all computed fields of the blueprint. values has to contain all fields of the item,
+// not only the written ones, so that the computed values do not depend on which fields have been changed.
+func (r *Registry) Compute(bp *blueprint.Blueprint, values blueprint.Item) (blueprint.Item, error) {
+	computed := blueprint.Item{}
+	for _, field := range bp.Fields {
+		if !field.IsComputed() {
+			continue
+		}
+		value, err := r.computeField(field, values)
+		if err != nil {
+			// Most errors are caused by values of the item, e. g. a number where a text is expected
+			return nil, hook.NewValidationError(field.Name, fmt.Sprintf("could not compute value: %v", err))
+		}
+		computed[field.Name] = value
+	}
+	return computed, nil
+}
+
+func (r *Registry) computeField(field blueprint.BlueprintField, values blueprint.Item) (any, error) {
+	if field.Computed.Function != "" {
+		r.mutex.RLock()
+		function, ok := r.functions[field.Computed.Function]
+		r.mutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("function %s has not been registered", field.Computed.Function)
+		}
+		value, err := function(values)
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := value.(string); ok {
+			return convert(field.Type, text)
+		}
+		if value != nil && (field.Type == blueprint.TypeArray || field.Type == blueprint.TypeObject) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("could not marshal value: %v", err)
+			}
+			return string(data), nil
+		}
+		return value, nil
+	}
+	tmpl, err := r.expression(field.Computed.Expression)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, templateData(values))
+	if err != nil {
+		return nil, err
+	}
+	return convert(field.Type, buf.String())
+}
+
+func (r *Registry) expression(expression string) (*template.Template, error) {
+	if tmpl, ok := r.expressions.Load(expression); ok {
+		return tmpl.(*template.Template), nil
+	}
+	tmpl, err := blueprint.ParseExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	r.expressions.Store(expression, tmpl)
+	return tmpl, nil
+}
+
+// templateData
+// returns the values of the item for expressions. Missing values are empty strings, which templates would print as "<no value>" otherwise.
+func templateData(values blueprint.Item) map[string]any {
+	data := make(map[string]any, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+			data[key] = ""
+		case []byte:
+			data[key] = string(v)
+		default:
+			data[key] = v
+		}
+	}
+	return data
+}
+
+// convert
+// converts the output of an expression into the type of the field. Empty output is stored as NULL.
+func convert(typ blueprint.Type, text string) (any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		if typ == blueprint.TypeString {
+			return "", nil
+		}
+		return nil, nil
+	}
+	switch typ {
+	case blueprint.TypeInt:
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", text)
+		}
+		return i, nil
+	case blueprint.TypeBoolean:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", text)
+		}
+		return b, nil
+	case blueprint.TypeArray, blueprint.TypeObject:
+		var decoded any
+		err := json.Unmarshal([]byte(text), &decoded)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not valid JSON", text)
+		}
+		_, isArray := decoded.([]any)
+		_, isObject := decoded.(map[string]any)
+		if (typ == blueprint.TypeArray && !isArray) || (typ == blueprint.TypeObject && !isObject) {
+			return nil, fmt.Errorf("%q is not a JSON %s", text, typ)
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,38 @@
+package compute
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+func TestProblemsReportFile(t *testing.T) {
+	dir := t.TempDir()
+	data := `{
+	"collection_display_name": {"en": "Pages"},
+	"collection_name": "pages",
+	"fields": [
+		{"name": "summary", "display_name": "Summary", "type": "string", "computed": {"function": "summarize"}}
+	]
+}`
+	err := os.WriteFile(filepath.Join(dir, "pages.json"), []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectionLoader := blueprint.NewCollectionLoader(dir)
+	collection, err := collectionLoader.Get("pages")
+	if err != nil {
+		t.Fatalf("could not load collection: %v", err)
+	}
+	registry := NewRegistry()
+	problems := registry.Problems(collection.Blueprint, collectionLoader.File("pages"))
+	if len(problems) != 1 || problems[0].File != filepath.Join(dir, "pages.json") || problems[0].Field != "summary" {
+		t.Fatalf("got problems %v, want one problem of field summary in %s", problems, filepath.Join(dir, "pages.json"))
+	}
+	registry.Register("summarize", func(item blueprint.Item) (any, error) { return "", nil })
+	if problems := registry.Problems(collection.Blueprint, collectionLoader.File("pages")); len(problems) != 0 {
+		t.Fatalf("got problems %v for a registered function", problems)
+	}
+}
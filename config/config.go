@@ -5,11 +5,15 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	env "github.com/Netflix/go-env"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/rangidev/rangi/asset"
 	"github.com/rangidev/rangi/database"
+	"github.com/rangidev/rangi/locale"
+	"github.com/rangidev/rangi/preview"
 )
 
 var (
@@ -17,11 +21,20 @@ var (
 	contentPathParts     = []string{"content"}
 	blueprintsPathParts  = append(contentPathParts, "blueprints")
 	sqlite3FilePathParts = append(contentPathParts, "sqlite3", "rangi.db")
+	assetsPathParts      = append(contentPathParts, "assets")
+	imageCachePathParts  = append(contentPathParts, "cache", "images")
+	themesPathParts      = []string{"themes"}
 )
 
 type Config struct {
 	// Server
 	HostAndPort string `env:"RANGI_HOST_AND_PORT,default=:6532"`
+	// Use X-Forwarded-For and X-Real-Ip headers to determine client IP addresses and X-Forwarded-Proto to detect HTTPS. Only enable this behind a reverse proxy.
+	TrustProxyHeaders bool `env:"RANGI_TRUST_PROXY_HEADERS,default=false"`
+	// Users
+	// Used to create the first admin user if no users exist
+	AdminEmail    string `env:"RANGI_ADMIN_EMAIL"`
+	AdminPassword string `env:"RANGI_ADMIN_PASSWORD"`
 	// Log
 	LogLevel  string `env:"RANGI_LOG_LEVEL,default=info" validate:"oneof=debug info warn error"`
 	LogFormat string `env:"RANGI_LOG_FROMAT,default=text" validate:"oneof=text json"`
@@ -36,11 +49,64 @@ type Config struct {
 	Sqlite3DatabaseFile string `env:"RANGI_SQLITE3_DATABASE_FILE"`
 	// Admin interface
 	AdminItemsLimit int `env:"RANGI_ADMIN_ITEMS_LIMIT,default=50" validate:"gte=1,lte=200"`
+	// Assets
+	StorageType string `env:"RANGI_STORAGE_TYPE,default=local" validate:"oneof=local s3"`
+	// Used to override the default directory of the local storage
+	AssetsPath string `env:"RANGI_ASSETS_PATH"`
+	// Maximum size of a single upload in bytes
+	AssetsMaxUploadSize int64 `env:"RANGI_ASSETS_MAX_UPLOAD_SIZE,default=33554432" validate:"gte=1"`
+	// S3 storage
+	S3Endpoint        string `env:"RANGI_S3_ENDPOINT" validate:"required_if=StorageType s3"`
+	S3Region          string `env:"RANGI_S3_REGION,default=us-east-1"`
+	S3Bucket          string `env:"RANGI_S3_BUCKET" validate:"required_if=StorageType s3"`
+	S3Prefix          string `env:"RANGI_S3_PREFIX"`
+	S3AccessKeyID     string `env:"RANGI_S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `env:"RANGI_S3_SECRET_ACCESS_KEY"`
+	// Required by most self-hosted S3-compatible servers
+	S3UsePathStyle bool `env:"RANGI_S3_USE_PATH_STYLE,default=false"`
+	// If enabled, asset requests are redirected to presigned URLs instead of being proxied through Rangi
+	S3RedirectToPresignedURL bool          `env:"RANGI_S3_REDIRECT_TO_PRESIGNED_URL,default=false"`
+	S3PresignedURLExpiry     time.Duration `env:"RANGI_S3_PRESIGNED_URL_EXPIRY,default=15m" validate:"gte=1s,lte=168h"`
+	// Images
+	// Used to override the default directory for resized images
+	ImageCachePath string `env:"RANGI_IMAGE_CACHE_PATH"`
+	// JSON file that maps preset names to image options. The built-in presets are used if empty.
+	ImagePresetsFile string `env:"RANGI_IMAGE_PRESETS_FILE"`
+	// Localization
+	// Locales of the content, separated by "|". The first locale is the default locale.
+	ContentLocales []string `env:"RANGI_CONTENT_LOCALES,default=en" validate:"min=1"`
+	// Fallback chains for missing translations, separated by "|", e. g. "de-AT:de|fr-CH:fr,de". All chains end with the default locale.
+	ContentLocaleFallbacks []string `env:"RANGI_CONTENT_LOCALE_FALLBACKS"`
+	// Webhooks
+	// JSON file with an array of webhooks (name, url, secret, events, collections). No webhooks are sent if empty.
+	WebhooksFile string `env:"RANGI_WEBHOOKS_FILE"`
+	// Number of attempts before a webhook delivery is marked as failed
+	WebhookMaxAttempts int `env:"RANGI_WEBHOOK_MAX_ATTEMPTS,default=10" validate:"gte=1"`
+	// Markdown
+	// HTML tags that are kept when sanitizing rendered markdown, separated by "|"
+	MarkdownAllowedTags []string `env:"RANGI_MARKDOWN_ALLOWED_TAGS,default=p|br|hr|h1|h2|h3|h4|h5|h6|strong|em|del|a|img|ul|ol|li|blockquote|pre|code|table|thead|tbody|tr|th|td"`
+	// Site
+	// Used to override the default themes directory next to the content directory
+	ThemesPath string `env:"RANGI_THEMES_PATH"`
+	// Name of the directory in the themes directory that is used to render the public site. The site is disabled if empty.
+	Theme string `env:"RANGI_THEME"`
+	// Number of items on list pages, unless the route sets its own page size
+	SitePageSize int `env:"RANGI_SITE_PAGE_SIZE,default=10" validate:"gte=1,lte=200"`
+	// Absolute URL of the site without trailing slash, e. g. https://example.com. The sitemap and the feeds are only available if set.
+	SiteURL string `env:"RANGI_SITE_URL" validate:"omitempty,url,endsnotwith=/"`
+	// Preview
+	// Used to sign preview tokens. A random secret is generated on start if empty, so that tokens become invalid on restart
+	// and are not accepted by other instances. Set it if any collection has a preview URL.
+	PreviewSecret      string        `env:"RANGI_PREVIEW_SECRET" validate:"omitempty,min=32"`
+	PreviewTokenExpiry time.Duration `env:"RANGI_PREVIEW_TOKEN_EXPIRY,default=1h" validate:"gte=1m,lte=168h"`
 
 	// Used to reference assets that are stored relative to the binary. TODO: Do we want to store everything relative to the binary as default behavior?
 	ExecutableDir    string
 	Logger           *slog.Logger
 	DatabaseInstance *database.DB
+	AssetStorage     asset.Storage
+	Locales          *locale.Locales
+	PreviewSigner    *preview.Signer
 	Validate         *validator.Validate
 }
 
@@ -63,13 +129,14 @@ func New() *Config {
 	if err != nil {
 		log.Fatalln("Error while determining log level", err)
 	}
+	// Logs are written to stderr, so that commands can write their output to stdout
 	switch config.LogFormat {
 	case "text":
-		config.Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 			Level: logLevel,
 		}))
 	case "json":
-		config.Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		config.Logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
 			Level: logLevel,
 		}))
 	default:
@@ -115,12 +182,76 @@ func New() *Config {
 			config.Logger.Error("Could not connect to sqlite3 database", "error", err)
 			os.Exit(1)
 		}
+		config.Sqlite3DatabaseFile = databaseFilename
 		config.DatabaseInstance = dbInstance
 	default:
 		config.Logger.Error("Invalid database type provided", "type", config.DatabaseType)
 		os.Exit(1)
 	}
 
+	// Asset storage
+	switch config.StorageType {
+	case "local":
+		assetsPath := config.AssetsPath
+		if assetsPath == "" {
+			// Use default assets path
+			assetsPath = filepath.Join(append([]string{config.ExecutableDir}, assetsPathParts...)...)
+		}
+		config.AssetsPath = assetsPath
+		storage, err := asset.NewLocalStorage(assetsPath)
+		if err != nil {
+			config.Logger.Error("Could not create local asset storage", "error", err)
+			os.Exit(1)
+		}
+		config.AssetStorage = storage
+	case "s3":
+		storage, err := asset.NewS3Storage(asset.S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			Prefix:          config.S3Prefix,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			UsePathStyle:    config.S3UsePathStyle,
+		}, nil)
+		if err != nil {
+			config.Logger.Error("Could not create S3 asset storage", "error", err)
+			os.Exit(1)
+		}
+		config.AssetStorage = storage
+	default:
+		config.Logger.Error("Invalid storage type provided", "type", config.StorageType)
+		os.Exit(1)
+	}
+
+	// Image cache
+	if config.ImageCachePath == "" {
+		// Use default image cache path
+		config.ImageCachePath = filepath.Join(append([]string{config.ExecutableDir}, imageCachePathParts...)...)
+	}
+
+	// Themes
+	if config.ThemesPath == "" {
+		// Use default themes path
+		config.ThemesPath = filepath.Join(append([]string{config.ExecutableDir}, themesPathParts...)...)
+	}
+
+	// Locales
+	locales, err := locale.New(config.ContentLocales, config.ContentLocaleFallbacks)
+	if err != nil {
+		config.Logger.Error("Could not configure content locales", "error", err)
+		os.Exit(1)
+	}
+	config.Locales = locales
+
+	// Preview tokens
+	previewSigner, err := preview.NewSigner(config.PreviewSecret, config.PreviewTokenExpiry)
+	if err != nil {
+		config.Logger.Error("Could not create preview token signer", "error", err)
+		os.Exit(1)
+	}
+	config.PreviewSigner = previewSigner
+
 	// Validator
 	config.Validate = validate
 
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rangidev/rangi/asset"
+)
+
+func (db *DB) CreateAssetTable() error {
+	var statement string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		statement = statementCreateAssetTableSqlite3
+	case DatabaseTypePostgres:
+		statement = statementCreateAssetTablePostgres
+	default:
+		return ErrorUnknownDatabaseType
+	}
+	_, err := db.db.Exec(statement)
+	return err
+}
+
+func (db *DB) CreateAsset(a *asset.Asset) error {
+	a.CreatedAt = time.Now().Unix()
+	result, err := db.db.NamedExec(statementInsertAsset, a)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err == nil {
+		a.ID = id
+	}
+	return nil
+}
+
+func (db *DB) GetAssets(limit int, offset int64) ([]asset.Asset, error) {
+	var assets []asset.Asset
+	err := db.db.Select(&assets, statementGetAssets, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %v", err)
+	}
+	return assets, nil
+}
+
+func (db *DB) GetAsset(uuid string) (*asset.Asset, error) {
+	var a asset.Asset
+	err := db.db.Get(&a, statementGetAsset, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *DB) UpdateAssetAltText(uuid string, altText string) error {
+	_, err := db.db.Exec(statementUpdateAssetAltText, altText, uuid)
+	return err
+}
+
+func (db *DB) UpdateAssetFocalPoint(uuid string, focalX float64, focalY float64) error {
+	_, err := db.db.Exec(statementUpdateAssetFocalPoint, focalX, focalY, uuid)
+	return err
+}
+
+func (db *DB) DeleteAsset(uuid string) error {
+	_, err := db.db.Exec(statementDeleteAsset, uuid)
+	return err
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuditEntry
+// records an administrative action
+type AuditEntry struct {
+	ID         int64  `db:"id" json:"id"`
+	CreatedAt  int64  `db:"created_at" json:"created_at"`
+	Action     string `db:"action" json:"action"`
+	ActorID    int64  `db:"actor_id" json:"actor_id"` // 0 if the actor is not a known user, e. g. for failed logins
+	Actor      string `db:"actor" json:"actor"`
+	IP         string `db:"ip" json:"ip"`
+	Collection string `db:"collection" json:"collection,omitempty"`
+	Target     string `db:"target" json:"target,omitempty"` // E. g. the ID of an item or the email of a user
+	Diff       string `db:"diff" json:"diff,omitempty"`     // JSON object that maps changed fields to [old, new]
+}
+
+// AuditFilter
+// restricts the returned audit entries. Empty fields are ignored.
+type AuditFilter struct {
+	Action     string
+	Actor      string
+	Collection string
+	Since      int64
+	Until      int64
+	BeforeID   int64 // Only entries with a lower ID, used for paging through all entries
+}
+
+func (db *DB) CreateAuditLogTable() error {
+	var statements []string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		statements = append([]string{statementCreateAuditLogTableSqlite3}, statementsProtectAuditLogSqlite3...)
+	case DatabaseTypePostgres:
+		statements = append([]string{statementCreateAuditLogTablePostgres}, statementsProtectAuditLogPostgres...)
+	default:
+		return ErrorUnknownDatabaseType
+	}
+	// In one transaction, so that the audit log is never without protection while the triggers are replaced
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// InsertAuditEntry
+// appends an entry to the audit log. Entries can not be changed afterwards.
+func (db *DB) InsertAuditEntry(entry *AuditEntry) error {
+	_, err := db.db.NamedExec(statementInsertAuditEntry, entry)
+	return err
+}
+
+// GetAuditEntries
+// returns matching entries, newest first
+func (db *DB) GetAuditEntries(filter AuditFilter, limit int, offset int64) ([]AuditEntry, error) {
+	var conditions []string
+	var args []any
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Collection != "" {
+		conditions = append(conditions, "collection = ?")
+		args = append(args, filter.Collection)
+	}
+	if filter.Since != 0 {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != 0 {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit, offset)
+	var entries []AuditEntry
+	err := db.db.Select(&entries, db.db.Rebind(fmt.Sprintf(statementGetAuditEntries, where)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	db, err := NewSqlite3Instance(filepath.Join(t.TempDir(), "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	// Creating the table again replaces the triggers
+	for range 2 {
+		err = db.CreateAuditLogTable()
+		if err != nil {
+			t.Fatalf("could not create audit log table: %v", err)
+		}
+	}
+	err = db.InsertAuditEntry(&AuditEntry{CreatedAt: 1, Action: "item.update", Actor: "admin@example.com"})
+	if err != nil {
+		t.Fatalf("could not insert audit entry: %v", err)
+	}
+	for _, statement := range []string{"UPDATE audit_log SET actor = 'someone';", "DELETE FROM audit_log;"} {
+		_, err = db.db.Exec(statement)
+		if err == nil {
+			t.Errorf("%s changed the audit log", statement)
+		}
+	}
+	entries, err := db.GetAuditEntries(AuditFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("could not get audit entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "admin@example.com" {
+		t.Fatalf("got entries %+v, want the unchanged entry", entries)
+	}
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+var (
+	ErrorBackupNotSupported = errors.New("backups are only supported for sqlite3 databases")
+)
+
+// Backup
+// writes a consistent copy of the database to a new file using the SQLite online backup API.
+// The database can still be read and written while the backup is running.
+func (db *DB) Backup(ctx context.Context, filename string) error {
+	if db.dbType != DatabaseTypeSqlite3 {
+		return ErrorBackupNotSupported
+	}
+	destDB, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", filename))
+	if err != nil {
+		return fmt.Errorf("could not open backup database: %v", err)
+	}
+	defer destDB.Close()
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect to backup database: %v", err)
+	}
+	defer destConn.Close()
+	srcConn, err := db.pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect to database: %v", err)
+	}
+	defer srcConn.Close()
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			backup, err := destDriverConn.(*sqlite3.SQLiteConn).Backup("main", srcDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return fmt.Errorf("could not start backup: %v", err)
+			}
+			// Copy all pages in one step, so that the copy is not restarted by concurrent writes
+			_, err = backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return fmt.Errorf("could not copy pages: %v", err)
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// CheckIntegrity
+// returns an error if the database file is corrupted
+func (db *DB) CheckIntegrity() error {
+	if db.dbType != DatabaseTypeSqlite3 {
+		return ErrorUnknownDatabaseType
+	}
+	var results []string
+	err := db.db.Select(&results, statementIntegrityCheckSqlite3)
+	if err != nil {
+		return fmt.Errorf("could not check integrity: %v", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(results, "; "))
+	}
+	return nil
+}
+
+// MissingColumns
+// returns the fields of the collection that have no column in its table. The table itself must exist.
+func (db *DB) MissingColumns(collection *blueprint.Collection) ([]string, error) {
+	columnNames, err := db.getColumnNames(collection.Blueprint.CollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get column names: %v", err)
+	}
+	if len(columnNames) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", collection.Blueprint.CollectionName)
+	}
+	var missing []string
+	for _, field := range collection.Blueprint.Fields {
+		sqlType, ok := db.castToSQLType(field.Type)
+		if !ok || sqlType == SQLTypeReference {
+			continue
+		}
+		if !slices.Contains(columnNames, field.Name) {
+			missing = append(missing, field.Name)
+		}
+	}
+	return missing, nil
+}
+
+func (db *DB) Close() error {
+	err := db.pool.Close()
+	if err != nil {
+		return err
+	}
+	return db.lock.Release()
+}
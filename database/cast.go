@@ -42,6 +42,12 @@ func (db *DB) castToSQLType(typ blueprint.Type) (SQLType, bool) {
 			return SQLTypeSqlite3Blob, true
 		case blueprint.TypeReference:
 			return SQLTypeReference, true
+		case blueprint.TypeMarkdown:
+			return SQLTypeSqlite3Text, true
+		case blueprint.TypeSlug:
+			return SQLTypeSqlite3Text, true
+		case blueprint.TypeAsset:
+			return SQLTypeSqlite3Text, true
 		default:
 			return "", false
 		}
@@ -63,6 +69,12 @@ func (db *DB) castToSQLType(typ blueprint.Type) (SQLType, bool) {
 			return SQLTypePostgresJsonb, true
 		case blueprint.TypeReference:
 			return SQLTypeReference, true
+		case blueprint.TypeMarkdown:
+			return SQLTypePostgresText, true
+		case blueprint.TypeSlug:
+			return SQLTypePostgresText, true
+		case blueprint.TypeAsset:
+			return SQLTypePostgresUUID, true
 		default:
 			return "", false
 		}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"slices"
-	"strings"
 
 	"github.com/rangidev/rangi/blueprint"
 )
@@ -19,55 +18,76 @@ func (db *DB) CreateTables(collections []blueprint.Collection, collectionLoader
 	return nil
 }
 
+// CreateTable
+// creates the table of the collection and adds missing columns and reference tables, see PlanMigration
 func (db *DB) CreateTable(collection *blueprint.Collection, collectionLoader *blueprint.CollectionLoader) error {
-	var subStatements []string
-	// TODO: Make sure field names aren't used twice between default and blueprint fields
 	for _, fieldDef := range collection.Blueprint.Fields {
-		sqlType, ok := db.castToSQLType(fieldDef.Type)
-		if !ok {
-			return fmt.Errorf("could not cast type %s", string(fieldDef.Type))
+		if fieldDef.Type != blueprint.TypeReference {
+			continue
 		}
-		if sqlType == SQLTypeReference {
-			// Get referenced collection
-			refCollection, err := collectionLoader.Get(fieldDef.Reference.Collection)
-			if err != nil {
-				return fmt.Errorf("could not get referenced collection %s: %v", fieldDef.Reference.Collection, err)
-			}
-			err = db.CreateReferenceTable(collection, refCollection)
-			if err != nil {
-				return fmt.Errorf("could not create reference table: %v", err)
-			}
-			// Create reference field in table
-		} else {
-			statement := fmt.Sprintf("%s %s", fieldDef.Name, sqlType)
-			if fieldDef.Required {
-				statement = statement + " NOT NULL"
-			}
-			subStatements = append(subStatements, statement)
+		// Get referenced collection
+		_, err := collectionLoader.Get(fieldDef.Reference.Collection)
+		if err != nil {
+			return fmt.Errorf("could not get referenced collection %s: %v", fieldDef.Reference.Collection, err)
 		}
 	}
-	finalStatement := fmt.Sprintf(statementCreateTable, collection.Blueprint.CollectionName, strings.Join(subStatements, ","))
-	_, err := db.db.Exec(finalStatement)
-	return err
+	plan, err := db.PlanMigration(collection)
+	if err != nil {
+		return err
+	}
+	err = db.ApplyMigration(plan)
+	if err != nil {
+		return err
+	}
+	err = db.createSingletonIndex(collection)
+	if err != nil {
+		return err
+	}
+	return db.createSlugIndexes(collection)
+}
+
+func (db *DB) getColumnNames(tableName string) ([]string, error) {
+	var columnNames []string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		err := db.db.Select(&columnNames, statementGetColumnNamesSqlite3, tableName)
+		if err != nil {
+			return nil, err
+		}
+	case DatabaseTypePostgres:
+		err := db.db.Select(&columnNames, statementGetColumnNamesPostgres, tableName)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrorUnknownDatabaseType
+	}
+	return columnNames, nil
 }
 
 func (db *DB) CreateReferenceTable(collection1 *blueprint.Collection, collection2 *blueprint.Collection) error {
-	if collection1.Blueprint.CollectionName == "" || collection2.Blueprint.CollectionName == "" {
-		return errors.New("empty collection string")
+	statement, err := db.createReferenceTableStatement(collection1.Blueprint.CollectionName, collection2.Blueprint.CollectionName)
+	if err != nil {
+		return err
+	}
+	_, err = db.db.Exec(statement)
+	return err
+}
+
+func (db *DB) createReferenceTableStatement(collection1 string, collection2 string) (string, error) {
+	if collection1 == "" || collection2 == "" {
+		return "", errors.New("empty collection string")
 	}
 	// Sort collections alphabetically to prevent creating reference table twice
-	sorted := []string{collection1.Blueprint.CollectionName, collection2.Blueprint.CollectionName}
+	sorted := []string{collection1, collection2}
 	slices.Sort(sorted)
 	tableName := sorted[0] + "_" + sorted[1]
-	var statement string
 	switch db.dbType {
 	case DatabaseTypeSqlite3:
-		statement = fmt.Sprintf(statementCreateReferenceTableSqlite3, tableName, sorted[0], sorted[1], sorted[0], sorted[0], sorted[1], sorted[1])
+		return fmt.Sprintf(statementCreateReferenceTableSqlite3, tableName, sorted[0], sorted[1], sorted[0], sorted[0], sorted[1], sorted[1]), nil
 	case DatabaseTypePostgres:
-		statement = fmt.Sprintf(statementCreateReferenceTablePostgres, tableName, sorted[0], sorted[1], sorted[0], sorted[0], sorted[1], sorted[1])
+		return fmt.Sprintf(statementCreateReferenceTablePostgres, tableName, sorted[0], sorted[1], sorted[0], sorted[0], sorted[1], sorted[1]), nil
 	default:
-		return ErrorUnknownDatabaseType
+		return "", ErrorUnknownDatabaseType
 	}
-	_, err := db.db.Exec(statement)
-	return err
 }
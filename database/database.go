@@ -2,13 +2,21 @@ package database
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type DB struct {
-	db     *sqlx.DB
-	dbType DatabaseType
+	db             handle // The connection pool or the transaction, see Transaction
+	pool           *sqlx.DB
+	tx             *sqlx.Tx // Only inside Transaction
+	parent         *DB      // Only inside Transaction, emits the events after the commit
+	pendingEvents  []Event
+	dbType         DatabaseType
+	listeners      []Listener
+	listenersMutex sync.RWMutex
+	lock           *Lock // Only for sqlite3
 }
 
 type DatabaseType string
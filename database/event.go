@@ -0,0 +1,57 @@
+package database
+
+import (
+	"github.com/rangidev/rangi/blueprint"
+)
+
+type EventType string
+
+const (
+	EventItemCreated   = EventType("item.created")
+	EventItemUpdated   = EventType("item.updated")
+	EventItemDeleted   = EventType("item.deleted")
+	EventItemPublished = EventType("item.published")
+)
+
+var (
+	AllEventTypes = []EventType{
+		EventItemCreated,
+		EventItemUpdated,
+		EventItemDeleted,
+		EventItemPublished,
+	}
+)
+
+// Event
+// describes a change of content that has been written to the database
+type Event struct {
+	Type       EventType      `json:"type"`
+	Collection string         `json:"collection"`
+	Item       blueprint.Item `json:"item"`
+	Time       int64          `json:"time"`
+}
+
+// Listener
+// is called synchronously after a change has been written, so it should return quickly
+type Listener func(event Event)
+
+// AddListener
+// registers a function that is called for every content change
+func (db *DB) AddListener(listener Listener) {
+	db.listenersMutex.Lock()
+	defer db.listenersMutex.Unlock()
+	db.listeners = append(db.listeners, listener)
+}
+
+func (db *DB) emit(event Event) {
+	if db.parent != nil {
+		// Changes are not visible before the transaction has been committed
+		db.pendingEvents = append(db.pendingEvents, event)
+		return
+	}
+	db.listenersMutex.RLock()
+	defer db.listenersMutex.RUnlock()
+	for _, listener := range db.listeners {
+		listener(event)
+	}
+}
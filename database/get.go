@@ -24,13 +24,73 @@ func (db *DB) GetItems(collection *blueprint.Collection, limit int, offset int64
 	return results, nil
 }
 
-func (db *DB) GetItem(collection *blueprint.Collection, id string) (blueprint.Item, error) {
+// GetItem
+// returns the item whose lookup key field equals value.
+// Allowed lookup keys are "id", "uuid" and the names of slug fields.
+func (db *DB) GetItem(collection *blueprint.Collection, key string, value string) (blueprint.Item, error) {
+	if !isLookupKey(collection, key) {
+		return nil, fmt.Errorf("invalid lookup key %s", key)
+	}
 	// TODO: Support for reference fields via JOIN
 	result := blueprint.Item{}
-	row := db.db.QueryRowx(fmt.Sprintf("SELECT * FROM %s WHERE id=$1;", collection.Blueprint.CollectionName), id)
+	row := db.db.QueryRowx(fmt.Sprintf("SELECT * FROM %s WHERE %s=$1;", collection.Blueprint.CollectionName, key), value)
 	err := row.MapScan(result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
+
+// GetPublishedItems
+// returns published items, the most recently published first
+func (db *DB) GetPublishedItems(collection *blueprint.Collection, limit int, offset int64) ([]blueprint.Item, error) {
+	var results []blueprint.Item
+	rows, err := db.db.Queryx(fmt.Sprintf(statementGetPublishedItems, collection.Blueprint.CollectionName), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute query: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		result := blueprint.Item{}
+		err = rows.MapScan(result)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CountPublishedItems
+// returns the number of published items
+func (db *DB) CountPublishedItems(collection *blueprint.Collection) (int64, error) {
+	var count int64
+	err := db.db.Get(&count, fmt.Sprintf(statementCountPublishedItems, collection.Blueprint.CollectionName))
+	if err != nil {
+		return 0, fmt.Errorf("could not count items: %v", err)
+	}
+	return count, nil
+}
+
+// GetLatestPublished
+// returns the latest updated_at of the published items, or 0 if there are none
+func (db *DB) GetLatestPublished(collection *blueprint.Collection) (int64, error) {
+	var updatedAt int64
+	err := db.db.Get(&updatedAt, fmt.Sprintf(statementGetLatestPublished, collection.Blueprint.CollectionName))
+	if err != nil {
+		return 0, fmt.Errorf("could not get latest update: %v", err)
+	}
+	return updatedAt, nil
+}
+
+func isLookupKey(collection *blueprint.Collection, key string) bool {
+	if key == blueprint.KeyID || key == blueprint.KeyUUID {
+		return true
+	}
+	for _, field := range collection.Blueprint.SlugFields() {
+		if field.Name == key {
+			return true
+		}
+	}
+	return false
+}
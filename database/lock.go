@@ -0,0 +1,45 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrorDatabaseInUse = errors.New("database is in use by another process")
+)
+
+// Lock
+// is held on the lock file next to a sqlite3 database. Every open database instance holds a shared lock,
+// operations that replace the database file, e. g. restores, need an exclusive lock.
+type Lock struct {
+	file *os.File
+}
+
+// LockSqlite3
+// locks the database file without waiting. Returns ErrorDatabaseInUse if the lock is held by another process.
+func LockSqlite3(databasePath string, exclusive bool) (*Lock, error) {
+	file, err := os.OpenFile(databasePath+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %v", err)
+	}
+	err = lockFile(file, exclusive)
+	if err != nil {
+		file.Close()
+		if errors.Is(err, errorWouldBlock) {
+			return nil, ErrorDatabaseInUse
+		}
+		return nil, fmt.Errorf("could not lock database: %v", err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release
+// unlocks the database. Closing the lock file releases the lock.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package database
+
+import (
+	"errors"
+	"os"
+)
+
+var (
+	errorWouldBlock = errors.New("would block")
+)
+
+// lockFile
+// does not lock on platforms without flock, running servers are not detected there
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
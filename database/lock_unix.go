@@ -0,0 +1,20 @@
+//go:build unix
+
+package database
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	errorWouldBlock = syscall.EWOULDBLOCK
+)
+
+func lockFile(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+// MigrationPlan
+// lists the statements that bring the table of a collection in line with its blueprint.
+// Columns are never dropped or changed, so that no content is lost; such differences are reported instead.
+type MigrationPlan struct {
+	Collection    string
+	NewTable      bool
+	Statements    []string
+	Warnings      []string
+	UnusedColumns []string // Columns without field, e. g. of removed fields. Their content is kept.
+}
+
+// Empty
+// returns true if the table already matches the blueprint
+func (p *MigrationPlan) Empty() bool {
+	return len(p.Statements) == 0
+}
+
+type column struct {
+	Name    string `db:"name"`
+	Type    string `db:"type"`
+	NotNull bool   `db:"not_null"`
+}
+
+// PlanMigration
+// compares the table of the collection with its blueprint without changing anything
+func (db *DB) PlanMigration(collection *blueprint.Collection) (*MigrationPlan, error) {
+	tableName := collection.Blueprint.CollectionName
+	plan := &MigrationPlan{Collection: tableName}
+	existingColumns, err := db.getColumns(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get columns of table %s: %v", tableName, err)
+	}
+	plan.NewTable = len(existingColumns) == 0
+	var subStatements []string
+	var newColumns []string
+	fieldNames := make(map[string]bool)
+	for _, field := range collection.Blueprint.Fields {
+		sqlType, ok := db.castToSQLType(field.Type)
+		if !ok {
+			return nil, fmt.Errorf("could not cast type %s", string(field.Type))
+		}
+		if sqlType == SQLTypeReference {
+			statement, err := db.planReferenceTable(tableName, field.Reference.Collection)
+			if err != nil {
+				return nil, err
+			}
+			if statement != "" && !slices.Contains(plan.Statements, statement) {
+				plan.Statements = append(plan.Statements, statement)
+			}
+			continue
+		}
+		fieldNames[field.Name] = true
+		index := slices.IndexFunc(existingColumns, func(c column) bool { return c.Name == field.Name })
+		switch {
+		case plan.NewTable:
+			statement := fmt.Sprintf("%s %s", field.Name, sqlType)
+			// Fields that are required while they are visible have no value while they are hidden
+			if field.Required && field.VisibleIf == nil {
+				statement = statement + " NOT NULL"
+			}
+			subStatements = append(subStatements, statement)
+			newColumns = append(newColumns, field.Name)
+		case index == -1:
+			// Added columns are always nullable, because existing rows do not have a value for them
+			plan.Statements = append(plan.Statements, fmt.Sprintf(statementAddColumn, tableName, field.Name, sqlType))
+			newColumns = append(newColumns, field.Name)
+			if field.Required && field.VisibleIf == nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("column %s is added as nullable, because existing items have no value for it", field.Name))
+			}
+		case field.Type != blueprint.TypeID && !sameSQLType(existingColumns[index].Type, string(sqlType)):
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("column %s has the type %s, but fields of type %s need %s. The column is not changed.", field.Name, existingColumns[index].Type, field.Type, sqlType))
+		case field.Type != blueprint.TypeID && existingColumns[index].NotNull && !(field.Required && field.VisibleIf == nil):
+			// The field is no longer required, or it has no value while it is hidden
+			if db.dbType == DatabaseTypePostgres {
+				plan.Statements = append(plan.Statements, fmt.Sprintf(statementDropNotNullPostgres, tableName, field.Name))
+			} else {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("column %s is NOT NULL, but the field is not always required. SQLite can not change this, so items without a value for it can not be saved until the table is recreated.", field.Name))
+			}
+		}
+	}
+	if plan.NewTable {
+		plan.Statements = append([]string{fmt.Sprintf(statementCreateTable, tableName, strings.Join(subStatements, ","))}, plan.Statements...)
+	}
+	for _, field := range collection.Blueprint.SlugFields() {
+		if slices.Contains(newColumns, field.Name) {
+			plan.Statements = append(plan.Statements, fmt.Sprintf(statementCreateUniqueIndex, tableName, field.Name, tableName, field.Name))
+			continue
+		}
+		unnormalized, err := db.getUnnormalizedSlugs(collection, field.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not get slugs of column %s: %v", field.Name, err)
+		}
+		if len(unnormalized) > 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("column %s has values that are not valid slugs (items %s). They are converted to slugs.", field.Name, slugItemIDs(unnormalized)))
+		}
+		duplicates, err := db.getDuplicateSlugs(collection, field.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not get duplicate slugs of column %s: %v", field.Name, err)
+		}
+		if len(duplicates) > 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("column %s has duplicate slugs (items %s). All but the item with the lowest id get a numeric suffix.", field.Name, slugItemIDs(duplicates)))
+		}
+	}
+	if collection.Blueprint.Singleton && !plan.NewTable {
+		count, err := db.countItems(collection)
+		if err != nil {
+			return nil, fmt.Errorf("could not count items: %v", err)
+		}
+		if count > 1 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("the collection is a singleton, but has %d items. Only the oldest item is used and the database can not prevent further items until the others have been deleted.", count))
+		}
+	}
+	for _, c := range existingColumns {
+		if !fieldNames[c.Name] {
+			plan.UnusedColumns = append(plan.UnusedColumns, c.Name)
+		}
+	}
+	return plan, nil
+}
+
+// slugItemIDs
+// returns the comma separated ids of the items
+func slugItemIDs(slugs []storedSlug) string {
+	ids := make([]string, len(slugs))
+	for index, slug := range slugs {
+		ids[index] = strconv.FormatInt(slug.ID, 10)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// planReferenceTable
+// returns the statement that creates the reference table, or an empty string if it already exists
+func (db *DB) planReferenceTable(collection string, refCollection string) (string, error) {
+	tableName := referenceTable(collection, refCollection)
+	columns, err := db.getColumnNames(tableName)
+	if err != nil {
+		return "", fmt.Errorf("could not get columns of table %s: %v", tableName, err)
+	}
+	if len(columns) > 0 {
+		return "", nil
+	}
+	return db.createReferenceTableStatement(collection, refCollection)
+}
+
+// ApplyMigration
+// executes all statements of the plan in one transaction
+func (db *DB) ApplyMigration(plan *MigrationPlan) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range plan.Statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return fmt.Errorf("could not execute %q: %v", statement, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *DB) getColumns(tableName string) ([]column, error) {
+	var columns []column
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		err := db.db.Select(&columns, statementGetColumnsSqlite3, tableName)
+		if err != nil {
+			return nil, err
+		}
+	case DatabaseTypePostgres:
+		err := db.db.Select(&columns, statementGetColumnsPostgres, tableName)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrorUnknownDatabaseType
+	}
+	return columns, nil
+}
+
+// sameSQLType
+// compares the type of an existing column with a SQLType, ignoring lengths and constraints
+func sameSQLType(columnType string, sqlType string) bool {
+	base := func(typ string) string {
+		typ, _, _ = strings.Cut(strings.ToLower(typ), "(")
+		typ, _, _ = strings.Cut(typ, " ")
+		return typ
+	}
+	return base(columnType) == base(sqlType)
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+// referenceTable
+// returns the name of the table that stores references between both collections, see CreateReferenceTable
+func referenceTable(collection string, refCollection string) string {
+	sorted := []string{collection, refCollection}
+	slices.Sort(sorted)
+	return sorted[0] + "_" + sorted[1]
+}
+
+// SetReferences
+// replaces the items of the referenced collection that the item references
+func (db *DB) SetReferences(collection *blueprint.Collection, refCollection *blueprint.Collection, itemID int64, refIDs []int64) error {
+	name := collection.Blueprint.CollectionName
+	refName := refCollection.Blueprint.CollectionName
+	if name == refName {
+		return fmt.Errorf("references within collection %s are not supported", name)
+	}
+	table := referenceTable(name, refName)
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(statementDeleteReferences, table, name), itemID)
+	if err != nil {
+		return err
+	}
+	for _, refID := range refIDs {
+		_, err = tx.Exec(fmt.Sprintf(statementInsertReference, table, name, refName), itemID, refID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetReferencedUUIDs
+// returns the UUIDs of the items of the referenced collection that the item references
+func (db *DB) GetReferencedUUIDs(collection *blueprint.Collection, refCollection *blueprint.Collection, itemID int64) ([]string, error) {
+	name := collection.Blueprint.CollectionName
+	refName := refCollection.Blueprint.CollectionName
+	table := referenceTable(name, refName)
+	var uuids []string
+	err := db.db.Select(&uuids, fmt.Sprintf(statementGetReferencedUUIDs, refName, table, refName, refName, table, refName, table, name, table), itemID)
+	if err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+// EachItem
+// calls fn for all items of the collection, ordered by id
+func (db *DB) EachItem(collection *blueprint.Collection, fn func(item blueprint.Item) error) error {
+	rows, err := db.db.Queryx(fmt.Sprintf(statementGetAllItems, collection.Blueprint.CollectionName))
+	if err != nil {
+		return fmt.Errorf("could not execute query: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := blueprint.Item{}
+		err = rows.MapScan(item)
+		if err != nil {
+			return fmt.Errorf("could not scan row: %v", err)
+		}
+		err = fn(item)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -10,6 +11,11 @@ import (
 func (db *DB) CreateItem(collection *blueprint.Collection, item blueprint.Item) error {
 	// Set updated_at field
 	item[blueprint.KeyUpdatedAt] = time.Now().Unix()
+	// Set slug fields
+	err := db.setSlugs(collection, item, nil)
+	if err != nil {
+		return err
+	}
 	statementStart := fmt.Sprintf("INSERT INTO %s (", collection.Blueprint.CollectionName)
 	statementEnd := "VALUES ("
 	fieldAdded := false
@@ -32,13 +38,36 @@ func (db *DB) CreateItem(collection *blueprint.Collection, item blueprint.Item)
 		fieldAdded = true
 	}
 	statement := statementStart + ") " + statementEnd + ");"
-	_, err := db.db.NamedExec(statement, item)
-	return err
+	var result sql.Result
+	if collection.Blueprint.Singleton {
+		result, err = db.createSingleton(collection, statement, item)
+	} else {
+		result, err = db.db.NamedExec(statement, item)
+	}
+	if err != nil {
+		return err
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		item[blueprint.KeyID] = id
+	}
+	db.emit(Event{Type: EventItemCreated, Collection: collection.Blueprint.CollectionName, Item: item, Time: item[blueprint.KeyUpdatedAt].(int64)})
+	return nil
 }
 
 func (db *DB) UpdateItem(collection *blueprint.Collection, item blueprint.Item) error {
 	// Set updated_at field
 	item[blueprint.KeyUpdatedAt] = time.Now().Unix()
+	// Set slug fields
+	if len(collection.Blueprint.SlugFields()) > 0 {
+		existingItem, err := db.GetItem(collection, blueprint.KeyID, fmt.Sprintf("%v", item[blueprint.KeyID]))
+		if err != nil {
+			return fmt.Errorf("could not get existing item: %v", err)
+		}
+		err = db.setSlugs(collection, item, existingItem)
+		if err != nil {
+			return err
+		}
+	}
 	statementStart := fmt.Sprintf("UPDATE %s SET (", collection.Blueprint.CollectionName)
 	statementEnd := "= ("
 	fieldAdded := false
@@ -62,5 +91,53 @@ func (db *DB) UpdateItem(collection *blueprint.Collection, item blueprint.Item)
 	}
 	statement := statementStart + ") " + statementEnd + ") WHERE id = :id;"
 	_, err := db.db.NamedExec(statement, item)
-	return err
+	if err != nil {
+		return err
+	}
+	db.emit(Event{Type: EventItemUpdated, Collection: collection.Blueprint.CollectionName, Item: item, Time: item[blueprint.KeyUpdatedAt].(int64)})
+	return nil
+}
+
+// PublishItem
+// marks the item as published
+func (db *DB) PublishItem(collection *blueprint.Collection, id string) error {
+	now := time.Now().Unix()
+	_, err := db.db.Exec(fmt.Sprintf(statementPublishItem, collection.Blueprint.CollectionName), now, now, id)
+	if err != nil {
+		return err
+	}
+	item, err := db.GetItem(collection, blueprint.KeyID, id)
+	if err != nil {
+		return fmt.Errorf("could not get published item: %v", err)
+	}
+	db.emit(Event{Type: EventItemPublished, Collection: collection.Blueprint.CollectionName, Item: item, Time: now})
+	return nil
+}
+
+// DeleteItem
+// removes the item together with its translations
+func (db *DB) DeleteItem(collection *blueprint.Collection, id string) error {
+	item, err := db.GetItem(collection, blueprint.KeyID, id)
+	if err != nil {
+		return err
+	}
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf(statementDeleteItem, collection.Blueprint.CollectionName), id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(statementDeleteItemTranslations, collection.Blueprint.CollectionName, id)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	db.emit(Event{Type: EventItemDeleted, Collection: collection.Blueprint.CollectionName, Item: item, Time: time.Now().Unix()})
+	return nil
 }
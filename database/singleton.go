@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+var (
+	ErrorSingletonExists = errors.New("the collection is a singleton and already has an item")
+)
+
+// GetSingleton
+// returns the item of a singleton collection. If there are several items, e. g. because the collection has been
+// turned into a singleton later, the oldest one is returned. Returns sql.ErrNoRows if there is no item yet.
+func (db *DB) GetSingleton(collection *blueprint.Collection) (blueprint.Item, error) {
+	result := blueprint.Item{}
+	row := db.db.QueryRowx(fmt.Sprintf(statementGetFirstItem, collection.Blueprint.CollectionName))
+	err := row.MapScan(result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createSingleton
+// inserts the item of a singleton collection if the table is still empty.
+// Concurrent inserts that pass the check are rejected by the unique index, see createSingletonIndex.
+func (db *DB) createSingleton(collection *blueprint.Collection, statement string, item blueprint.Item) (sql.Result, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var count int64
+	err = tx.Get(&count, fmt.Sprintf(statementCountItems, collection.Blueprint.CollectionName))
+	if err != nil {
+		return nil, fmt.Errorf("could not count items: %v", err)
+	}
+	if count > 0 {
+		return nil, ErrorSingletonExists
+	}
+	result, err := tx.NamedExec(statement, item)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if _, getErr := db.GetSingleton(collection); getErr == nil {
+			// Another insert has been committed first
+			return nil, ErrorSingletonExists
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// countItems
+// returns the number of items in the table of the collection
+func (db *DB) countItems(collection *blueprint.Collection) (int64, error) {
+	var count int64
+	err := db.db.Get(&count, fmt.Sprintf(statementCountItems, collection.Blueprint.CollectionName))
+	return count, err
+}
+
+// createSingletonIndex
+// creates a unique index on a constant for singleton collections, so that the database rejects a second item.
+// The index is not created if the table already has several items, e. g. because the collection has been turned
+// into a singleton later, see PlanMigration. It is dropped if the collection is not a singleton (anymore).
+func (db *DB) createSingletonIndex(collection *blueprint.Collection) error {
+	tableName := collection.Blueprint.CollectionName
+	if !collection.Blueprint.Singleton {
+		_, err := db.db.Exec(fmt.Sprintf(statementDropSingletonIndex, tableName))
+		if err != nil {
+			return fmt.Errorf("could not drop singleton index: %v", err)
+		}
+		return nil
+	}
+	count, err := db.countItems(collection)
+	if err != nil {
+		return fmt.Errorf("could not count items: %v", err)
+	}
+	if count > 1 {
+		return nil
+	}
+	_, err = db.db.Exec(fmt.Sprintf(statementCreateSingletonIndex, tableName))
+	if err != nil {
+		return fmt.Errorf("could not create singleton index: %v", err)
+	}
+	return nil
+}
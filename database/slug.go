@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+// setSlugs
+// generates, normalizes and deduplicates the values of all slug fields of the item.
+// existingItem is the currently stored version of the item and nil for new items.
+func (db *DB) setSlugs(collection *blueprint.Collection, item blueprint.Item, existingItem blueprint.Item) error {
+	for _, field := range collection.Blueprint.SlugFields() {
+		if existingItem != nil && field.Slug.LockAfterPublish && existingItem.IsPublished() {
+			// Keep the stored slug
+			item[field.Name] = existingItem[field.Name]
+			continue
+		}
+		value, _ := item[field.Name].(string)
+		if value == "" {
+			// Generate slug from source field
+			source, ok := item[field.Slug.Source]
+			if !ok && existingItem != nil {
+				source = existingItem[field.Slug.Source]
+			}
+			if source != nil {
+				value = fmt.Sprintf("%v", source)
+			}
+		}
+		slug := blueprint.Slugify(value)
+		if slug == "" {
+			return fmt.Errorf("could not generate slug for field %s", field.Name)
+		}
+		var excludeID interface{} = -1
+		if existingItem != nil {
+			excludeID = existingItem[blueprint.KeyID]
+		}
+		slug, err := db.uniqueSlug(collection, field.Name, slug, excludeID)
+		if err != nil {
+			return fmt.Errorf("could not make slug for field %s unique: %v", field.Name, err)
+		}
+		item[field.Name] = slug
+	}
+	return nil
+}
+
+// uniqueSlug
+// appends a numeric suffix to the slug if it is already used by another item of the collection
+func (db *DB) uniqueSlug(collection *blueprint.Collection, fieldName string, slug string, excludeID interface{}) (string, error) {
+	var usedSlugs []string
+	statement := fmt.Sprintf(statementGetSimilarSlugs, fieldName, collection.Blueprint.CollectionName, fieldName, fieldName)
+	err := db.db.Select(&usedSlugs, statement, slug, slug+"-%", excludeID)
+	if err != nil {
+		return "", err
+	}
+	candidate := slug
+	for suffix := 2; slices.Contains(usedSlugs, candidate); suffix++ {
+		candidate = fmt.Sprintf("%s-%d", slug, suffix)
+	}
+	return candidate, nil
+}
+
+type storedSlug struct {
+	ID   int64  `db:"id"`
+	Slug string `db:"slug"`
+}
+
+// getDuplicateSlugs
+// returns all items whose slug is also used by another item, ordered by id
+func (db *DB) getDuplicateSlugs(collection *blueprint.Collection, fieldName string) ([]storedSlug, error) {
+	var duplicates []storedSlug
+	err := db.db.Select(&duplicates, fmt.Sprintf(statementGetDuplicateSlugs, fieldName, collection.Blueprint.CollectionName))
+	if err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// getUnnormalizedSlugs
+// returns all items whose slug is not in the form of Slugify, e. g. of items that were stored before the field was a slug field
+func (db *DB) getUnnormalizedSlugs(collection *blueprint.Collection, fieldName string) ([]storedSlug, error) {
+	var slugs []storedSlug
+	err := db.db.Select(&slugs, fmt.Sprintf(statementGetSlugs, fieldName, collection.Blueprint.CollectionName))
+	if err != nil {
+		return nil, err
+	}
+	var unnormalized []storedSlug
+	for _, slug := range slugs {
+		if blueprint.Slugify(slug.Slug) != slug.Slug {
+			unnormalized = append(unnormalized, slug)
+		}
+	}
+	return unnormalized, nil
+}
+
+// normalizeSlugs
+// converts the slugs of existing items with Slugify, so that they are valid in URLs.
+// Values without any letter or digit are replaced by the id of the item.
+func (db *DB) normalizeSlugs(collection *blueprint.Collection, fieldName string) error {
+	unnormalized, err := db.getUnnormalizedSlugs(collection, fieldName)
+	if err != nil {
+		return fmt.Errorf("could not get slugs: %v", err)
+	}
+	for _, stored := range unnormalized {
+		slug := blueprint.Slugify(stored.Slug)
+		if slug == "" {
+			slug = strconv.FormatInt(stored.ID, 10)
+		}
+		slug, err = db.uniqueSlug(collection, fieldName, slug, stored.ID)
+		if err != nil {
+			return err
+		}
+		_, err = db.db.Exec(fmt.Sprintf(statementSetSlug, collection.Blueprint.CollectionName, fieldName), slug, time.Now().Unix(), stored.ID)
+		if err != nil {
+			return fmt.Errorf("could not update slug of item %d: %v", stored.ID, err)
+		}
+	}
+	return nil
+}
+
+// dedupeSlugs
+// makes the slugs of existing items unique, e. g. of items that were stored before the field was a slug field.
+// The item with the lowest id keeps its slug, the others get a numeric suffix.
+func (db *DB) dedupeSlugs(collection *blueprint.Collection, fieldName string) error {
+	duplicates, err := db.getDuplicateSlugs(collection, fieldName)
+	if err != nil {
+		return fmt.Errorf("could not get duplicate slugs: %v", err)
+	}
+	kept := make(map[string]bool)
+	for _, duplicate := range duplicates {
+		if !kept[duplicate.Slug] {
+			kept[duplicate.Slug] = true
+			continue
+		}
+		slug := blueprint.Slugify(duplicate.Slug)
+		if slug == "" {
+			slug = strconv.FormatInt(duplicate.ID, 10)
+		}
+		slug, err = db.uniqueSlug(collection, fieldName, slug, duplicate.ID)
+		if err != nil {
+			return err
+		}
+		_, err = db.db.Exec(fmt.Sprintf(statementSetSlug, collection.Blueprint.CollectionName, fieldName), slug, time.Now().Unix(), duplicate.ID)
+		if err != nil {
+			return fmt.Errorf("could not update slug of item %d: %v", duplicate.ID, err)
+		}
+	}
+	return nil
+}
+
+// createSlugIndexes
+// ensures uniqueness of slugs on the database level. Existing slugs are normalized and made unique first, see normalizeSlugs and dedupeSlugs.
+func (db *DB) createSlugIndexes(collection *blueprint.Collection) error {
+	tableName := collection.Blueprint.CollectionName
+	for _, field := range collection.Blueprint.SlugFields() {
+		err := db.normalizeSlugs(collection, field.Name)
+		if err != nil {
+			return fmt.Errorf("could not normalize slug field %s: %v", field.Name, err)
+		}
+		err = db.dedupeSlugs(collection, field.Name)
+		if err != nil {
+			return fmt.Errorf("could not dedupe slug field %s: %v", field.Name, err)
+		}
+		_, err = db.db.Exec(fmt.Sprintf(statementCreateUniqueIndex, tableName, field.Name, tableName, field.Name))
+		if err != nil {
+			return fmt.Errorf("could not create unique index for slug field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
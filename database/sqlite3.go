@@ -7,10 +7,17 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// NewSqlite3Instance
+// opens the database and holds a shared lock on it until it is closed, see LockSqlite3
 func NewSqlite3Instance(databasePath string) (*DB, error) {
+	lock, err := LockSqlite3(databasePath, false)
+	if err != nil {
+		return nil, err
+	}
 	db, err := sqlx.Connect("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", databasePath))
 	if err != nil {
+		lock.Release()
 		return nil, err
 	}
-	return &DB{db: db, dbType: DatabaseTypeSqlite3}, nil
+	return &DB{db: db, pool: db, dbType: DatabaseTypeSqlite3, lock: lock}, nil
 }
@@ -3,7 +3,106 @@ package database
 var (
 	// Create table
 	statementCreateTable                 = "CREATE TABLE IF NOT EXISTS %s (%s);"
+	statementAddColumn                   = "ALTER TABLE %s ADD COLUMN %s %s;"
+	statementCreateUniqueIndex           = "CREATE UNIQUE INDEX IF NOT EXISTS %s_%s_unique ON %s (%s);"
 	statementCreateReferenceTableSqlite3 = "CREATE TABLE IF NOT EXISTS %s (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, %s_id INTEGER NOT NULL, %s_id INTEGER NOT NULL, FOREIGN KEY(%s_id) REFERENCES %s(id), FOREIGN KEY(%s_id) REFERENCES %s(id));"
 	// TODO: Fix this for Postgres
 	statementCreateReferenceTablePostgres = "CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL NOT NULL PRIMARY KEY, %s_id BIGINT NOT NULL, %s_id BIGINT NOT NULL, FOREIGN KEY(%s_id) REFERENCES %s(id), FOREIGN KEY(%s_id) REFERENCES %s(id));"
+	// Slugs
+	statementGetSimilarSlugs   = "SELECT %s FROM %s WHERE (%s = $1 OR %s LIKE $2) AND id != $3;"
+	statementGetDuplicateSlugs = "SELECT id, %[1]s AS slug FROM %[2]s WHERE %[1]s IN (SELECT %[1]s FROM %[2]s GROUP BY %[1]s HAVING COUNT(*) > 1) ORDER BY id;"
+	statementGetSlugs          = "SELECT id, %[1]s AS slug FROM %[2]s WHERE %[1]s IS NOT NULL AND %[1]s != '' ORDER BY id;"
+	statementSetSlug           = "UPDATE %s SET %s = $1, updated_at = $2 WHERE id = $3;"
+	// Singletons
+	statementGetFirstItem         = "SELECT * FROM %s ORDER BY id LIMIT 1;"
+	statementCountItems           = "SELECT COUNT(*) FROM %s;"
+	statementCreateSingletonIndex = "CREATE UNIQUE INDEX IF NOT EXISTS %[1]s_singleton ON %[1]s ((1));"
+	statementDropSingletonIndex   = "DROP INDEX IF EXISTS %s_singleton;"
+	// Publishing
+	statementPublishItem = "UPDATE %s SET published_at = $1, updated_at = $2 WHERE id = $3;"
+	// Published items
+	statementGetPublishedItems   = "SELECT * FROM %s WHERE published_at > 0 ORDER BY published_at DESC, id DESC LIMIT $1 OFFSET $2;"
+	statementCountPublishedItems = "SELECT COUNT(*) FROM %s WHERE published_at > 0;"
+	statementGetLatestPublished  = "SELECT COALESCE(MAX(updated_at), 0) FROM %s WHERE published_at > 0;"
+	// Deleting
+	statementDeleteItem             = "DELETE FROM %s WHERE id = $1;"
+	statementDeleteItemTranslations = "DELETE FROM translations WHERE collection = $1 AND item_id = $2;"
+	// Table information
+	statementGetColumnNamesSqlite3  = "SELECT name FROM pragma_table_info($1);"
+	statementGetColumnNamesPostgres = "SELECT column_name FROM information_schema.columns WHERE table_name = $1;"
+	statementGetColumnsSqlite3      = "SELECT name, type, \"notnull\" = 1 AS not_null FROM pragma_table_info($1);"
+	statementGetColumnsPostgres     = "SELECT column_name AS name, data_type AS type, is_nullable = 'NO' AS not_null FROM information_schema.columns WHERE table_name = $1;"
+	statementDropNotNullPostgres    = "ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;"
+	// Assets
+	statementCreateAssetTableSqlite3  = "CREATE TABLE IF NOT EXISTS assets (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, uuid TEXT NOT NULL UNIQUE, filename TEXT NOT NULL, storage_key TEXT NOT NULL, mime_type TEXT NOT NULL, size INTEGER NOT NULL, width INTEGER NOT NULL, height INTEGER NOT NULL, alt_text TEXT NOT NULL, focal_x REAL NOT NULL DEFAULT 0.5, focal_y REAL NOT NULL DEFAULT 0.5, created_at INTEGER NOT NULL);"
+	statementCreateAssetTablePostgres = "CREATE TABLE IF NOT EXISTS assets (id BIGSERIAL NOT NULL PRIMARY KEY, uuid CHARACTER(36) NOT NULL UNIQUE, filename TEXT NOT NULL, storage_key TEXT NOT NULL, mime_type TEXT NOT NULL, size BIGINT NOT NULL, width INTEGER NOT NULL, height INTEGER NOT NULL, alt_text TEXT NOT NULL, focal_x DOUBLE PRECISION NOT NULL DEFAULT 0.5, focal_y DOUBLE PRECISION NOT NULL DEFAULT 0.5, created_at BIGINT NOT NULL);"
+	statementInsertAsset              = "INSERT INTO assets (uuid, filename, storage_key, mime_type, size, width, height, alt_text, focal_x, focal_y, created_at) VALUES (:uuid, :filename, :storage_key, :mime_type, :size, :width, :height, :alt_text, :focal_x, :focal_y, :created_at);"
+	statementGetAssets                = "SELECT * FROM assets ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2;"
+	statementGetAsset                 = "SELECT * FROM assets WHERE uuid = $1;"
+	statementUpdateAssetAltText       = "UPDATE assets SET alt_text = $1 WHERE uuid = $2;"
+	statementUpdateAssetFocalPoint    = "UPDATE assets SET focal_x = $1, focal_y = $2 WHERE uuid = $3;"
+	statementDeleteAsset              = "DELETE FROM assets WHERE uuid = $1;"
+	// Translations
+	statementCreateTranslationTableSqlite3  = "CREATE TABLE IF NOT EXISTS translations (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, collection TEXT NOT NULL, item_id INTEGER NOT NULL, locale TEXT NOT NULL, field TEXT NOT NULL, value TEXT NOT NULL, UNIQUE(collection, item_id, locale, field));"
+	statementCreateTranslationTablePostgres = "CREATE TABLE IF NOT EXISTS translations (id BIGSERIAL NOT NULL PRIMARY KEY, collection TEXT NOT NULL, item_id BIGINT NOT NULL, locale TEXT NOT NULL, field TEXT NOT NULL, value TEXT NOT NULL, UNIQUE(collection, item_id, locale, field));"
+	statementUpsertTranslation              = "INSERT INTO translations (collection, item_id, locale, field, value) VALUES ($1, $2, $3, $4, $5) ON CONFLICT(collection, item_id, locale, field) DO UPDATE SET value = excluded.value;"
+	statementDeleteTranslation              = "DELETE FROM translations WHERE collection = $1 AND item_id = $2 AND locale = $3 AND field = $4;"
+	statementGetTranslations                = "SELECT item_id, locale, field, value FROM translations WHERE collection = $1 AND item_id = $2 AND locale = $3;"
+	statementGetTranslationCounts           = "SELECT locale, COUNT(*) FROM translations WHERE collection = $1 AND item_id = $2 GROUP BY locale;"
+	statementGetTranslationsForItems        = "SELECT item_id, locale, field, value FROM translations WHERE collection = ? AND item_id IN (?) AND locale IN (?);"
+	// Webhooks
+	statementCreateWebhookDeliveryTableSqlite3  = "CREATE TABLE IF NOT EXISTS webhook_deliveries (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, webhook TEXT NOT NULL, event TEXT NOT NULL, collection TEXT NOT NULL, payload TEXT NOT NULL, status TEXT NOT NULL, attempts INTEGER NOT NULL, next_attempt_at INTEGER NOT NULL, last_status_code INTEGER NOT NULL, last_error TEXT NOT NULL, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL);"
+	statementCreateWebhookDeliveryTablePostgres = "CREATE TABLE IF NOT EXISTS webhook_deliveries (id BIGSERIAL NOT NULL PRIMARY KEY, webhook TEXT NOT NULL, event TEXT NOT NULL, collection TEXT NOT NULL, payload TEXT NOT NULL, status TEXT NOT NULL, attempts INTEGER NOT NULL, next_attempt_at BIGINT NOT NULL, last_status_code INTEGER NOT NULL, last_error TEXT NOT NULL, created_at BIGINT NOT NULL, updated_at BIGINT NOT NULL);"
+	statementInsertWebhookDelivery              = "INSERT INTO webhook_deliveries (webhook, event, collection, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, updated_at) VALUES (:webhook, :event, :collection, :payload, :status, :attempts, :next_attempt_at, :last_status_code, :last_error, :created_at, :updated_at);"
+	statementGetDueWebhookDeliveries            = "SELECT * FROM webhook_deliveries WHERE status IN ($1, $2) AND next_attempt_at <= $3 ORDER BY next_attempt_at, id LIMIT $4;"
+	statementClaimWebhookDelivery               = "UPDATE webhook_deliveries SET status = $1, next_attempt_at = $2, updated_at = $3 WHERE id = $4 AND status = $5 AND next_attempt_at = $6;"
+	statementUpdateWebhookDelivery              = "UPDATE webhook_deliveries SET status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at, last_status_code = :last_status_code, last_error = :last_error, updated_at = :updated_at WHERE id = :id;"
+	statementGetWebhookDeliveries               = "SELECT * FROM webhook_deliveries ORDER BY id DESC LIMIT $1 OFFSET $2;"
+	// Users
+	statementCreateUserTableSqlite3  = "CREATE TABLE IF NOT EXISTS users (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL, role TEXT NOT NULL, locale TEXT NOT NULL DEFAULT '', created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL);"
+	statementCreateUserTablePostgres = "CREATE TABLE IF NOT EXISTS users (id BIGSERIAL NOT NULL PRIMARY KEY, email TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL, role TEXT NOT NULL, locale TEXT NOT NULL DEFAULT '', created_at BIGINT NOT NULL, updated_at BIGINT NOT NULL);"
+	statementInsertUser              = "INSERT INTO users (email, password_hash, role, created_at, updated_at) VALUES (:email, :password_hash, :role, :created_at, :updated_at);"
+	statementGetUsers                = "SELECT * FROM users ORDER BY email;"
+	statementGetUser                 = "SELECT * FROM users WHERE id = $1;"
+	statementGetUserByEmail          = "SELECT * FROM users WHERE email = $1;"
+	statementCountUsers              = "SELECT COUNT(*) FROM users;"
+	statementUpdateUserRole          = "UPDATE users SET role = $1, updated_at = $2 WHERE id = $3;"
+	statementUpdateUserPassword      = "UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3;"
+	statementUpdateUserLocale        = "UPDATE users SET locale = $1, updated_at = $2 WHERE id = $3;"
+	// Sessions
+	statementCreateSessionTableSqlite3  = "CREATE TABLE IF NOT EXISTS sessions (token_hash TEXT NOT NULL PRIMARY KEY, user_id INTEGER NOT NULL, expires_at INTEGER NOT NULL, created_at INTEGER NOT NULL);"
+	statementCreateSessionTablePostgres = "CREATE TABLE IF NOT EXISTS sessions (token_hash TEXT NOT NULL PRIMARY KEY, user_id BIGINT NOT NULL, expires_at BIGINT NOT NULL, created_at BIGINT NOT NULL);"
+	statementInsertSession              = "INSERT INTO sessions (token_hash, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4);"
+	statementGetSessionUser             = "SELECT users.* FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.token_hash = $1 AND sessions.expires_at > $2;"
+	statementDeleteSession              = "DELETE FROM sessions WHERE token_hash = $1;"
+	statementDeleteExpiredSessions      = "DELETE FROM sessions WHERE expires_at <= $1;"
+	statementDeleteUserSessions         = "DELETE FROM sessions WHERE user_id = $1;"
+	// Audit log
+	statementCreateAuditLogTableSqlite3  = "CREATE TABLE IF NOT EXISTS audit_log (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, created_at INTEGER NOT NULL, action TEXT NOT NULL, actor_id INTEGER NOT NULL, actor TEXT NOT NULL, ip TEXT NOT NULL, collection TEXT NOT NULL, target TEXT NOT NULL, diff TEXT NOT NULL);"
+	statementCreateAuditLogTablePostgres = "CREATE TABLE IF NOT EXISTS audit_log (id BIGSERIAL NOT NULL PRIMARY KEY, created_at BIGINT NOT NULL, action TEXT NOT NULL, actor_id BIGINT NOT NULL, actor TEXT NOT NULL, ip TEXT NOT NULL, collection TEXT NOT NULL, target TEXT NOT NULL, diff TEXT NOT NULL);"
+	// The audit log is append-only, changing or removing entries is rejected by the database
+	statementsProtectAuditLogSqlite3 = []string{
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;",
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;",
+	}
+	statementsProtectAuditLogPostgres = []string{
+		// Rules of earlier versions silently discarded the statements, so that the triggers would not fire
+		"DROP RULE IF EXISTS audit_log_no_update ON audit_log;",
+		"DROP RULE IF EXISTS audit_log_no_delete ON audit_log;",
+		"CREATE OR REPLACE FUNCTION audit_log_reject() RETURNS trigger AS $$ BEGIN RAISE EXCEPTION 'audit log is append-only'; END; $$ LANGUAGE plpgsql;",
+		"DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;",
+		"CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log FOR EACH ROW EXECUTE FUNCTION audit_log_reject();",
+		"DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;",
+		"CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log FOR EACH STATEMENT EXECUTE FUNCTION audit_log_reject();",
+	}
+	statementInsertAuditEntry = "INSERT INTO audit_log (created_at, action, actor_id, actor, ip, collection, target, diff) VALUES (:created_at, :action, :actor_id, :actor, :ip, :collection, :target, :diff);"
+	statementGetAuditEntries  = "SELECT * FROM audit_log%s ORDER BY id DESC LIMIT ? OFFSET ?;"
+	// References
+	statementDeleteReferences   = "DELETE FROM %s WHERE %s_id = $1;"
+	statementInsertReference    = "INSERT INTO %s (%s_id, %s_id) VALUES ($1, $2);"
+	statementGetReferencedUUIDs = "SELECT %s.uuid FROM %s JOIN %s ON %s.id = %s.%s_id WHERE %s.%s_id = $1 ORDER BY %s.id;"
+	// Items
+	statementGetAllItems = "SELECT * FROM %s ORDER BY id;"
+	// Backup
+	statementIntegrityCheckSqlite3 = "PRAGMA integrity_check;"
 )
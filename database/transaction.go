@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// handle
+// is implemented by *sqlx.DB and *sqlx.Tx, so that all methods of DB can be used inside a transaction
+type handle interface {
+	sqlx.Ext
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+}
+
+// transaction
+// is a transaction that has been started by a method of DB, see begin
+type transaction interface {
+	handle
+	Commit() error
+	Rollback() error
+}
+
+// nestedTransaction
+// is used by methods of DB that run inside Transaction. The surrounding transaction is committed or rolled back by Transaction.
+type nestedTransaction struct {
+	*sqlx.Tx
+}
+
+func (nestedTransaction) Commit() error {
+	return nil
+}
+
+func (nestedTransaction) Rollback() error {
+	return nil
+}
+
+type transactionContextKey struct{}
+
+// Transaction
+// runs fn with a DB whose methods all use the same transaction. The transaction is committed if fn returns nil
+// and rolled back otherwise. Events of the changes are emitted after the commit.
+func (db *DB) Transaction(fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	sqlTx, err := db.pool.Beginx()
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+	tx := &DB{db: sqlTx, pool: db.pool, tx: sqlTx, parent: db, dbType: db.dbType}
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	err = sqlTx.Commit()
+	if err != nil {
+		return err
+	}
+	for _, event := range tx.pendingEvents {
+		db.emit(event)
+	}
+	return nil
+}
+
+// ContextWithTransaction
+// returns a context that carries the DB of a transaction, e. g. for hooks and the audit log, see FromContext
+func ContextWithTransaction(ctx context.Context, tx *DB) context.Context {
+	return context.WithValue(ctx, transactionContextKey{}, tx)
+}
+
+// FromContext
+// returns the DB of the transaction in the context, or db if there is none
+func FromContext(ctx context.Context, db *DB) *DB {
+	if tx, ok := ctx.Value(transactionContextKey{}).(*DB); ok {
+		return tx
+	}
+	return db
+}
+
+// begin
+// starts a transaction. Inside Transaction, the surrounding transaction is used instead.
+func (db *DB) begin() (transaction, error) {
+	if db.tx != nil {
+		return nestedTransaction{db.tx}, nil
+	}
+	return db.pool.Beginx()
+}
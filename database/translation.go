@@ -0,0 +1,206 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+// Translation
+// is the value of a localized field in a locale other than the default locale
+type Translation struct {
+	ItemID int64  `db:"item_id"`
+	Locale string `db:"locale"`
+	Field  string `db:"field"`
+	Value  string `db:"value"`
+}
+
+func (db *DB) CreateTranslationTable() error {
+	var statement string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		statement = statementCreateTranslationTableSqlite3
+	case DatabaseTypePostgres:
+		statement = statementCreateTranslationTablePostgres
+	default:
+		return ErrorUnknownDatabaseType
+	}
+	_, err := db.db.Exec(statement)
+	return err
+}
+
+// SetTranslations
+// stores the values of all localized fields that are contained in values as text, see translationValue.
+// Empty values remove the translation, so that the fallback chain applies again.
+func (db *DB) SetTranslations(collection *blueprint.Collection, itemID string, locale string, values blueprint.Item) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, field := range collection.Blueprint.LocalizedFields() {
+		value, ok := values[field.Name]
+		if !ok {
+			continue
+		}
+		if value == nil || value == "" {
+			_, err = tx.Exec(statementDeleteTranslation, collection.Blueprint.CollectionName, itemID, locale, field.Name)
+		} else {
+			var text string
+			text, err = translationText(value)
+			if err == nil {
+				_, err = tx.Exec(statementUpsertTranslation, collection.Blueprint.CollectionName, itemID, locale, field.Name, text)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("could not set translation of field %s: %v", field.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTranslations
+// returns the translated values of an item in exactly the given locale, without applying fallbacks
+func (db *DB) GetTranslations(collection *blueprint.Collection, itemID string, locale string) (blueprint.Item, error) {
+	var translations []Translation
+	err := db.db.Select(&translations, statementGetTranslations, collection.Blueprint.CollectionName, itemID, locale)
+	if err != nil {
+		return nil, err
+	}
+	values := blueprint.Item{}
+	fields := collection.Blueprint.LocalizedFields()
+	for _, translation := range translations {
+		index := slices.IndexFunc(fields, func(field blueprint.BlueprintField) bool { return field.Name == translation.Field })
+		if index == -1 {
+			// The field is not localized anymore
+			continue
+		}
+		values[translation.Field], err = translationValue(fields[index], translation.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// translationText
+// converts a value into the text that is stored as translation. Arrays and objects are stored as JSON.
+func translationText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// translationValue
+// converts a stored translation back into a value of the type of the field
+func translationValue(field blueprint.BlueprintField, text string) (interface{}, error) {
+	switch field.Type {
+	case blueprint.TypeBoolean:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean translation of field %s: %v", field.Name, err)
+		}
+		return value, nil
+	case blueprint.TypeInt:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer translation of field %s: %v", field.Name, err)
+		}
+		return value, nil
+	default:
+		return text, nil
+	}
+}
+
+// GetTranslationCounts
+// returns the number of translated fields of an item per locale
+func (db *DB) GetTranslationCounts(collection *blueprint.Collection, itemID string) (map[string]int, error) {
+	rows, err := db.db.Queryx(statementGetTranslationCounts, collection.Blueprint.CollectionName, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var locale string
+		var count int
+		err = rows.Scan(&locale, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[locale] = count
+	}
+	return counts, rows.Err()
+}
+
+// LocalizeItems
+// replaces the values of localized fields with the first translation found in the locale chain.
+// Values of the default locale are stored in the collection table, so the chain stops there.
+func (db *DB) LocalizeItems(collection *blueprint.Collection, items []blueprint.Item, chain []string, defaultLocale string) error {
+	if len(items) == 0 || len(collection.Blueprint.LocalizedFields()) == 0 {
+		return nil
+	}
+	// Locales that are tried before the default locale
+	var locales []string
+	for _, locale := range chain {
+		if locale == defaultLocale {
+			break
+		}
+		locales = append(locales, locale)
+	}
+	if len(locales) == 0 {
+		return nil
+	}
+	itemIDs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item[blueprint.KeyID])
+	}
+	query, args, err := sqlx.In(statementGetTranslationsForItems, collection.Blueprint.CollectionName, itemIDs, locales)
+	if err != nil {
+		return err
+	}
+	var translations []Translation
+	err = db.db.Select(&translations, db.db.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("could not get translations: %v", err)
+	}
+	// Index translations by item and field, keeping the one with the highest priority
+	type key struct {
+		itemID int64
+		field  string
+	}
+	best := make(map[key]Translation)
+	for _, translation := range translations {
+		k := key{itemID: translation.ItemID, field: translation.Field}
+		current, ok := best[k]
+		if !ok || slices.Index(locales, translation.Locale) < slices.Index(locales, current.Locale) {
+			best[k] = translation
+		}
+	}
+	for _, item := range items {
+		itemID, _ := item[blueprint.KeyID].(int64)
+		for _, field := range collection.Blueprint.LocalizedFields() {
+			if translation, ok := best[key{itemID: itemID, field: field.Name}]; ok {
+				value, err := translationValue(field, translation.Value)
+				if err != nil {
+					return err
+				}
+				item[field.Name] = value
+			}
+		}
+	}
+	return nil
+}
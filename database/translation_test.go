@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+const (
+	testTranslationBlueprint = `{
+	"collection_display_name": {"en": "Events"},
+	"collection_name": "events",
+	"fields": [
+		{"name": "free", "display_name": "free", "type": "boolean", "localized": true},
+		{"name": "seats", "display_name": "seats", "type": "int", "localized": true},
+		{"name": "venue", "display_name": "venue", "type": "string", "localized": true}
+	]
+}`
+)
+
+func TestTranslationsKeepTypes(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "events.json"), []byte(testTranslationBlueprint), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectionLoader := blueprint.NewCollectionLoader(dir)
+	collection, err := collectionLoader.Get("events")
+	if err != nil {
+		t.Fatalf("could not load collection: %v", err)
+	}
+	db, err := NewSqlite3Instance(filepath.Join(dir, "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	err = db.CreateTranslationTable()
+	if err == nil {
+		err = db.CreateTable(collection, collectionLoader)
+	}
+	if err != nil {
+		t.Fatalf("could not create tables: %v", err)
+	}
+	item, err := blueprint.NewItem(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item[blueprint.KeyTitle] = "Concert"
+	item["free"] = true
+	item["seats"] = int64(100)
+	item["venue"] = "Hall"
+	err = db.CreateItem(collection, item)
+	if err != nil {
+		t.Fatalf("could not create item: %v", err)
+	}
+	id := fmt.Sprintf("%v", item[blueprint.KeyID])
+	err = db.SetTranslations(collection, id, "de", blueprint.Item{"free": false, "seats": int64(80), "venue": "Halle"})
+	if err != nil {
+		t.Fatalf("could not set translations: %v", err)
+	}
+	want := blueprint.Item{"free": false, "seats": int64(80), "venue": "Halle"}
+	translations, err := db.GetTranslations(collection, id, "de")
+	if err != nil {
+		t.Fatalf("could not get translations: %v", err)
+	}
+	items := []blueprint.Item{{blueprint.KeyID: item[blueprint.KeyID]}}
+	err = db.LocalizeItems(collection, items, []string{"de", "en"}, "en")
+	if err != nil {
+		t.Fatalf("could not localize items: %v", err)
+	}
+	for name, value := range want {
+		if translations[name] != value {
+			t.Errorf("translation of %s is %#v, want %#v", name, translations[name], value)
+		}
+		if items[0][name] != value {
+			t.Errorf("localized value of %s is %#v, want %#v", name, items[0][name], value)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type UserRole string
+
+const (
+	UserRoleAdmin  = UserRole("admin")  // Can access everything including settings
+	UserRoleEditor = UserRole("editor") // Can edit content and media
+)
+
+var (
+	AllUserRoles = []UserRole{
+		UserRoleAdmin,
+		UserRoleEditor,
+	}
+)
+
+type User struct {
+	ID           int64    `db:"id"`
+	Email        string   `db:"email"`
+	PasswordHash string   `db:"password_hash"`
+	Role         UserRole `db:"role"`
+	Locale       string   `db:"locale"` // Locale of the admin interface, empty to use the languages of the browser
+	CreatedAt    int64    `db:"created_at"`
+	UpdatedAt    int64    `db:"updated_at"`
+}
+
+func (db *DB) CreateUserTable() error {
+	var statements []string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		statements = []string{statementCreateUserTableSqlite3, statementCreateSessionTableSqlite3}
+	case DatabaseTypePostgres:
+		statements = []string{statementCreateUserTablePostgres, statementCreateSessionTablePostgres}
+	default:
+		return ErrorUnknownDatabaseType
+	}
+	for _, statement := range statements {
+		_, err := db.db.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateUser
+// stores a new user, the password has to be hashed already
+func (db *DB) CreateUser(user *User) error {
+	now := time.Now().Unix()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	result, err := db.db.NamedExec(statementInsertUser, user)
+	if err != nil {
+		return err
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		user.ID = id
+	}
+	return nil
+}
+
+func (db *DB) GetUsers() ([]User, error) {
+	var users []User
+	err := db.db.Select(&users, statementGetUsers)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (db *DB) GetUser(id int64) (*User, error) {
+	var user User
+	err := db.db.Get(&user, statementGetUser, id)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (db *DB) GetUserByEmail(email string) (*User, error) {
+	var user User
+	err := db.db.Get(&user, statementGetUserByEmail, email)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (db *DB) CountUsers() (int64, error) {
+	var count int64
+	err := db.db.Get(&count, statementCountUsers)
+	return count, err
+}
+
+func (db *DB) UpdateUserRole(id int64, role UserRole) error {
+	_, err := db.db.Exec(statementUpdateUserRole, role, time.Now().Unix(), id)
+	return err
+}
+
+// UpdateUserLocale
+// stores the locale of the admin interface chosen by the user
+func (db *DB) UpdateUserLocale(id int64, locale string) error {
+	_, err := db.db.Exec(statementUpdateUserLocale, locale, time.Now().Unix(), id)
+	return err
+}
+
+// UpdateUserPassword
+// stores the new password hash and ends all sessions of the user
+func (db *DB) UpdateUserPassword(id int64, passwordHash string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(statementUpdateUserPassword, passwordHash, time.Now().Unix(), id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(statementDeleteUserSessions, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateSession
+// stores a session for the token. Only a hash of the token is stored.
+func (db *DB) CreateSession(token string, userID int64, duration time.Duration) error {
+	now := time.Now()
+	_, err := db.db.Exec(statementDeleteExpiredSessions, now.Unix())
+	if err != nil {
+		return err
+	}
+	_, err = db.db.Exec(statementInsertSession, hashToken(token), userID, now.Add(duration).Unix(), now.Unix())
+	return err
+}
+
+// GetSessionUser
+// returns the user of an unexpired session
+func (db *DB) GetSessionUser(token string) (*User, error) {
+	var user User
+	err := db.db.Get(&user, statementGetSessionUser, hashToken(token), time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (db *DB) DeleteSession(token string) error {
+	_, err := db.db.Exec(statementDeleteSession, hashToken(token))
+	return err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
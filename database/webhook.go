@@ -0,0 +1,110 @@
+package database
+
+import (
+	"time"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   = WebhookDeliveryStatus("pending")
+	WebhookDeliverySending   = WebhookDeliveryStatus("sending") // Claimed by a dispatcher until next_attempt_at, retried afterwards
+	WebhookDeliverySucceeded = WebhookDeliveryStatus("succeeded")
+	WebhookDeliveryFailed    = WebhookDeliveryStatus("failed") // No more attempts will be made
+)
+
+// WebhookDelivery
+// is an entry of the persistent delivery queue and log of outgoing webhooks
+type WebhookDelivery struct {
+	ID             int64                 `db:"id"`
+	Webhook        string                `db:"webhook"`
+	Event          string                `db:"event"`
+	Collection     string                `db:"collection"`
+	Payload        string                `db:"payload"`
+	Status         WebhookDeliveryStatus `db:"status"`
+	Attempts       int                   `db:"attempts"`
+	NextAttemptAt  int64                 `db:"next_attempt_at"`
+	LastStatusCode int                   `db:"last_status_code"`
+	LastError      string                `db:"last_error"`
+	CreatedAt      int64                 `db:"created_at"`
+	UpdatedAt      int64                 `db:"updated_at"`
+}
+
+func (db *DB) CreateWebhookDeliveryTable() error {
+	var statement string
+	switch db.dbType {
+	case DatabaseTypeSqlite3:
+		statement = statementCreateWebhookDeliveryTableSqlite3
+	case DatabaseTypePostgres:
+		statement = statementCreateWebhookDeliveryTablePostgres
+	default:
+		return ErrorUnknownDatabaseType
+	}
+	_, err := db.db.Exec(statement)
+	return err
+}
+
+// EnqueueWebhookDelivery
+// adds a delivery that is due immediately
+func (db *DB) EnqueueWebhookDelivery(delivery *WebhookDelivery) error {
+	now := time.Now().Unix()
+	delivery.Status = WebhookDeliveryPending
+	delivery.NextAttemptAt = now
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	_, err := db.db.NamedExec(statementInsertWebhookDelivery, delivery)
+	return err
+}
+
+// GetDueWebhookDeliveries
+// returns pending deliveries whose next attempt is due and deliveries whose claim has expired, oldest first
+func (db *DB) GetDueWebhookDeliveries(limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.db.Select(&deliveries, statementGetDueWebhookDeliveries, WebhookDeliveryPending, WebhookDeliverySending, time.Now().Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery
+// marks a due delivery as sending for the duration of the lease. It returns false if the delivery
+// has been changed since it was read, e. g. because another process claimed it first.
+func (db *DB) ClaimWebhookDelivery(delivery *WebhookDelivery, lease time.Duration) (bool, error) {
+	now := time.Now()
+	nextAttemptAt := now.Add(lease).Unix()
+	result, err := db.db.Exec(statementClaimWebhookDelivery, WebhookDeliverySending, nextAttemptAt, now.Unix(), delivery.ID, delivery.Status, delivery.NextAttemptAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows != 1 {
+		return false, nil
+	}
+	delivery.Status = WebhookDeliverySending
+	delivery.NextAttemptAt = nextAttemptAt
+	delivery.UpdatedAt = now.Unix()
+	return true, nil
+}
+
+// UpdateWebhookDelivery
+// stores the result of a delivery attempt
+func (db *DB) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now().Unix()
+	_, err := db.db.NamedExec(statementUpdateWebhookDelivery, delivery)
+	return err
+}
+
+// GetWebhookDeliveries
+// returns the most recent deliveries for the delivery log
+func (db *DB) GetWebhookDeliveries(limit int, offset int64) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.db.Select(&deliveries, statementGetWebhookDeliveries, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
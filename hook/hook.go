@@ -0,0 +1,121 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+type Operation string
+
+const (
+	OperationCreate  = Operation("create")
+	OperationUpdate  = Operation("update")
+	OperationDelete  = Operation("delete")
+	OperationPublish = Operation("publish")
+)
+
+// Event
+// is passed to the hooks of an operation on an item
+type Event struct {
+	Operation  Operation
+	Collection *blueprint.Collection
+	// Before create and update: the item that will be written, hooks may modify it.
+	// Before delete and publish: the current item from the database, changes are ignored.
+	// After an operation: the item as it has been written.
+	Item blueprint.Item
+	// Locale of the written values, empty for the default locale
+	Locale string
+}
+
+// CollectionName
+// is a shortcut to filter events in hooks
+func (e *Event) CollectionName() string {
+	return e.Collection.Blueprint.CollectionName
+}
+
+// BeforeHook
+// is called before an item is written. Returning an error aborts the operation.
+// Return a *ValidationError to report invalid input to the user.
+type BeforeHook func(ctx context.Context, event *Event) error
+
+// AfterHook
+// is called after an item has been written
+type AfterHook func(ctx context.Context, event *Event)
+
+// ValidationError
+// rejects an operation because of invalid input
+type ValidationError struct {
+	Field   string // Optional
+	Message string
+}
+
+func NewValidationError(field string, message string) *ValidationError {
+	return &ValidationError{Field: field, Message: message}
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Registry
+// stores hooks per operation. Hooks are called in the order of registration.
+type Registry struct {
+	mutex  sync.RWMutex
+	before map[Operation][]BeforeHook
+	after  map[Operation][]AfterHook
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		before: make(map[Operation][]BeforeHook),
+		after:  make(map[Operation][]AfterHook),
+	}
+}
+
+// Before
+// registers a hook that is called before the operation
+func (r *Registry) Before(operation Operation, hook BeforeHook) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.before[operation] = append(r.before[operation], hook)
+}
+
+// After
+// registers a hook that is called after the operation
+func (r *Registry) After(operation Operation, hook AfterHook) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.after[operation] = append(r.after[operation], hook)
+}
+
+// RunBefore
+// calls the before hooks of the operation and stops at the first error
+func (r *Registry) RunBefore(ctx context.Context, event *Event) error {
+	r.mutex.RLock()
+	hooks := r.before[event.Operation]
+	r.mutex.RUnlock()
+	for _, hook := range hooks {
+		err := hook(ctx, event)
+		if err != nil {
+			return fmt.Errorf("before %s hook failed: %w", event.Operation, err)
+		}
+	}
+	return nil
+}
+
+// RunAfter
+// calls the after hooks of the operation
+func (r *Registry) RunAfter(ctx context.Context, event *Event) {
+	r.mutex.RLock()
+	hooks := r.after[event.Operation]
+	r.mutex.RUnlock()
+	for _, hook := range hooks {
+		hook(ctx, event)
+	}
+}
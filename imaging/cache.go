@@ -0,0 +1,60 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Cache
+// stores derived images on disk, grouped by the UUID of their source asset
+type Cache struct {
+	path string
+}
+
+func NewCache(path string) (*Cache, error) {
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not make directory for image cache: %v", err)
+	}
+	return &Cache{path: path}, nil
+}
+
+// Filename
+// returns the file name under which the variant of the asset is cached
+func (c *Cache) Filename(assetUUID string, options Options) (string, error) {
+	if !filepath.IsLocal(assetUUID) {
+		return "", fmt.Errorf("invalid asset uuid %s", assetUUID)
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%s|%d|%f|%f", options.Width, options.Height, options.Fit, options.Format, options.Quality, options.FocalX, options.FocalY)))
+	return filepath.Join(c.path, assetUUID, hex.EncodeToString(hash[:16])+"."+options.Format), nil
+}
+
+// Create
+// returns a temporary file for a variant. The file is moved to its final location with Commit.
+func (c *Cache) Create(filename string) (*os.File, error) {
+	err := os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(filepath.Dir(filename), ".variant-*")
+}
+
+func (c *Cache) Commit(file *os.File, filename string) error {
+	err := file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// Purge
+// removes all cached variants of an asset
+func (c *Cache) Purge(assetUUID string) error {
+	if !filepath.IsLocal(assetUUID) {
+		return fmt.Errorf("invalid asset uuid %s", assetUUID)
+	}
+	return os.RemoveAll(filepath.Join(c.path, assetUUID))
+}
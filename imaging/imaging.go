@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Decodes WebP sources, encodeWebP writes WebP images
+)
+
+const (
+	FitCover   = "cover"   // Fill the target size and crop around the focal point
+	FitContain = "contain" // Fit into the target size while keeping the aspect ratio
+	FitFill    = "fill"    // Stretch to the target size
+
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+
+	defaultQuality = 80
+	// Protects against decompression bombs
+	maxSourcePixels = 50_000_000
+)
+
+// Options
+// describe a derived image. A zero Width or Height is calculated from the aspect ratio of the source.
+type Options struct {
+	Width   int     `json:"width" schema:"w"`
+	Height  int     `json:"height" schema:"h"`
+	Fit     string  `json:"fit" schema:"fit"`
+	Format  string  `json:"format" schema:"format"`
+	Quality int     `json:"quality" schema:"q"`
+	FocalX  float64 `json:"-" schema:"-"` // Relative horizontal position between 0 and 1 that is kept visible when cropping
+	FocalY  float64 `json:"-" schema:"-"` // Relative vertical position between 0 and 1 that is kept visible when cropping
+}
+
+// Normalize
+// fills in defaults, so that equal images have equal options
+func (o Options) Normalize() Options {
+	if o.Fit == "" {
+		o.Fit = FitCover
+	}
+	if o.Format == "" {
+		o.Format = FormatJPEG
+	}
+	if o.Quality == 0 && (o.Format == FormatJPEG || o.Format == FormatWebP) {
+		o.Quality = defaultQuality
+	}
+	if o.Format == FormatPNG {
+		// PNG is lossless
+		o.Quality = 0
+	}
+	return o
+}
+
+func (o Options) Validate() error {
+	if o.Width < 0 || o.Height < 0 || (o.Width == 0 && o.Height == 0) {
+		return fmt.Errorf("invalid size %dx%d", o.Width, o.Height)
+	}
+	switch o.Fit {
+	case FitCover, FitContain, FitFill:
+	default:
+		return fmt.Errorf("invalid fit %q", o.Fit)
+	}
+	switch o.Format {
+	case FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		return fmt.Errorf("invalid format %q", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("invalid quality %d", o.Quality)
+	}
+	return nil
+}
+
+func (o Options) MimeType() string {
+	return "image/" + o.Format
+}
+
+// Process
+// reads an image, transforms it according to the options and writes it in the requested format
+func Process(r io.ReadSeeker, w io.Writer, options Options) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("could not decode image config: %v", err)
+	}
+	if config.Width*config.Height > maxSourcePixels {
+		return fmt.Errorf("image is too large (%dx%d)", config.Width, config.Height)
+	}
+	_, err = r.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("could not rewind image: %v", err)
+	}
+	source, _, err := image.Decode(r)
+	if err != nil {
+		return fmt.Errorf("could not decode image: %v", err)
+	}
+	result := Transform(source, options)
+	switch options.Format {
+	case FormatJPEG:
+		return jpeg.Encode(w, result, &jpeg.Options{Quality: options.Quality})
+	case FormatPNG:
+		return png.Encode(w, result)
+	case FormatWebP:
+		return encodeWebP(w, result, options.Quality)
+	default:
+		return fmt.Errorf("invalid format %q", options.Format)
+	}
+}
+
+// Transform
+// scales and crops the image
+func Transform(source image.Image, options Options) image.Image {
+	bounds := source.Bounds()
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	width, height := options.Width, options.Height
+	// Calculate missing dimension from aspect ratio
+	if width == 0 {
+		width = int(math.Round(float64(height) * float64(sourceWidth) / float64(sourceHeight)))
+	} else if height == 0 {
+		height = int(math.Round(float64(width) * float64(sourceHeight) / float64(sourceWidth)))
+	}
+	sourceRect := bounds
+	switch options.Fit {
+	case FitContain:
+		scale := math.Min(float64(width)/float64(sourceWidth), float64(height)/float64(sourceHeight))
+		width = int(math.Round(float64(sourceWidth) * scale))
+		height = int(math.Round(float64(sourceHeight) * scale))
+	case FitCover:
+		sourceRect = cropRect(bounds, width, height, options.FocalX, options.FocalY)
+	}
+	width, height = max(width, 1), max(height, 1)
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(result, result.Bounds(), source, sourceRect, draw.Src, nil)
+	return result
+}
+
+// cropRect
+// returns the largest rectangle with the target aspect ratio that is centered on the focal point as far as possible
+func cropRect(bounds image.Rectangle, width int, height int, focalX float64, focalY float64) image.Rectangle {
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	targetRatio := float64(width) / float64(height)
+	cropWidth, cropHeight := sourceWidth, sourceHeight
+	if float64(sourceWidth)/float64(sourceHeight) > targetRatio {
+		cropWidth = int(math.Round(float64(sourceHeight) * targetRatio))
+	} else {
+		cropHeight = int(math.Round(float64(sourceWidth) / targetRatio))
+	}
+	x := clamp(int(math.Round(focalX*float64(sourceWidth)))-cropWidth/2, 0, sourceWidth-cropWidth)
+	y := clamp(int(math.Round(focalY*float64(sourceHeight)))-cropHeight/2, 0, sourceHeight-cropHeight)
+	return image.Rect(x, y, x+cropWidth, y+cropHeight).Add(bounds.Min)
+}
+
+func clamp(value int, minimum int, maximum int) int {
+	return max(minimum, min(value, maximum))
+}
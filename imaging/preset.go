@@ -0,0 +1,63 @@
+package imaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+var (
+	DefaultPresets = Presets{
+		"thumbnail": {Width: 200, Height: 200, Fit: FitCover, Format: FormatWebP},
+		"small":     {Width: 640, Fit: FitContain, Format: FormatWebP},
+		"medium":    {Width: 1280, Fit: FitContain, Format: FormatWebP},
+		"large":     {Width: 1920, Fit: FitContain, Format: FormatWebP},
+	}
+)
+
+// Presets
+// is the allow-list of image variants that can be requested.
+// Restricting the variants prevents clients from filling the cache or exhausting the CPU with arbitrary sizes.
+type Presets map[string]Options
+
+// LoadPresets
+// reads presets from a JSON file that maps preset names to options. Returns the default presets if filename is empty.
+func LoadPresets(filename string) (Presets, error) {
+	if filename == "" {
+		return DefaultPresets.normalize()
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read presets: %v", err)
+	}
+	var presets Presets
+	err = json.Unmarshal(data, &presets)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal json data: %v", err)
+	}
+	return presets.normalize()
+}
+
+func (p Presets) normalize() (Presets, error) {
+	normalized := make(Presets, len(p))
+	for name, options := range p {
+		options = options.Normalize()
+		err := options.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid preset %s: %v", name, err)
+		}
+		normalized[name] = options
+	}
+	return normalized, nil
+}
+
+// Match
+// returns true if the options equal one of the presets
+func (p Presets) Match(options Options) bool {
+	for _, preset := range p {
+		if preset == options {
+			return true
+		}
+	}
+	return false
+}
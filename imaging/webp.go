@@ -0,0 +1,915 @@
+package imaging
+
+// This file implements a lossy WebP encoder. The Go encoders that are maintained are either lossless or need cgo.
+// Only VP8 key frames with 16x16 luma and 8x8 chroma prediction are written, which is all that still images need.
+// The bitstream is specified in RFC 6386 and decoded by golang.org/x/image/vp8, whose conventions are followed here.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	vp8Planes     = 4
+	vp8Bands      = 8
+	vp8Contexts   = 3
+	vp8TokenProbs = 11
+
+	// Token planes
+	vp8PlaneY1WithY2 = 0
+	vp8PlaneY2       = 1
+	vp8PlaneUV       = 2
+
+	// Prediction modes of 16x16 luma and 8x8 chroma blocks
+	vp8PredDC = 0
+	vp8PredTM = 1
+	vp8PredVE = 2
+	vp8PredHE = 3
+
+	// VP8 stores dimensions with 14 bits
+	vp8MaxSize = 16383
+	// Largest quantized coefficient that can be written, see category 6 in section 13.2
+	vp8MaxLevel = 2048
+	// Sizes of the first partition and the token partition are limited by the frame header and the decoder
+	vp8MaxFirstPartition = 1<<19 - 1
+	vp8MaxTokenPartition = 1<<24 - 1
+)
+
+var (
+	// The mapping from the position of a coefficient to its band is specified in section 13.3
+	vp8BandOf = [17]uint8{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	// Natural position of the coefficients in the order they are written
+	vp8Zigzag = [16]uint8{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	// Probabilities of the extra bits of the categories 3 to 6, see section 13.2
+	vp8CategoryProbs = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+	// The dequantization tables are specified in section 14.1
+	vp8DequantDC = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 10, 11, 12, 13, 14, 15, 16, 17, 17,
+		18, 19, 20, 20, 21, 21, 22, 22, 23, 23, 24, 25, 25, 26, 27, 28,
+		29, 30, 31, 32, 33, 34, 35, 36, 37, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58,
+		59, 60, 61, 62, 63, 64, 65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
+		75, 76, 76, 77, 78, 79, 80, 81, 82, 83, 84, 85, 86, 87, 88, 89,
+		91, 93, 95, 96, 98, 100, 101, 102, 104, 106, 108, 110, 112, 114, 116, 118,
+		122, 124, 126, 128, 130, 132, 134, 136, 138, 140, 143, 145, 148, 151, 154, 157,
+	}
+	vp8DequantAC = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+		20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35,
+		36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
+		52, 53, 54, 55, 56, 57, 58, 60, 62, 64, 66, 68, 70, 72, 74, 76,
+		78, 80, 82, 84, 86, 88, 90, 92, 94, 96, 98, 100, 102, 104, 106, 108,
+		110, 112, 114, 116, 119, 122, 125, 128, 131, 134, 137, 140, 143, 146, 149, 152,
+		155, 158, 161, 164, 167, 170, 173, 177, 181, 185, 189, 193, 197, 201, 205, 209,
+		213, 217, 221, 225, 229, 234, 239, 245, 249, 254, 259, 264, 269, 274, 279, 284,
+	}
+)
+
+// encodeWebP
+// writes the image as lossy WebP with a quality between 1 and 100. Transparency is kept in an uncompressed alpha channel.
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	bounds := img.Bounds()
+	if bounds.Dx() > vp8MaxSize || bounds.Dy() > vp8MaxSize {
+		return fmt.Errorf("image is too large for WebP (%dx%d)", bounds.Dx(), bounds.Dy())
+	}
+	source := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(source, source.Bounds(), img, bounds.Min, draw.Src)
+	encoder := newVP8Encoder(source, quality)
+	encoder.encodeMacroblocks()
+	frame, err := encoder.frame()
+	if err != nil {
+		return err
+	}
+	alpha := alphaChannel(source)
+	var chunks []byte
+	if alpha != nil {
+		// Extended format: the canvas size, then the alpha channel without filtering or compression
+		extended := make([]byte, 10)
+		extended[0] = 0x10 // Alpha flag
+		putUint24(extended[4:], uint32(source.Rect.Dx()-1))
+		putUint24(extended[7:], uint32(source.Rect.Dy()-1))
+		chunks = appendChunk(chunks, "VP8X", extended)
+		chunks = appendChunk(chunks, "ALPH", append([]byte{0}, alpha...))
+	}
+	chunks = appendChunk(chunks, "VP8 ", frame)
+	header := make([]byte, 12)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+len(chunks)))
+	copy(header[8:], "WEBP")
+	_, err = w.Write(append(header, chunks...))
+	return err
+}
+
+// alphaChannel
+// returns the alpha values of the image, or nil if it is opaque
+func alphaChannel(img *image.NRGBA) []byte {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	alpha := make([]byte, width*height)
+	opaque := true
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a := img.Pix[y*img.Stride+x*4+3]
+			alpha[y*width+x] = a
+			opaque = opaque && a == 0xff
+		}
+	}
+	if opaque {
+		return nil
+	}
+	return alpha
+}
+
+func appendChunk(data []byte, fourCC string, chunk []byte) []byte {
+	data = append(data, fourCC...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(chunk)))
+	data = append(data, chunk...)
+	if len(chunk)%2 == 1 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// vp8Quantizer
+// holds the factors of DC and AC coefficients of a plane
+type vp8Quantizer [2]int32
+
+// quantize
+// returns the level of a coefficient. AC coefficients are rounded towards zero a bit more, which saves more bits than it costs quality.
+func (q vp8Quantizer) quantize(coefficient int32, ac bool) int16 {
+	factor := q[0]
+	bias := factor / 2
+	if ac {
+		factor = q[1]
+		bias = factor * 3 / 8
+	}
+	level := (abs32(coefficient) + bias) / factor
+	level = min(level, vp8MaxLevel)
+	if coefficient < 0 {
+		level = -level
+	}
+	return int16(level)
+}
+
+// vp8Macroblock
+// is the encoded data of 16x16 pixels
+type vp8Macroblock struct {
+	yMode  uint8
+	uvMode uint8
+	skip   bool // No non-zero levels
+	// Levels in the order they are written: 16 luma blocks, 4 blocks of each chroma plane and the block of the luma DCs
+	levels [25][16]int16
+}
+
+type vp8Encoder struct {
+	width, height int
+	mbw, mbh      int
+	qIndex        int
+	y1, y2, uv    vp8Quantizer
+	// Source and reconstructed planes, padded to whole macroblocks. Prediction uses the reconstruction like the decoder does.
+	yStride, uvStride int
+	srcY, srcU, srcV  []uint8
+	recY, recU, recV  []uint8
+	macroblocks       []vp8Macroblock
+	tokenProbs        [vp8Planes][vp8Bands][vp8Contexts][vp8TokenProbs]uint8
+}
+
+func newVP8Encoder(img *image.NRGBA, quality int) *vp8Encoder {
+	e := &vp8Encoder{
+		width:  img.Rect.Dx(),
+		height: img.Rect.Dy(),
+	}
+	e.mbw, e.mbh = (e.width+15)/16, (e.height+15)/16
+	// Quality 100 uses the finest and quality 1 the coarsest quantizer
+	quality = max(1, min(quality, 100))
+	e.qIndex = int(math.Round(127 * math.Pow(float64(100-quality)/99, 1.2)))
+	e.y1 = vp8Quantizer{vp8DequantDC[e.qIndex], vp8DequantAC[e.qIndex]}
+	e.y2 = vp8Quantizer{vp8DequantDC[e.qIndex] * 2, max(vp8DequantAC[e.qIndex]*155/100, 8)}
+	e.uv = vp8Quantizer{vp8DequantDC[min(e.qIndex, 117)], vp8DequantAC[e.qIndex]}
+	e.yStride, e.uvStride = e.mbw*16, e.mbw*8
+	e.srcY = make([]uint8, e.yStride*e.mbh*16)
+	e.srcU = make([]uint8, e.uvStride*e.mbh*8)
+	e.srcV = make([]uint8, e.uvStride*e.mbh*8)
+	e.recY = make([]uint8, len(e.srcY))
+	e.recU = make([]uint8, len(e.srcU))
+	e.recV = make([]uint8, len(e.srcV))
+	e.macroblocks = make([]vp8Macroblock, e.mbw*e.mbh)
+	e.tokenProbs = vp8DefaultTokenProb
+	e.convert(img)
+	return e
+}
+
+// convert
+// fills the source planes with the colors of the image in the limited range of BT.601, as WebP decoders expect.
+// The image is padded with its edge pixels and colors are averaged over 2x2 pixels for the chroma planes.
+func (e *vp8Encoder) convert(img *image.NRGBA) {
+	pixel := func(x, y int) (int32, int32, int32) {
+		x, y = min(x, e.width-1), min(y, e.height-1)
+		offset := y*img.Stride + x*4
+		return int32(img.Pix[offset]), int32(img.Pix[offset+1]), int32(img.Pix[offset+2])
+	}
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < e.mbw*16; x++ {
+			r, g, b := pixel(x, y)
+			e.srcY[y*e.yStride+x] = uint8((16839*r + 33059*g + 6420*b + 1<<15 + 16<<16) >> 16)
+		}
+	}
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < e.mbw*8; x++ {
+			var r, g, b int32
+			for _, offset := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := pixel(2*x+offset[0], 2*y+offset[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.srcU[y*e.uvStride+x] = clip8((-9719*r - 19081*g + 28800*b + 1<<17 + 128<<18) >> 18)
+			e.srcV[y*e.uvStride+x] = clip8((28800*r - 24116*g - 4684*b + 1<<17 + 128<<18) >> 18)
+		}
+	}
+}
+
+// encodeMacroblocks
+// chooses the prediction modes, quantizes the residuals and reconstructs all macroblocks
+func (e *vp8Encoder) encodeMacroblocks() {
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.macroblocks[mby*e.mbw+mbx]
+			e.encodeLuma(mb, mbx, mby)
+			e.encodeChroma(mb, mbx, mby)
+			mb.skip = true
+			for _, block := range mb.levels {
+				mb.skip = mb.skip && block == [16]int16{}
+			}
+		}
+	}
+}
+
+// vp8Edges
+// are the reconstructed pixels above and left of a block, which it is predicted from
+type vp8Edges struct {
+	top, left       [16]int32
+	corner          int32
+	hasTop, hasLeft bool
+	size            int
+}
+
+// edges
+// returns the edges of the block at x, y in the plane. Missing edges have the values that the decoder assumes.
+func edges(plane []uint8, stride int, x int, y int, size int) vp8Edges {
+	edges := vp8Edges{hasTop: y > 0, hasLeft: x > 0, size: size, corner: 0x7f}
+	for i := 0; i < size; i++ {
+		edges.top[i], edges.left[i] = 0x7f, 0x81
+		if edges.hasTop {
+			edges.top[i] = int32(plane[(y-1)*stride+x+i])
+		}
+		if edges.hasLeft {
+			edges.left[i] = int32(plane[(y+i)*stride+x-1])
+		}
+	}
+	if edges.hasTop {
+		edges.corner = 0x81
+		if edges.hasLeft {
+			edges.corner = int32(plane[(y-1)*stride+x-1])
+		}
+	}
+	return edges
+}
+
+// predict
+// writes the prediction of the mode into the block, see section 12.2
+func (edges *vp8Edges) predict(mode uint8, block []int32) {
+	size := edges.size
+	switch mode {
+	case vp8PredDC:
+		var sum int32
+		count := 0
+		if edges.hasTop {
+			for i := 0; i < size; i++ {
+				sum += edges.top[i]
+			}
+			count += size
+		}
+		if edges.hasLeft {
+			for i := 0; i < size; i++ {
+				sum += edges.left[i]
+			}
+			count += size
+		}
+		dc := int32(0x80)
+		if count > 0 {
+			dc = (sum + int32(count/2)) / int32(count)
+		}
+		for i := range block[:size*size] {
+			block[i] = dc
+		}
+	case vp8PredTM:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				block[y*size+x] = int32(clip8(edges.left[y] + edges.top[x] - edges.corner))
+			}
+		}
+	case vp8PredVE:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				block[y*size+x] = edges.top[x]
+			}
+		}
+	case vp8PredHE:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				block[y*size+x] = edges.left[y]
+			}
+		}
+	}
+}
+
+// bestMode
+// returns the prediction mode with the smallest squared error for the blocks of the planes
+func bestMode(planes [][]uint8, stride int, x int, y int, size int, predictions [][4][256]int32) uint8 {
+	best, bestError := uint8(vp8PredDC), int64(math.MaxInt64)
+	for mode := uint8(0); mode < 4; mode++ {
+		var sum int64
+		for index, plane := range planes {
+			prediction := predictions[index][mode][:]
+			for j := 0; j < size; j++ {
+				for i := 0; i < size; i++ {
+					diff := int64(plane[(y+j)*stride+x+i]) - int64(prediction[j*size+i])
+					sum += diff * diff
+				}
+			}
+		}
+		if sum < bestError {
+			best, bestError = mode, sum
+		}
+	}
+	return best
+}
+
+func (e *vp8Encoder) encodeLuma(mb *vp8Macroblock, mbx int, mby int) {
+	x0, y0 := mbx*16, mby*16
+	lumaEdges := edges(e.recY, e.yStride, x0, y0, 16)
+	var predictions [1][4][256]int32
+	for mode := uint8(0); mode < 4; mode++ {
+		lumaEdges.predict(mode, predictions[0][mode][:])
+	}
+	mb.yMode = bestMode([][]uint8{e.srcY}, e.yStride, x0, y0, 16, predictions[:])
+	prediction := &predictions[0][mb.yMode]
+	// Transform the residuals of the 4x4 blocks, their DCs are transformed again as one block
+	var coefficients [16][16]int32
+	var dcs [16]int32
+	for n := 0; n < 16; n++ {
+		bx, by := n%4*4, n/4*4
+		var residual [16]int32
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				residual[j*4+i] = int32(e.srcY[(y0+by+j)*e.yStride+x0+bx+i]) - prediction[(by+j)*16+bx+i]
+			}
+		}
+		coefficients[n] = forwardDCT(residual)
+		dcs[n] = coefficients[n][0]
+	}
+	whtCoefficients := forwardWHT(dcs)
+	var dequantized [16]int32
+	for i, z := range vp8Zigzag {
+		level := e.y2.quantize(whtCoefficients[z], z > 0)
+		mb.levels[24][i] = level
+		dequantized[z] = int32(level) * e.y2[btoi(z > 0)]
+	}
+	reconstructedDCs := inverseWHT(dequantized)
+	for n := 0; n < 16; n++ {
+		var block [16]int32
+		block[0] = reconstructedDCs[n]
+		for i, z := range vp8Zigzag[1:] {
+			level := e.y1.quantize(coefficients[n][z], true)
+			mb.levels[n][i+1] = level
+			block[z] = int32(level) * e.y1[1]
+		}
+		bx, by := n%4*4, n/4*4
+		inverseDCT(block, prediction[(by*16+bx):], 16, e.recY[(y0+by)*e.yStride+x0+bx:], e.yStride)
+	}
+}
+
+func (e *vp8Encoder) encodeChroma(mb *vp8Macroblock, mbx int, mby int) {
+	x0, y0 := mbx*8, mby*8
+	planes := [][]uint8{e.srcU, e.srcV}
+	reconstructed := [][]uint8{e.recU, e.recV}
+	var predictions [2][4][256]int32
+	for index, plane := range reconstructed {
+		chromaEdges := edges(plane, e.uvStride, x0, y0, 8)
+		for mode := uint8(0); mode < 4; mode++ {
+			chromaEdges.predict(mode, predictions[index][mode][:])
+		}
+	}
+	mb.uvMode = bestMode(planes, e.uvStride, x0, y0, 8, predictions[:])
+	for index, plane := range planes {
+		prediction := &predictions[index][mb.uvMode]
+		for n := 0; n < 4; n++ {
+			bx, by := n%2*4, n/2*4
+			var residual [16]int32
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					residual[j*4+i] = int32(plane[(y0+by+j)*e.uvStride+x0+bx+i]) - prediction[(by+j)*8+bx+i]
+				}
+			}
+			coefficients := forwardDCT(residual)
+			var block [16]int32
+			for i, z := range vp8Zigzag {
+				level := e.uv.quantize(coefficients[z], z > 0)
+				mb.levels[16+index*4+n][i] = level
+				block[z] = int32(level) * e.uv[btoi(z > 0)]
+			}
+			inverseDCT(block, prediction[(by*8+bx):], 8, reconstructed[index][(y0+by)*e.uvStride+x0+bx:], e.uvStride)
+		}
+	}
+}
+
+// forwardDCT
+// transforms the residuals of a 4x4 block like the reference encoder, so that inverseDCT restores them
+func forwardDCT(input [16]int32) [16]int32 {
+	var temp, output [16]int32
+	for i := 0; i < 4; i++ {
+		row := input[i*4 : i*4+4]
+		a := (row[0] + row[3]) * 8
+		b := (row[1] + row[2]) * 8
+		c := (row[1] - row[2]) * 8
+		d := (row[0] - row[3]) * 8
+		temp[i*4+0] = a + b
+		temp[i*4+2] = a - b
+		temp[i*4+1] = (c*2217 + d*5352 + 14500) >> 12
+		temp[i*4+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := temp[i] + temp[12+i]
+		b := temp[4+i] + temp[8+i]
+		c := temp[4+i] - temp[8+i]
+		d := temp[i] - temp[12+i]
+		output[i] = (a + b + 7) >> 4
+		output[8+i] = (a - b + 7) >> 4
+		output[4+i] = (c*2217 + d*5352 + 12000) >> 16
+		if d != 0 {
+			output[4+i]++
+		}
+		output[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+	return output
+}
+
+// inverseDCT
+// adds the inverse transform of the coefficients to the prediction and stores the result, exactly like the decoder, see section 14.3
+func inverseDCT(coefficients [16]int32, prediction []int32, predictionStride int, output []uint8, outputStride int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coefficients[i] + coefficients[8+i]
+		b := coefficients[i] - coefficients[8+i]
+		c := (coefficients[4+i]*c2)>>16 - (coefficients[12+i]*c1)>>16
+		d := (coefficients[4+i]*c1)>>16 + (coefficients[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		for i, value := range [4]int32{a + d, b + c, b - c, a - d} {
+			output[j*outputStride+i] = clip8(prediction[j*predictionStride+i] + value>>3)
+		}
+	}
+}
+
+// forwardWHT
+// transforms the DCs of the 16 luma blocks. The Walsh-Hadamard matrix h is symmetric and h*h is 4 times the identity,
+// so h*dcs*h/2 is restored by inverseWHT, which computes h*coefficients*h/8.
+func forwardWHT(dcs [16]int32) [16]int32 {
+	var temp, output [16]int32
+	for i := 0; i < 4; i++ {
+		column := [4]int32{dcs[i], dcs[4+i], dcs[8+i], dcs[12+i]}
+		for r, value := range hadamard(column) {
+			temp[r*4+i] = value
+		}
+	}
+	for r := 0; r < 4; r++ {
+		for c, value := range hadamard([4]int32(temp[r*4 : r*4+4])) {
+			if value >= 0 {
+				output[r*4+c] = (value + 1) >> 1
+			} else {
+				output[r*4+c] = -((1 - value) >> 1)
+			}
+		}
+	}
+	return output
+}
+
+func hadamard(x [4]int32) [4]int32 {
+	a, b := x[0]+x[3], x[1]+x[2]
+	c, d := x[0]-x[3], x[1]-x[2]
+	return [4]int32{a + b, c + d, a - b, c - d}
+}
+
+// inverseWHT
+// returns the DCs of the 16 luma blocks exactly like the decoder, see section 14.3
+func inverseWHT(coefficients [16]int32) [16]int32 {
+	var m, output [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := coefficients[i] + coefficients[12+i]
+		a1 := coefficients[4+i] + coefficients[8+i]
+		a2 := coefficients[4+i] - coefficients[8+i]
+		a3 := coefficients[i] - coefficients[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		output[i*4+0] = (a0 + a1) >> 3
+		output[i*4+1] = (a3 + a2) >> 3
+		output[i*4+2] = (a0 - a1) >> 3
+		output[i*4+3] = (a3 - a2) >> 3
+	}
+	return output
+}
+
+// vp8TokenStats
+// counts the zeros and ones that are coded with each token probability
+type vp8TokenStats [vp8Planes][vp8Bands][vp8Contexts][vp8TokenProbs][2]uint32
+
+// vp8TokenWriter
+// writes the levels of blocks to the token partition, or only counts the bits if stats is set
+type vp8TokenWriter struct {
+	encoder *boolEncoder
+	stats   *vp8TokenStats
+	probs   *[vp8Planes][vp8Bands][vp8Contexts][vp8TokenProbs]uint8
+}
+
+func (t *vp8TokenWriter) writeToken(plane int, band uint8, context int, index int, bit bool) {
+	if t.stats != nil {
+		t.stats[plane][band][context][index][btoi(bit)]++
+		return
+	}
+	t.encoder.writeBit(t.probs[plane][band][context][index], bit)
+}
+
+// writeFixed
+// writes a bit whose probability is not adapted, such as signs and extra bits
+func (t *vp8TokenWriter) writeFixed(prob uint8, bit bool) {
+	if t.stats == nil {
+		t.encoder.writeBit(prob, bit)
+	}
+}
+
+// writeBlock
+// writes the levels of a block from the position first on, see section 13.
+// The context is the number of neighbouring blocks with non-zero levels. Returns whether the block has any.
+func (t *vp8TokenWriter) writeBlock(levels *[16]int16, plane int, context int, first int) bool {
+	last := -1
+	for i := 15; i >= first; i-- {
+		if levels[i] != 0 {
+			last = i
+			break
+		}
+	}
+	band := vp8BandOf[first]
+	if last < 0 {
+		t.writeToken(plane, band, context, 0, false)
+		return false
+	}
+	t.writeToken(plane, band, context, 0, true)
+	for i := first; i <= last; i++ {
+		level := abs32(int32(levels[i]))
+		if level == 0 {
+			t.writeToken(plane, band, context, 1, false)
+			band, context = vp8BandOf[i+1], 0
+			continue
+		}
+		t.writeToken(plane, band, context, 1, true)
+		if level == 1 {
+			t.writeToken(plane, band, context, 2, false)
+			band, context = vp8BandOf[i+1], 1
+		} else {
+			t.writeToken(plane, band, context, 2, true)
+			t.writeLevel(plane, band, context, level)
+			band, context = vp8BandOf[i+1], 2
+		}
+		t.writeFixed(128, levels[i] < 0)
+		if i < 15 {
+			// End of block if this was the last non-zero level
+			t.writeToken(plane, band, context, 0, i < last)
+		}
+	}
+	return true
+}
+
+// writeLevel
+// writes an absolute level of at least 2 with the token tree of section 13.2
+func (t *vp8TokenWriter) writeLevel(plane int, band uint8, context int, level int32) {
+	switch {
+	case level <= 4:
+		t.writeToken(plane, band, context, 3, false)
+		if level == 2 {
+			t.writeToken(plane, band, context, 4, false)
+			return
+		}
+		t.writeToken(plane, band, context, 4, true)
+		t.writeToken(plane, band, context, 5, level == 4)
+	case level <= 10:
+		t.writeToken(plane, band, context, 3, true)
+		t.writeToken(plane, band, context, 6, false)
+		if level <= 6 {
+			// Category 1
+			t.writeToken(plane, band, context, 7, false)
+			t.writeFixed(159, level == 6)
+			return
+		}
+		// Category 2
+		t.writeToken(plane, band, context, 7, true)
+		t.writeFixed(165, (level-7)&2 != 0)
+		t.writeFixed(145, (level-7)&1 != 0)
+	default:
+		t.writeToken(plane, band, context, 3, true)
+		t.writeToken(plane, band, context, 6, true)
+		// Categories 3 to 6 start at 11, 19, 35 and 67
+		category := 3
+		for category > 0 && level < 3+int32(8<<category) {
+			category--
+		}
+		t.writeToken(plane, band, context, 8, category >= 2)
+		t.writeToken(plane, band, context, 9+category/2, category%2 == 1)
+		extra := level - (3 + int32(8<<category))
+		probs := vp8CategoryProbs[category]
+		for i, prob := range probs {
+			t.writeFixed(prob, extra&(1<<(len(probs)-1-i)) != 0)
+		}
+	}
+}
+
+// writeTokens
+// writes the levels of all macroblocks in the order and with the contexts of section 13.3
+func (e *vp8Encoder) writeTokens(t *vp8TokenWriter) {
+	// Whether the blocks at the bottom of the macroblocks above and at the right of the macroblock to the left have non-zero levels.
+	// Luma has 4 of these blocks, each chroma plane 2 and the block of the luma DCs 1.
+	top := make([][9]bool, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		var left [9]bool
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.macroblocks[mby*e.mbw+mbx]
+			if mb.skip {
+				top[mbx], left = [9]bool{}, [9]bool{}
+				continue
+			}
+			nz := t.writeBlock(&mb.levels[24], vp8PlaneY2, btoi(left[8])+btoi(top[mbx][8]), 0)
+			left[8], top[mbx][8] = nz, nz
+			for n := 0; n < 16; n++ {
+				x, y := n%4, n/4
+				nz := t.writeBlock(&mb.levels[n], vp8PlaneY1WithY2, btoi(left[y])+btoi(top[mbx][x]), 1)
+				left[y], top[mbx][x] = nz, nz
+			}
+			for n := 0; n < 8; n++ {
+				// 4 for the first chroma plane, then 4 for the second
+				x, y := 4+n/4*2+n%2, 4+n/4*2+n%4/2
+				nz := t.writeBlock(&mb.levels[16+n], vp8PlaneUV, btoi(left[y])+btoi(top[mbx][x]), 0)
+				left[y], top[mbx][x] = nz, nz
+			}
+		}
+	}
+}
+
+// updateTokenProbs
+// adapts the token probabilities to the statistics of the image where this saves more bits than the update costs
+func (e *vp8Encoder) updateTokenProbs(stats *vp8TokenStats) {
+	for i := range e.tokenProbs {
+		for j := range e.tokenProbs[i] {
+			for k := range e.tokenProbs[i][j] {
+				for l, prob := range e.tokenProbs[i][j][k] {
+					counts := stats[i][j][k][l]
+					total := counts[0] + counts[1]
+					if total == 0 {
+						continue
+					}
+					updated := uint8(max(1, min(255, (255*counts[0]+total/2)/total)))
+					updateProb := vp8TokenUpdateProb[i][j][k][l]
+					savings := bitCost(prob, counts) - bitCost(updated, counts)
+					updateCost := 8 + bitCost(updateProb, [2]uint32{0, 1}) - bitCost(updateProb, [2]uint32{1, 0})
+					if savings > updateCost {
+						e.tokenProbs[i][j][k][l] = updated
+					}
+				}
+			}
+		}
+	}
+}
+
+// bitCost
+// returns the number of bits that coding the zeros and ones with the probability of a zero takes
+func bitCost(prob uint8, counts [2]uint32) float64 {
+	p := float64(prob) / 256
+	return -float64(counts[0])*math.Log2(p) - float64(counts[1])*math.Log2(1-p)
+}
+
+// frame
+// returns the VP8 key frame with the first partition, which holds the headers and modes, and one token partition
+func (e *vp8Encoder) frame() ([]byte, error) {
+	var stats vp8TokenStats
+	e.writeTokens(&vp8TokenWriter{stats: &stats})
+	e.updateTokenProbs(&stats)
+	tokens := &boolEncoder{}
+	e.writeTokens(&vp8TokenWriter{encoder: tokens, probs: &e.tokenProbs})
+	tokenPartition := tokens.finish()
+
+	skipped := 0
+	for _, mb := range e.macroblocks {
+		skipped += btoi(mb.skip)
+	}
+	first := &boolEncoder{}
+	first.writeBit(128, false) // Color space
+	first.writeBit(128, false) // Clamping is required
+	first.writeBit(128, false) // No segmentation
+	first.writeBit(128, false) // Normal loop filter
+	// Loop filter level, coarser quantizers need stronger filtering
+	first.writeUint(uint32(min(e.qIndex/2, 63)), 6)
+	first.writeUint(0, 3)      // Sharpness
+	first.writeBit(128, false) // No loop filter adjustments
+	first.writeUint(0, 2)      // One token partition
+	first.writeUint(uint32(e.qIndex), 7)
+	for range 5 {
+		first.writeBit(128, false) // No quantizer deltas
+	}
+	first.writeBit(128, false) // Refresh entropy probabilities
+	for i := range e.tokenProbs {
+		for j := range e.tokenProbs[i] {
+			for k := range e.tokenProbs[i][j] {
+				for l, prob := range e.tokenProbs[i][j][k] {
+					updated := prob != vp8DefaultTokenProb[i][j][k][l]
+					first.writeBit(vp8TokenUpdateProb[i][j][k][l], updated)
+					if updated {
+						first.writeUint(uint32(prob), 8)
+					}
+				}
+			}
+		}
+	}
+	// Macroblocks without non-zero levels are marked as skipped instead of writing their blocks
+	skipProb := uint8(max(1, min(254, 255*(len(e.macroblocks)-skipped)/len(e.macroblocks))))
+	first.writeBit(128, skipped > 0)
+	if skipped > 0 {
+		first.writeUint(uint32(skipProb), 8)
+	}
+	for _, mb := range e.macroblocks {
+		if skipped > 0 {
+			first.writeBit(skipProb, mb.skip)
+		}
+		first.writeBit(145, true) // 16x16 luma prediction, see section 11.2
+		switch mb.yMode {
+		case vp8PredDC:
+			first.writeBit(156, false)
+			first.writeBit(163, false)
+		case vp8PredVE:
+			first.writeBit(156, false)
+			first.writeBit(163, true)
+		case vp8PredHE:
+			first.writeBit(156, true)
+			first.writeBit(128, false)
+		case vp8PredTM:
+			first.writeBit(156, true)
+			first.writeBit(128, true)
+		}
+		first.writeBit(142, mb.uvMode != vp8PredDC)
+		if mb.uvMode != vp8PredDC {
+			first.writeBit(114, mb.uvMode != vp8PredVE)
+			if mb.uvMode != vp8PredVE {
+				first.writeBit(183, mb.uvMode == vp8PredTM)
+			}
+		}
+	}
+	firstPartition := first.finish()
+	if len(firstPartition) > vp8MaxFirstPartition || len(tokenPartition) > vp8MaxTokenPartition {
+		return nil, fmt.Errorf("image is too large for WebP (%dx%d)", e.width, e.height)
+	}
+
+	// Frame tag of a shown key frame and the start code, see section 9.1
+	frame := make([]byte, 10, 10+len(firstPartition)+len(tokenPartition))
+	putUint24(frame, uint32(len(firstPartition))<<5|1<<4)
+	frame[3], frame[4], frame[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(frame[6:], uint16(e.width))
+	binary.LittleEndian.PutUint16(frame[8:], uint16(e.height))
+	frame = append(frame, firstPartition...)
+	return append(frame, tokenPartition...), nil
+}
+
+// boolEncoder
+// is the arithmetic encoder of section 7.3
+type boolEncoder struct {
+	output   []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+	started  bool
+}
+
+// writeBit
+// writes a bit that is false with the probability prob/256
+func (b *boolEncoder) writeBit(prob uint8, bit bool) {
+	if !b.started {
+		b.rng, b.bitCount, b.started = 255, 24, true
+	}
+	split := 1 + ((b.rng-1)*uint32(prob))>>8
+	if bit {
+		b.bottom += split
+		b.rng -= split
+	} else {
+		b.rng = split
+	}
+	for b.rng < 128 {
+		b.rng <<= 1
+		if b.bottom&(1<<31) != 0 {
+			b.carry()
+		}
+		b.bottom <<= 1
+		b.bitCount--
+		if b.bitCount == 0 {
+			b.output = append(b.output, byte(b.bottom>>24))
+			b.bottom &= 1<<24 - 1
+			b.bitCount = 8
+		}
+	}
+}
+
+// writeUint
+// writes the bits of an unsigned integer with equal probability, the most significant first
+func (b *boolEncoder) writeUint(value uint32, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		b.writeBit(128, value&(1<<i) != 0)
+	}
+}
+
+// carry
+// adds one to the bytes that have been written
+func (b *boolEncoder) carry() {
+	i := len(b.output) - 1
+	for ; i >= 0 && b.output[i] == 0xff; i-- {
+		b.output[i] = 0
+	}
+	if i >= 0 {
+		b.output[i]++
+	}
+}
+
+// finish
+// writes the remaining bits and returns the partition
+func (b *boolEncoder) finish() []byte {
+	if !b.started {
+		b.rng, b.bitCount, b.started = 255, 24, true
+	}
+	c := b.bitCount
+	v := b.bottom
+	if v&(1<<(32-c)) != 0 {
+		b.carry()
+	}
+	v <<= c & 7
+	for c >>= 3; c > 0; c-- {
+		v <<= 8
+	}
+	for range 4 {
+		b.output = append(b.output, byte(v>>24))
+		v <<= 8
+	}
+	return b.output
+}
+
+func clip8(value int32) uint8 {
+	return uint8(max(0, min(value, 255)))
+}
+
+func abs32(value int32) int32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/vp8"
+	"golang.org/x/image/webp"
+)
+
+// newTestImage
+// returns an image with gradients, edges and noise, whose size is not a multiple of the macroblock size
+func newTestImage(width int, height int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	random := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{
+				R: uint8(x * 255 / width),
+				G: uint8(y * 255 / height),
+				B: uint8(random.Intn(64)),
+				A: 0xff,
+			}
+			if (x/20+y/20)%2 == 0 {
+				c.B += 128
+			}
+			if alpha {
+				c.A = uint8((x + y) % 256)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// decodeVP8
+// decodes the frame with the decoder of golang.org/x/image, which is the reference for the encoder
+func decodeVP8(t *testing.T, frame []byte) *image.YCbCr {
+	t.Helper()
+	decoder := vp8.NewDecoder()
+	decoder.Init(bytes.NewReader(frame), len(frame))
+	_, err := decoder.DecodeFrameHeader()
+	if err != nil {
+		t.Fatalf("could not decode frame header: %v", err)
+	}
+	img, err := decoder.DecodeFrame()
+	if err != nil {
+		t.Fatalf("could not decode frame: %v", err)
+	}
+	return img
+}
+
+func TestWebPReconstructionMatchesDecoder(t *testing.T) {
+	// Quality 100 disables the loop filter, so the decoder has to produce exactly the planes that the encoder predicted from
+	for _, img := range []*image.NRGBA{newTestImage(83, 45, false), newTestImage(16, 16, false), newTestImage(1, 1, false)} {
+		encoder := newVP8Encoder(img, 100)
+		encoder.encodeMacroblocks()
+		frame, err := encoder.frame()
+		if err != nil {
+			t.Fatalf("could not encode frame: %v", err)
+		}
+		decoded := decodeVP8(t, frame)
+		if decoded.Rect.Dx() != encoder.width || decoded.Rect.Dy() != encoder.height {
+			t.Fatalf("got size %v, want %dx%d", decoded.Rect, encoder.width, encoder.height)
+		}
+		for y := 0; y < encoder.height; y++ {
+			for x := 0; x < encoder.width; x++ {
+				if got, want := decoded.Y[decoded.YOffset(x, y)], encoder.recY[y*encoder.yStride+x]; got != want {
+					t.Fatalf("%v: luma at %d,%d is %d, want %d", img.Rect, x, y, got, want)
+				}
+				offset := y/2*encoder.uvStride + x/2
+				if decoded.Cb[decoded.COffset(x, y)] != encoder.recU[offset] || decoded.Cr[decoded.COffset(x, y)] != encoder.recV[offset] {
+					t.Fatalf("%v: chroma at %d,%d differs", img.Rect, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestWebPQuality(t *testing.T) {
+	img := newTestImage(200, 150, false)
+	source := newVP8Encoder(img, 100)
+	previousSize := math.MaxInt
+	previousPSNR := math.Inf(1)
+	for _, quality := range []int{100, 80, 50, 10} {
+		var buffer bytes.Buffer
+		err := encodeWebP(&buffer, img, quality)
+		if err != nil {
+			t.Fatalf("could not encode quality %d: %v", quality, err)
+		}
+		decoded, err := webp.Decode(bytes.NewReader(buffer.Bytes()))
+		if err != nil {
+			t.Fatalf("could not decode quality %d: %v", quality, err)
+		}
+		luma := decoded.(*image.YCbCr)
+		var sum float64
+		for y := 0; y < 150; y++ {
+			for x := 0; x < 200; x++ {
+				diff := float64(luma.Y[luma.YOffset(x, y)]) - float64(source.srcY[y*source.yStride+x])
+				sum += diff * diff
+			}
+		}
+		psnr := 10 * math.Log10(255*255/(sum/(200*150)))
+		if buffer.Len() >= previousSize || psnr > previousPSNR {
+			t.Errorf("quality %d: got %d bytes with %.1f dB, want less than %d bytes with at most %.1f dB", quality, buffer.Len(), psnr, previousSize, previousPSNR)
+		}
+		if quality >= 80 && psnr < 35 {
+			t.Errorf("quality %d: got %.1f dB, want at least 35 dB", quality, psnr)
+		}
+		previousSize, previousPSNR = buffer.Len(), psnr
+	}
+}
+
+func TestWebPAlpha(t *testing.T) {
+	img := newTestImage(37, 29, true)
+	var buffer bytes.Buffer
+	err := encodeWebP(&buffer, img, defaultQuality)
+	if err != nil {
+		t.Fatalf("could not encode: %v", err)
+	}
+	decoded, err := webp.Decode(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("could not decode: %v", err)
+	}
+	withAlpha, ok := decoded.(*image.NYCbCrA)
+	if !ok {
+		t.Fatalf("got %T, want *image.NYCbCrA", decoded)
+	}
+	for y := 0; y < 29; y++ {
+		for x := 0; x < 37; x++ {
+			if got, want := withAlpha.A[withAlpha.AOffset(x, y)], img.NRGBAAt(x, y).A; got != want {
+				t.Fatalf("alpha at %d,%d is %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestWebPTooLarge(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, vp8MaxSize+1, 1))
+	err := encodeWebP(&bytes.Buffer{}, img, defaultQuality)
+	if err == nil {
+		t.Fatal("got no error for an image that is too wide")
+	}
+}
@@ -0,0 +1,297 @@
+package jsonschema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/sql"
+)
+
+const (
+	uuidPattern = "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+)
+
+var (
+	nameSchema = &Schema{Type: Types{TypeString}, Pattern: sql.AllowedFieldAndTableNameRegex.String()}
+)
+
+// Blueprint
+// returns the schema of blueprint files, so that editors can offer autocompletion and validation
+func Blueprint() *Schema {
+	return &Schema{
+		Schema:   Draft,
+		Title:    "Rangi blueprint",
+		Type:     Types{TypeObject},
+		Required: []string{"collection_name", "fields"},
+		Properties: map[string]*Schema{
+			"$schema":                 {Type: Types{TypeString}},
+			"collection_name":         withDescription(nameSchema, "Name of the collection, must match the file name"),
+			"collection_display_name": label("Name of the collection in the admin interface"),
+			"singleton":               {Type: Types{TypeBoolean}, Description: "The collection has exactly one item, which is created when it is saved for the first time"},
+			"localized_title":         {Type: Types{TypeBoolean}, Description: "The title of items can be translated into all configured locales"},
+			"fields":                  {Type: Types{TypeArray}, Items: fieldDefinition([]blueprint.Type{blueprint.TypeID, blueprint.TypeUUID})},
+			"feed": {
+				Type:        Types{TypeObject},
+				Description: "Enables RSS and Atom feeds of the published items. The values are names of fields.",
+				Properties: map[string]*Schema{
+					"title":   withDescription(nameSchema, "Field of type string or slug, defaults to title"),
+					"summary": withDescription(nameSchema, "Field of type string or markdown"),
+					"date":    withDescription(nameSchema, "Field of type int with a Unix timestamp, defaults to published_at"),
+					"author":  withDescription(nameSchema, "Field of type string"),
+				},
+				AdditionalProperties: false,
+			},
+			"preview_url": {
+				Type:        Types{TypeString},
+				Description: "URL of the frontend that shows unpublished items, e. g. https://example.com/blog/{slug}?preview={token}. {token} is replaced by a preview token, other placeholders by values of fields.",
+			},
+		},
+		AdditionalProperties: false,
+	}
+}
+
+// Component
+// returns the schema of component files
+func Component() *Schema {
+	return &Schema{
+		Schema:   Draft,
+		Title:    "Rangi component",
+		Type:     Types{TypeObject},
+		Required: []string{"component_name", "fields"},
+		Properties: map[string]*Schema{
+			"$schema":        {Type: Types{TypeString}},
+			"component_name": withDescription(nameSchema, "Name of the component, must match the file name"),
+			"display_name":   label("Name of the component in the admin interface"),
+			"fields":         {Type: Types{TypeArray}, Items: fieldDefinition(blueprint.ComponentUnsupportedTypes)},
+		},
+		AdditionalProperties: false,
+	}
+}
+
+// fieldDefinition
+// returns the schema of a field in blueprint and component files. The excluded types can not be used.
+func fieldDefinition(excludedTypes []blueprint.Type) *Schema {
+	var types []any
+	for _, typ := range blueprint.AllTypes {
+		if !slices.Contains(excludedTypes, typ) {
+			types = append(types, string(typ))
+		}
+	}
+	return &Schema{
+		Type:     Types{TypeObject},
+		Required: []string{"name", "type"},
+		Properties: map[string]*Schema{
+			"name":         withDescription(nameSchema, "Name of the field, used as column name in the database"),
+			"display_name": label("Name of the field in the admin interface"),
+			"type":         {Type: Types{TypeString}, Enum: types},
+			"required":     {Type: Types{TypeBoolean}},
+			"hidden":       {Type: Types{TypeBoolean}, Description: "Hide the field in the admin interface"},
+			"localized":    {Type: Types{TypeBoolean}, Description: "Values can be translated into all configured locales"},
+			"reference": {
+				Type:        Types{TypeObject},
+				Description: "Only for fields of type reference",
+				Properties: map[string]*Schema{
+					"collection": withDescription(nameSchema, "Name of the referenced collection"),
+					"max_references": {
+						Type:        Types{TypeInteger},
+						Description: "-1 means infinite references allowed",
+						Minimum:     ptr(int64(-1)),
+						Not:         &Schema{Const: 0},
+					},
+				},
+				Required:             []string{"collection", "max_references"},
+				AdditionalProperties: false,
+			},
+			"slug": {
+				Type:        Types{TypeObject},
+				Description: "Only for fields of type slug",
+				Properties: map[string]*Schema{
+					"source":             withDescription(nameSchema, "Name of the field the slug is generated from"),
+					"lock_after_publish": {Type: Types{TypeBoolean}, Description: "The slug can not be changed anymore once the item has been published"},
+				},
+				Required:             []string{"source"},
+				AdditionalProperties: false,
+			},
+			"computed": {
+				Type:        Types{TypeObject},
+				Description: "The value is computed when the item is written, either by an expression or by a registered Go function",
+				Properties: map[string]*Schema{
+					"expression": {Type: Types{TypeString}, Description: "Go template with the sprig functions, wordCount and readingTime, e. g. {{ .first_name }} {{ .last_name }}"},
+					"function":   {Type: Types{TypeString}, Description: "Name of a function that has been registered at the server"},
+				},
+				OneOf: []*Schema{
+					{Required: []string{"expression"}, Not: &Schema{Required: []string{"function"}}},
+					{Required: []string{"function"}, Not: &Schema{Required: []string{"expression"}}},
+				},
+				AdditionalProperties: false,
+			},
+			"visible_if":  conditions("The field is only shown if all conditions match. Required fields are only required while they are shown."),
+			"required_if": conditions("The field is required if all conditions match"),
+			"component":   withDescription(nameSchema, "Name of a component in the components directory. Only for fields of type object (one group) and array (list of groups)"),
+			"components": {
+				Type:        Types{TypeArray},
+				Description: "Names of the components that entries of a dynamic zone can be. Only for fields of type array",
+				Items:       nameSchema,
+			},
+		},
+		AdditionalProperties: false,
+	}
+}
+
+// Collection
+// returns the schema of the items of a collection as they are exported. Labels are resolved for the locale,
+// the labels of the default fields are translated with translate, which may be nil.
+func Collection(collection *blueprint.Collection, locale string, translate blueprint.Translator) *Schema {
+	bp := collection.Blueprint
+	schema := &Schema{
+		Schema:               Draft,
+		Title:                bp.CollectionDisplayName.Resolve(locale),
+		Type:                 Types{TypeObject},
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	for _, field := range bp.Fields {
+		property := fieldSchema(field, locale)
+		property.Title = field.DisplayName.Localize(locale, translate)
+		// Values that are set by Rangi
+		property.ReadOnly = field.Name == blueprint.KeyID || field.Name == blueprint.KeyUpdatedAt || bp.IsDerivedField(field.Name) || field.IsComputed()
+		// Conditions are checked separately, see validateItem in the server package
+		if field.Required && field.VisibleIf == nil {
+			schema.Required = append(schema.Required, field.Name)
+		} else if field.Type != blueprint.TypeReference {
+			property.Type = append(property.Type, TypeNull)
+		}
+		schema.Properties[field.Name] = property
+	}
+	return schema
+}
+
+// zoneSchema
+// returns the schema of the value of a dynamic zone. Entries are validated against the schema of their component.
+func zoneSchema(components []blueprint.Component, locale string) *Schema {
+	names := make([]any, len(components))
+	item := &Schema{
+		Type:     Types{TypeObject},
+		Required: []string{blueprint.ComponentKey},
+	}
+	for index, component := range components {
+		names[index] = component.ComponentName
+		schema := componentSchema(component.Fields, locale)
+		schema.Title = component.DisplayName.Resolve(locale)
+		schema.Properties[blueprint.ComponentKey] = &Schema{Type: Types{TypeString}, Const: component.ComponentName}
+		item.AllOf = append(item.AllOf, &Schema{
+			If:   &Schema{Required: []string{blueprint.ComponentKey}, Properties: map[string]*Schema{blueprint.ComponentKey: {Const: component.ComponentName}}},
+			Then: schema,
+		})
+	}
+	item.Properties = map[string]*Schema{
+		blueprint.ComponentKey: {Type: Types{TypeString}, Description: "Name of the component of the entry", Enum: names},
+	}
+	return &Schema{Type: Types{TypeArray}, Items: item}
+}
+
+// componentSchema
+// returns the schema of the value of a component, which is an object with a property per field
+func componentSchema(fields []blueprint.BlueprintField, locale string) *Schema {
+	schema := &Schema{
+		Type:                 Types{TypeObject},
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	for _, field := range fields {
+		property := fieldSchema(field, locale)
+		property.Title = field.DisplayName.Resolve(locale)
+		if field.Required {
+			schema.Required = append(schema.Required, field.Name)
+		} else {
+			property.Type = append(property.Type, TypeNull)
+		}
+		schema.Properties[field.Name] = property
+	}
+	return schema
+}
+
+// fieldSchema
+// returns the schema of a single value
+func fieldSchema(field blueprint.BlueprintField, locale string) *Schema {
+	if len(field.Zone) > 0 {
+		return zoneSchema(field.Zone, locale)
+	}
+	if len(field.Fields) > 0 {
+		if field.Type == blueprint.TypeArray {
+			return &Schema{Type: Types{TypeArray}, Items: componentSchema(field.Fields, locale)}
+		}
+		return componentSchema(field.Fields, locale)
+	}
+	switch field.Type {
+	case blueprint.TypeID, blueprint.TypeInt:
+		return &Schema{Type: Types{TypeInteger}}
+	case blueprint.TypeUUID:
+		return &Schema{Type: Types{TypeString}, Format: FormatUUID, Pattern: uuidPattern}
+	case blueprint.TypeAsset:
+		// Empty if no asset has been selected
+		return &Schema{Type: Types{TypeString}, Description: "UUID of an asset", Pattern: fmt.Sprintf("^$|%s", uuidPattern)}
+	case blueprint.TypeBoolean:
+		return &Schema{Type: Types{TypeBoolean}}
+	case blueprint.TypeArray:
+		return &Schema{Type: Types{TypeArray}}
+	case blueprint.TypeObject:
+		return &Schema{Type: Types{TypeObject}}
+	case blueprint.TypeMarkdown:
+		return &Schema{Type: Types{TypeString}, Description: "Markdown"}
+	case blueprint.TypeReference:
+		schema := &Schema{
+			Type:        Types{TypeArray},
+			Description: fmt.Sprintf("UUIDs of items of the collection %s", field.Reference.Collection),
+			Items:       &Schema{Type: Types{TypeString}, Format: FormatUUID, Pattern: uuidPattern},
+		}
+		if field.Reference.MaxReferences >= 0 {
+			schema.MaxItems = ptr(field.Reference.MaxReferences)
+		}
+		return schema
+	default:
+		return &Schema{Type: Types{TypeString}}
+	}
+}
+
+// conditions
+// returns the schema of a list of blueprint.BlueprintCondition
+func conditions(description string) *Schema {
+	operators := make([]any, len(blueprint.AllOperators))
+	for index, operator := range blueprint.AllOperators {
+		operators[index] = string(operator)
+	}
+	return &Schema{
+		Type:        Types{TypeArray},
+		Description: description,
+		Items: &Schema{
+			Type:     Types{TypeObject},
+			Required: []string{"field", "operator"},
+			Properties: map[string]*Schema{
+				"field":    withDescription(nameSchema, "Name of the field whose value is compared"),
+				"operator": {Type: Types{TypeString}, Enum: operators},
+				"value":    {Description: "Compared value, an array for the operators in and not_in. Values are compared as text."},
+			},
+			AdditionalProperties: false,
+		},
+	}
+}
+
+// label
+// returns the schema of a blueprint.Label, which is either a string or an object that maps locales to strings
+func label(description string) *Schema {
+	return &Schema{
+		Description: description,
+		OneOf: []*Schema{
+			{Type: Types{TypeString}},
+			{Type: Types{TypeObject}, AdditionalProperties: &Schema{Type: Types{TypeString}}},
+		},
+	}
+}
+
+func withDescription(schema *Schema, description string) *Schema {
+	copied := *schema
+	copied.Description = description
+	return &copied
+}
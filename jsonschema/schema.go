@@ -0,0 +1,78 @@
+package jsonschema
+
+import (
+	"encoding/json"
+)
+
+const (
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeString  = "string"
+	TypeArray   = "array"
+	TypeObject  = "object"
+
+	FormatUUID = "uuid"
+)
+
+// Schema
+// is a JSON Schema. Only the keywords that are needed by Rangi are supported.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        Types  `json:"type,omitempty"`
+	ReadOnly    bool   `json:"readOnly,omitempty"`
+	// Validation
+	Enum     []any     `json:"enum,omitempty"`
+	Const    any       `json:"const,omitempty"`
+	Not      *Schema   `json:"not,omitempty"`
+	OneOf    []*Schema `json:"oneOf,omitempty"`
+	AllOf    []*Schema `json:"allOf,omitempty"`
+	If       *Schema   `json:"if,omitempty"`
+	Then     *Schema   `json:"then,omitempty"` // Only applied if the value matches If
+	Format   string    `json:"format,omitempty"`
+	Pattern  string    `json:"pattern,omitempty"`
+	Minimum  *int64    `json:"minimum,omitempty"`
+	MaxItems *int      `json:"maxItems,omitempty"`
+	Items    *Schema   `json:"items,omitempty"`
+	// Objects
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// Either a bool or a *Schema for the values of all properties that are not listed in Properties
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+}
+
+// Types
+// is marshaled as a single string if it only contains one type
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*t = Types{s}
+		return nil
+	}
+	var types []string
+	err := json.Unmarshal(data, &types)
+	if err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
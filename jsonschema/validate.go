@@ -0,0 +1,235 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	maxCachedPatterns = 256
+)
+
+var (
+	patterns      = make(map[string]*regexp.Regexp)
+	patternsMutex sync.Mutex
+)
+
+// Error
+// describes a value that does not match the schema. Path is a JSON Pointer to the value.
+type Error struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate
+// returns all errors of the value. Values can be decoded JSON or Go values, e. g. int64 or []string.
+func (s *Schema) Validate(value any) []Error {
+	return s.validate(normalize(value), "")
+}
+
+func (s *Schema) validate(value any, path string) []Error {
+	var errors []Error
+	report := func(format string, a ...any) {
+		errors = append(errors, Error{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+	typ := typeOf(value)
+	if len(s.Type) > 0 && !slices.Contains(s.Type, typ) && !(typ == TypeInteger && slices.Contains(s.Type, TypeNumber)) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), typ)
+		// Other keywords would only report follow-up errors
+		return errors
+	}
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(normalize(e), value) }) {
+		report("value must be one of %v", s.Enum)
+	}
+	if s.Const != nil && !equal(normalize(s.Const), value) {
+		report("value must be %v", s.Const)
+	}
+	if s.Not != nil && len(s.Not.validate(value, path)) == 0 {
+		report("value is not allowed")
+	}
+	if s.OneOf != nil {
+		matches := 0
+		for _, schema := range s.OneOf {
+			if len(schema.validate(value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			report("value must match exactly one schema, matches %d", matches)
+		}
+	}
+	for _, schema := range s.AllOf {
+		errors = append(errors, schema.validate(value, path)...)
+	}
+	if s.If != nil && s.Then != nil && len(s.If.validate(value, path)) == 0 {
+		errors = append(errors, s.Then.validate(value, path)...)
+	}
+	switch v := value.(type) {
+	case string:
+		if s.Pattern != "" {
+			re, err := compilePattern(s.Pattern)
+			if err != nil {
+				report("invalid pattern %q: %v", s.Pattern, err)
+			} else if !re.MatchString(v) {
+				report("value does not match %s", s.Pattern)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < float64(*s.Minimum) {
+			report("value must be at least %d", *s.Minimum)
+		}
+	case []any:
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("at most %d items are allowed", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for index, item := range v {
+				errors = append(errors, s.Items.validate(item, fmt.Sprintf("%s/%d", path, index))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("property %q is required", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertyPath := path + "/" + escapePointer(key)
+			if property, ok := s.Properties[key]; ok {
+				errors = append(errors, property.validate(v[key], propertyPath)...)
+				continue
+			}
+			switch additional := s.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					errors = append(errors, Error{Path: propertyPath, Message: "property is not allowed"})
+				}
+			case *Schema:
+				errors = append(errors, additional.validate(v[key], propertyPath)...)
+			}
+		}
+	}
+	return errors
+}
+
+// normalize
+// converts Go values into the types that encoding/json uses for decoded values
+func normalize(value any) any {
+	switch v := value.(type) {
+	case nil, bool, string, float64:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case []byte:
+		return string(v)
+	case json.RawMessage:
+		var decoded any
+		if json.Unmarshal(v, &decoded) != nil {
+			return string(v)
+		}
+		return decoded
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		values := make([]any, rv.Len())
+		for index := range values {
+			values[index] = normalize(rv.Index(index).Interface())
+		}
+		return values
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		values := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = normalize(iter.Value().Interface())
+		}
+		return values
+	}
+	return value
+}
+
+// typeOf
+// returns the JSON Schema type of a normalized value
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return TypeInteger
+		}
+		return TypeNumber
+	case string:
+		return TypeString
+	case []any:
+		return TypeArray
+	case map[string]any:
+		return TypeObject
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func equal(a any, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// escapePointer
+// escapes a key for use in a JSON Pointer
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// compilePattern
+// caches compiled patterns, because the same patterns are used for many values
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternsMutex.Lock()
+	defer patternsMutex.Unlock()
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) >= maxCachedPatterns {
+		clear(patterns)
+	}
+	patterns[pattern] = re
+	return re, nil
+}
@@ -0,0 +1,110 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+const testUUID = "2f1c8c1e-7b0a-4c55-9a55-1b2f0b6d1c3e"
+
+func TestValidateCollection(t *testing.T) {
+	collection := &blueprint.Collection{Blueprint: &blueprint.Blueprint{
+		CollectionName: "articles",
+		Fields: []blueprint.BlueprintField{
+			{Name: blueprint.KeyID, Type: blueprint.TypeID, Required: true},
+			{Name: blueprint.KeyUUID, Type: blueprint.TypeUUID, Required: true},
+			{Name: blueprint.KeyTitle, Type: blueprint.TypeString, Required: true},
+			{Name: "pages", Type: blueprint.TypeInt},
+			{Name: "authors", Type: blueprint.TypeReference, Reference: blueprint.BlueprintReference{Collection: "authors", MaxReferences: 1}},
+			{Name: "seo", Type: blueprint.TypeObject, Fields: []blueprint.BlueprintField{
+				{Name: "meta_title", Type: blueprint.TypeString, Required: true},
+			}},
+			{Name: "content", Type: blueprint.TypeArray, Zone: []blueprint.Component{
+				{ComponentName: "quote", Fields: []blueprint.BlueprintField{{Name: "text", Type: blueprint.TypeString, Required: true}}},
+			}},
+		},
+	}}
+	schema := Collection(collection, "en", nil)
+	tests := []struct {
+		name  string
+		item  string
+		paths []string
+	}{
+		{"valid", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "pages": 412, "authors": ["` + testUUID + `"], "seo": {"meta_title": "Dune"}, "content": [{"_component": "quote", "text": "Fear is the mind-killer."}]}`, nil},
+		{"optional values are null", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "pages": null, "seo": null}`, nil},
+		{"missing required property", `{"id": 1, "uuid": "` + testUUID + `"}`, []string{""}},
+		{"wrong types", `{"id": 1.5, "uuid": "` + testUUID + `", "title": 1, "pages": "412"}`, []string{"/id", "/pages", "/title"}},
+		{"invalid uuid", `{"id": 1, "uuid": "dune", "title": "Dune"}`, []string{"/uuid"}},
+		{"too many references", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "authors": ["` + testUUID + `", "` + testUUID + `"]}`, []string{"/authors"}},
+		{"unknown property", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "subtitle": ""}`, []string{"/subtitle"}},
+		{"invalid component", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "seo": {"meta_description": ""}}`, []string{"/seo", "/seo/meta_description"}},
+		{"invalid zone entry", `{"id": 1, "uuid": "` + testUUID + `", "title": "Dune", "content": [{"_component": "quote"}, {"_component": "video"}]}`, []string{"/content/0", "/content/1/_component"}},
+	}
+	for _, test := range tests {
+		var item any
+		err := json.Unmarshal([]byte(test.item), &item)
+		if err != nil {
+			t.Fatalf("%s: could not unmarshal item: %v", test.name, err)
+		}
+		errors := schema.Validate(item)
+		if len(errors) != len(test.paths) {
+			t.Errorf("%s: got errors %v, want errors at %v", test.name, errors, test.paths)
+			continue
+		}
+		for index, err := range errors {
+			if err.Path != test.paths[index] {
+				t.Errorf("%s: got error %v, want error at %q", test.name, err, test.paths[index])
+			}
+		}
+	}
+}
+
+func TestValidateGoValues(t *testing.T) {
+	schema := &Schema{Type: Types{TypeObject}, Properties: map[string]*Schema{
+		"count":  {Type: Types{TypeInteger}, Minimum: ptr(int64(1))},
+		"tags":   {Type: Types{TypeArray}, Items: &Schema{Type: Types{TypeString}}},
+		"active": {Type: Types{TypeBoolean}},
+	}}
+	valid := map[string]any{"count": int64(2), "tags": []string{"news"}, "active": true}
+	if errors := schema.Validate(valid); len(errors) != 0 {
+		t.Errorf("got errors %v for valid Go values", errors)
+	}
+	invalid := map[string]any{"count": 0, "tags": []any{"news", 1}, "active": []byte("true")}
+	if errors := schema.Validate(invalid); len(errors) != 3 {
+		t.Errorf("got errors %v, want 3 errors", errors)
+	}
+}
+
+func TestValidateBlueprintFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "blueprint", "blueprint", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("could not find the embedded blueprints: %v", err)
+	}
+	schema := Blueprint()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var value any
+		err = json.Unmarshal(data, &value)
+		if err != nil {
+			t.Fatalf("could not unmarshal %s: %v", file, err)
+		}
+		if errors := schema.Validate(value); len(errors) != 0 {
+			t.Errorf("%s does not match the blueprint schema: %v", file, errors)
+		}
+	}
+	var invalid any
+	err = json.Unmarshal([]byte(`{"collection_name": "articles", "fields": [{"name": "title", "type": "text"}], "colection_display_name": "Articles"}`), &invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := schema.Validate(invalid); len(errors) == 0 {
+		t.Errorf("invalid blueprint matches the blueprint schema")
+	}
+}
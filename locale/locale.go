@@ -0,0 +1,111 @@
+package locale
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Locales
+// contains the configured content locales and the order in which they are tried when a value is missing
+type Locales struct {
+	all       []string
+	fallbacks map[string][]string
+}
+
+// New
+// creates the locale settings. The first locale is the default locale, whose values are stored in the collection tables.
+// fallbacks contains entries in the format "locale:fallback1,fallback2", e. g. "de-AT:de".
+// Every chain implicitly ends with the default locale.
+func New(locales []string, fallbacks []string) (*Locales, error) {
+	if len(locales) == 0 {
+		return nil, fmt.Errorf("no locales configured")
+	}
+	l := Locales{fallbacks: make(map[string][]string)}
+	for _, locale := range locales {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locale %s: %v", locale, err)
+		}
+		if slices.Contains(l.all, tag.String()) {
+			return nil, fmt.Errorf("duplicate locale %s", locale)
+		}
+		l.all = append(l.all, tag.String())
+	}
+	for _, fallback := range fallbacks {
+		if fallback == "" {
+			continue
+		}
+		locale, chain, ok := strings.Cut(fallback, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid fallback %s", fallback)
+		}
+		locale, err := l.normalize(locale)
+		if err != nil {
+			return nil, err
+		}
+		for _, fallbackLocale := range strings.Split(chain, ",") {
+			fallbackLocale, err = l.normalize(fallbackLocale)
+			if err != nil {
+				return nil, err
+			}
+			l.fallbacks[locale] = append(l.fallbacks[locale], fallbackLocale)
+		}
+	}
+	return &l, nil
+}
+
+func (l *Locales) normalize(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %s: %v", locale, err)
+	}
+	if !slices.Contains(l.all, tag.String()) {
+		return "", fmt.Errorf("locale %s is not configured", locale)
+	}
+	return tag.String(), nil
+}
+
+// All
+// returns all configured locales, starting with the default locale
+func (l *Locales) All() []string {
+	return l.all
+}
+
+func (l *Locales) Default() string {
+	return l.all[0]
+}
+
+func (l *Locales) IsDefault(locale string) bool {
+	return locale == l.Default()
+}
+
+// Parse
+// returns the configured locale for the given string. An empty string results in the default locale.
+func (l *Locales) Parse(locale string) (string, error) {
+	if locale == "" {
+		return l.Default(), nil
+	}
+	return l.normalize(locale)
+}
+
+// Chain
+// returns the locales that are tried in order when reading values for the given locale.
+// The chain does not contain duplicates and always ends with the default locale.
+func (l *Locales) Chain(locale string) []string {
+	chain := []string{locale}
+	// Follow fallbacks of fallbacks
+	for index := 0; index < len(chain); index++ {
+		for _, fallback := range l.fallbacks[chain[index]] {
+			if !slices.Contains(chain, fallback) {
+				chain = append(chain, fallback)
+			}
+		}
+	}
+	if !slices.Contains(chain, l.Default()) {
+		chain = append(chain, l.Default())
+	}
+	return chain
+}
@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+type Renderer struct {
+	markdown  goldmark.Markdown
+	sanitizer *Sanitizer
+}
+
+func NewRenderer(allowedTags []string) *Renderer {
+	return &Renderer{
+		markdown: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			// Raw HTML is passed through here and filtered by the sanitizer according to the allowed tags
+			goldmark.WithRendererOptions(html.WithUnsafe()),
+		),
+		sanitizer: NewSanitizer(allowedTags),
+	}
+}
+
+// Render
+// converts markdown to HTML and sanitizes the result
+func (r *Renderer) Render(source string) (string, error) {
+	var buf bytes.Buffer
+	err := r.markdown.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", fmt.Errorf("could not convert markdown: %v", err)
+	}
+	return r.sanitizer.Sanitize(buf.String())
+}
+
+// RenderItem
+// renders all markdown fields of the item and stores the HTML in the corresponding derived fields
+func (r *Renderer) RenderItem(bp *blueprint.Blueprint, item blueprint.Item) error {
+	for _, field := range bp.Fields {
+		if field.Type != blueprint.TypeMarkdown {
+			continue
+		}
+		value, ok := item[field.Name]
+		if !ok {
+			continue
+		}
+		source, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("markdown field %s is not a string", field.Name)
+		}
+		rendered, err := r.Render(source)
+		if err != nil {
+			return fmt.Errorf("could not render field %s: %v", field.Name, err)
+		}
+		item[blueprint.MarkdownHTMLFieldName(field.Name)] = rendered
+	}
+	return nil
+}
+
+// ItemHTML
+// returns the sanitized HTML that has been rendered from the named markdown field of an item, for use in templates
+func ItemHTML(item blueprint.Item, fieldName string) template.HTML {
+	rendered, _ := item[blueprint.MarkdownHTMLFieldName(fieldName)].(string)
+	return template.HTML(rendered)
+}
@@ -0,0 +1,142 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// Elements whose content is removed together with the element if they are not allowed
+	dropWithContent = []atom.Atom{
+		atom.Script,
+		atom.Style,
+		atom.Iframe,
+		atom.Object,
+		atom.Embed,
+		atom.Template,
+		atom.Noscript,
+		atom.Textarea,
+		atom.Select,
+	}
+	allowedAttributes = map[string][]string{
+		"*":    {"title"},
+		"a":    {"href"},
+		"img":  {"src", "alt", "width", "height"},
+		"code": {"class"},
+		"th":   {"align"},
+		"td":   {"align"},
+		"ol":   {"start"},
+	}
+	urlAttributes = []string{
+		"href",
+		"src",
+	}
+	allowedURLSchemes = []string{
+		"",
+		"http",
+		"https",
+		"mailto",
+	}
+)
+
+type Sanitizer struct {
+	allowedTags []string
+}
+
+func NewSanitizer(allowedTags []string) *Sanitizer {
+	return &Sanitizer{allowedTags: allowedTags}
+}
+
+// Sanitize
+// removes all elements that are not in the list of allowed tags and all attributes that are not known to be safe
+func (s *Sanitizer) Sanitize(source string) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(source), context)
+	if err != nil {
+		return "", fmt.Errorf("could not parse html: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		for _, sanitized := range s.sanitizeNode(node) {
+			err = html.Render(&buf, sanitized)
+			if err != nil {
+				return "", fmt.Errorf("could not render html: %v", err)
+			}
+		}
+	}
+	return buf.String(), nil
+}
+
+// sanitizeNode
+// returns the nodes that should replace the given node
+func (s *Sanitizer) sanitizeNode(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{node}
+	case html.ElementNode:
+		// Handled below
+	default:
+		// Comments, doctypes, etc.
+		return nil
+	}
+	// Sanitize children first
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, child)
+	}
+	for _, child := range children {
+		node.RemoveChild(child)
+	}
+	var sanitizedChildren []*html.Node
+	for _, child := range children {
+		sanitizedChildren = append(sanitizedChildren, s.sanitizeNode(child)...)
+	}
+	if !slices.Contains(s.allowedTags, node.Data) {
+		if slices.Contains(dropWithContent, node.DataAtom) {
+			return nil
+		}
+		// Keep the content of the element
+		return sanitizedChildren
+	}
+	for _, child := range sanitizedChildren {
+		node.AppendChild(child)
+	}
+	node.Attr = sanitizeAttributes(node.Data, node.Attr)
+	return []*html.Node{node}
+}
+
+func sanitizeAttributes(tag string, attributes []html.Attribute) []html.Attribute {
+	var sanitized []html.Attribute
+	for _, attribute := range attributes {
+		if attribute.Namespace != "" {
+			continue
+		}
+		key := strings.ToLower(attribute.Key)
+		if !slices.Contains(allowedAttributes["*"], key) && !slices.Contains(allowedAttributes[tag], key) {
+			continue
+		}
+		if slices.Contains(urlAttributes, key) && !isSafeURL(attribute.Val) {
+			continue
+		}
+		if tag == "code" && key == "class" && !strings.HasPrefix(attribute.Val, "language-") {
+			// Only allow classes used for syntax highlighting
+			continue
+		}
+		sanitized = append(sanitized, attribute)
+	}
+	return sanitized
+}
+
+func isSafeURL(value string) bool {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	return slices.Contains(allowedURLSchemes, strings.ToLower(u.Scheme))
+}
@@ -0,0 +1,107 @@
+package preview
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Name of the query parameter of preview tokens, for the public site and the preview API
+	QueryParam = "preview"
+	// Length of generated secrets in bytes
+	secretLength = 32
+)
+
+var (
+	ErrorInvalidToken = errors.New("invalid preview token")
+	ErrorExpiredToken = errors.New("preview token has expired")
+
+	encoding = base64.RawURLEncoding
+)
+
+// Claims
+// are signed by a preview token. The token grants read access to the item, even if it has not been published.
+type Claims struct {
+	Collection string `json:"collection"`
+	UUID       string `json:"uuid"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+// Allows
+// returns true if the claims grant access to the item
+func (c *Claims) Allows(collection string, uuid string) bool {
+	return c != nil && c.Collection == collection && c.UUID == uuid
+}
+
+// Signer
+// creates and verifies preview tokens. Tokens consist of the claims and their HMAC-SHA256 signature, so they need no storage.
+type Signer struct {
+	secret []byte
+	expiry time.Duration
+}
+
+// NewSigner
+// returns a signer for tokens that expire after the duration. If secret is empty, a random secret is generated,
+// so that tokens become invalid when the process ends.
+func NewSigner(secret string, expiry time.Duration) (*Signer, error) {
+	s := Signer{secret: []byte(secret), expiry: expiry}
+	if secret == "" {
+		s.secret = make([]byte, secretLength)
+		_, err := rand.Read(s.secret)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate secret: %v", err)
+		}
+	}
+	return &s, nil
+}
+
+// Token
+// returns a token for the item and the time it expires
+func (s *Signer) Token(collection string, uuid string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.expiry)
+	payload, err := json.Marshal(Claims{Collection: collection, UUID: uuid, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("could not marshal claims: %v", err)
+	}
+	encoded := encoding.EncodeToString(payload)
+	return encoded + "." + encoding.EncodeToString(s.sign(encoded)), expiresAt, nil
+}
+
+// Verify
+// returns the claims of the token if its signature is valid and it has not expired
+func (s *Signer) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrorInvalidToken
+	}
+	decodedSignature, err := encoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, s.sign(encoded)) {
+		return nil, ErrorInvalidToken
+	}
+	payload, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrorInvalidToken
+	}
+	var claims Claims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, ErrorInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrorExpiredToken
+	}
+	return &claims, nil
+}
+
+func (s *Signer) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
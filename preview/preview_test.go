@@ -0,0 +1,88 @@
+package preview
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestVerify(t *testing.T) {
+	signer, err := NewSigner(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	token, expiresAt, err := signer.Token("articles", "c0ffee")
+	if err != nil {
+		t.Fatalf("could not create token: %v", err)
+	}
+	if time.Until(expiresAt) <= 59*time.Minute {
+		t.Errorf("token expires at %v, want in one hour", expiresAt)
+	}
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("could not verify token: %v", err)
+	}
+	if !claims.Allows("articles", "c0ffee") || claims.Allows("articles", "other") || claims.Allows("pages", "c0ffee") {
+		t.Errorf("claims %+v allow the wrong items", claims)
+	}
+	// Tokens are valid for other signers with the same secret, e. g. other instances
+	other, err := NewSigner(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	if _, err := other.Verify(token); err != nil {
+		t.Errorf("token is not valid for a signer with the same secret: %v", err)
+	}
+}
+
+func TestVerifyInvalidToken(t *testing.T) {
+	signer, err := NewSigner(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	token, _, err := signer.Token("articles", "c0ffee")
+	if err != nil {
+		t.Fatalf("could not create token: %v", err)
+	}
+	encoded, signature, _ := strings.Cut(token, ".")
+	// Claims of another item with the signature of the token
+	forged, _, err := signer.Token("articles", "other")
+	if err != nil {
+		t.Fatalf("could not create token: %v", err)
+	}
+	forgedEncoded, _, _ := strings.Cut(forged, ".")
+	random, err := NewSigner("", time.Hour)
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	expired, err := NewSigner(testSecret, -time.Second)
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	expiredToken, _, err := expired.Token("articles", "c0ffee")
+	if err != nil {
+		t.Fatalf("could not create token: %v", err)
+	}
+	tests := []struct {
+		name   string
+		signer *Signer
+		token  string
+		want   error
+	}{
+		{"empty", signer, "", ErrorInvalidToken},
+		{"no signature", signer, encoded, ErrorInvalidToken},
+		{"changed claims", signer, forgedEncoded + "." + signature, ErrorInvalidToken},
+		{"invalid encoding", signer, encoded + ".!" + signature, ErrorInvalidToken},
+		{"other secret", random, token, ErrorInvalidToken},
+		{"expired", signer, expiredToken, ErrorExpiredToken},
+	}
+	for _, test := range tests {
+		claims, err := test.signer.Verify(test.token)
+		if !errors.Is(err, test.want) || claims != nil {
+			t.Errorf("%s: got claims %+v and error %v, want %v", test.name, claims, err, test.want)
+		}
+	}
+}
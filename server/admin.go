@@ -4,31 +4,58 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/rangidev/rangi/admin"
 	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
 )
 
 type getItemsQueryParams struct {
-	Limit  int   `schema:"limit,required" validate:"gte=1,lte=200"`
-	Offset int64 `schema:"offset,required"`
+	Limit  int    `schema:"limit,required" validate:"gte=1,lte=200"`
+	Offset int64  `schema:"offset,required"`
+	Locale string `schema:"locale"` // Defaults to the default locale
 }
 
 func createAdminRouter(s *Server) http.Handler {
 	router := chi.NewRouter()
-	router.Use(admin.EnsurePermission)
+	router.Use(admin.EnsurePermission(s.config.DatabaseInstance, s.config.TrustProxyHeaders))
 	router.Get("/", s.GetAdminBase)
 	router.Get("/login", s.GetAdminLogin)
 	router.Post("/login", s.PostAdminLogin)
+	router.Post("/logout", s.PostAdminLogout)
 	router.Get("/dashboard", s.GetAdminDashboard)
 	router.Get("/collections/{collection}", s.GetAdminCollection)
-	router.Get("/edit/{collection}/{id}", s.GetAdminEdit) // If id == "new", we will display an empty input form
+	router.Get("/edit/{collection}/{id}", s.GetAdminEdit) // If id == "new", we will display an empty input form. Singleton collections ignore the id.
+	router.Get("/preview/{collection}/{id}", s.GetAdminPreview)
 	router.Get("/settings", s.GetAdminSettings)
+	router.Post("/settings/locale", s.PostAdminSettingsLocale)
+	router.Put("/settings/users/{id}/role", s.PutAdminUserRole)
+	router.Post("/settings/blueprints/reload", s.PostAdminBlueprintsReload)
+	router.Get("/settings/blueprints", s.GetAdminBlueprints)
+	router.Get("/settings/blueprints/new", s.GetAdminBlueprint)
+	router.Get("/settings/blueprints/edit/{collection}", s.GetAdminBlueprint)
+	router.Post("/settings/blueprints/preview", s.PostAdminBlueprintPreview)
+	router.Post("/settings/blueprints/apply", s.PostAdminBlueprintApply)
+	router.Get("/settings/audit", s.GetAdminAudit)              // For possible query parameters see getAuditQueryParams
+	router.Get("/settings/audit/export", s.GetAdminAuditExport) // For possible query parameters see getAuditQueryParams
+	router.Post("/markdown/preview", s.PostAdminMarkdownPreview)
+	router.Get("/media", s.GetAdminMedia)
+	router.Get("/assets", s.GetAdminAssets) // For possible query parameters see getAssetsQueryParams
+	router.Post("/assets", s.PostAdminAssets)
+	router.Put("/assets/{uuid}", s.PutAdminAsset)
+	router.Put("/assets/{uuid}/focal-point", s.PutAdminAssetFocalPoint)
+	router.Delete("/assets/{uuid}", s.DeleteAdminAsset)
+	router.Get("/export", s.GetAdminExport) // For possible query parameters see getExportQueryParams
+	router.Get("/{collection}/schema.json", s.GetAdminCollectionSchema)
+	router.Get("/{collection}/item", s.GetAdminSingleton) // For possible query parameters see GetAdminSingleton
+	router.Get("/{collection}/export", s.GetAdminCollectionExport)
+	router.Post("/{collection}/import", s.PostAdminCollectionImport)
 	router.Post("/{collection}/items", s.PostAdminItem)
 	router.Put("/{collection}/items", s.PutAdminItem)
+	router.Post("/{collection}/items/{id}/publish", s.PostAdminItemPublish)
+	router.Delete("/{collection}/items/{id}", s.DeleteAdminItem)
 	router.Get("/{collection}/items", s.GetAdminItems) // For possible query parameters see getItemsQueryParams
 	return router
 }
@@ -44,39 +71,15 @@ func (s *Server) GetAdminStatic(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GetAdminLogin(w http.ResponseWriter, r *http.Request) {
-	err := s.adminTemplates.Render(w, nil, admin.TemplateLogin, s.collectionLoader, "")
+	err := s.adminTemplates.Render(w, r, nil, admin.TemplateLogin, s.collectionLoader, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering login template: %v", err), http.StatusInternalServerError)
 		return
 	}
 }
 
-func (s *Server) PostAdminLogin(w http.ResponseWriter, r *http.Request) {
-	// Read login data
-	email := r.PostFormValue("email")
-	if email == "" {
-		http.Error(w, "missing value 'email'", http.StatusBadRequest)
-		return
-	}
-	password := r.PostFormValue("password")
-	if password == "" {
-		http.Error(w, "missing value 'password'", http.StatusBadRequest)
-		return
-	}
-	// Return dummy cookie for now
-	c := &http.Cookie{
-		Name:     admin.SessionCookieName,
-		Value:    "true",
-		HttpOnly: true,
-		Path:     "/admin/",
-		Expires:  time.Now().Add(admin.SessionCookieDuration),
-	}
-	http.SetCookie(w, c)
-	w.Header().Set("HX-Redirect", admin.DashboardPath)
-}
-
 func (s *Server) GetAdminDashboard(w http.ResponseWriter, r *http.Request) {
-	err := s.adminTemplates.Render(w, nil, admin.TemplateDashboard, s.collectionLoader, "")
+	err := s.adminTemplates.Render(w, r, nil, admin.TemplateDashboard, s.collectionLoader, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering dashboard template: %v", err), http.StatusInternalServerError)
 		return
@@ -90,6 +93,11 @@ func (s *Server) GetAdminCollection(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if collectionData.Blueprint.Singleton {
+		// There is no list of items
+		http.Redirect(w, r, admin.SingletonPath(collectionData.Blueprint.CollectionName), http.StatusFound)
+		return
+	}
 	// Get items
 	items, err := s.config.DatabaseInstance.GetItems(collectionData, s.config.AdminItemsLimit, 0)
 	if err != nil {
@@ -101,7 +109,7 @@ func (s *Server) GetAdminCollection(w http.ResponseWriter, r *http.Request) {
 		"items":      items,
 		"limit":      s.config.AdminItemsLimit,
 	}
-	err = s.adminTemplates.Render(w, templateData, admin.TemplateCollection, s.collectionLoader, "")
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateCollection, s.collectionLoader, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering collection template: %v", err), http.StatusInternalServerError)
 		return
@@ -120,21 +128,59 @@ func (s *Server) GetAdminEdit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	locale, err := s.config.Locales.Parse(r.URL.Query().Get("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	item := make(blueprint.Item)
+	if collectionData.Blueprint.Singleton {
+		singleton, err := s.getOrCreateSingleton(r.Context(), collectionData)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get item: %v", err), http.StatusInternalServerError)
+			return
+		}
+		id = fmt.Sprintf("%v", singleton[blueprint.KeyID])
+	}
+	translationCounts := make(map[string]int)
 	if id != "new" {
 		// Get item
-		item, err = s.config.DatabaseInstance.GetItem(collectionData, id)
+		item, err = s.config.DatabaseInstance.GetItem(collectionData, blueprint.KeyID, id)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("could not get item: %v", err), http.StatusInternalServerError)
 			return
 		}
+		translationCounts, err = s.config.DatabaseInstance.GetTranslationCounts(collectionData, id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get translation status: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !s.config.Locales.IsDefault(locale) {
+			// Show the translations of the locale only, missing translations are left empty
+			translations, err := s.config.DatabaseInstance.GetTranslations(collectionData, id, locale)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("could not get translations: %v", err), http.StatusInternalServerError)
+				return
+			}
+			for _, field := range collectionData.Blueprint.LocalizedFields() {
+				item[field.Name] = translations[field.Name]
+			}
+		}
+	} else if !s.config.Locales.IsDefault(locale) {
+		http.Error(w, "new items have to be created in the default locale", http.StatusBadRequest)
+		return
 	}
 	templateData := admin.TemplateData{
-		"collection": collectionData.Blueprint.CollectionName,
-		"blueprint":  collectionData.Blueprint,
-		"item":       item,
-	}
-	err = s.adminTemplates.Render(w, templateData, admin.TemplateEdit, s.collectionLoader, "")
+		"collection":          collectionData.Blueprint.CollectionName,
+		"blueprint":           collectionData.Blueprint,
+		"item":                item,
+		"locale":              locale,
+		"locales":             s.config.Locales.All(),
+		"defaultLocale":       s.config.Locales.Default(),
+		"translationCounts":   translationCounts,
+		"localizedFieldCount": len(collectionData.Blueprint.LocalizedFields()),
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateEdit, s.collectionLoader, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering collection template: %v", err), http.StatusInternalServerError)
 		return
@@ -142,13 +188,65 @@ func (s *Server) GetAdminEdit(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GetAdminSettings(w http.ResponseWriter, r *http.Request) {
-	err := s.adminTemplates.Render(w, nil, admin.TemplateSettings, s.collectionLoader, "")
+	templateData := admin.TemplateData{
+		"adminLocales": s.adminTemplates.Catalogs().Options(),
+	}
+	if admin.IsAdmin(r.Context()) {
+		users, err := s.config.DatabaseInstance.GetUsers()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get users: %v", err), http.StatusInternalServerError)
+			return
+		}
+		webhookDeliveries, err := s.config.DatabaseInstance.GetWebhookDeliveries(s.config.AdminItemsLimit, 0)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get webhook deliveries: %v", err), http.StatusInternalServerError)
+			return
+		}
+		templateData["users"] = users
+		templateData["roles"] = database.AllUserRoles
+		templateData["webhooks"] = s.webhookDispatcher.Webhooks()
+		templateData["webhookDeliveries"] = webhookDeliveries
+	}
+	err := s.adminTemplates.Render(w, r, templateData, admin.TemplateSettings, s.collectionLoader, "")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering settings template: %v", err), http.StatusInternalServerError)
 		return
 	}
 }
 
+// PostAdminSettingsLocale
+// stores the chosen locale of the admin interface for the logged in user
+func (s *Server) PostAdminSettingsLocale(w http.ResponseWriter, r *http.Request) {
+	locale := r.PostFormValue("locale")
+	if !s.adminTemplates.Catalogs().IsAvailable(locale) {
+		http.Error(w, fmt.Sprintf("unknown locale %s", locale), http.StatusBadRequest)
+		return
+	}
+	user := admin.UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+	err := s.config.DatabaseInstance.UpdateUserLocale(user.ID, locale)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not update locale: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Redirect", admin.SettingsPath)
+}
+
+// PostAdminMarkdownPreview
+// renders the markdown from the "markdown" form value to sanitized HTML
+func (s *Server) PostAdminMarkdownPreview(w http.ResponseWriter, r *http.Request) {
+	rendered, err := s.markdownRenderer.Render(r.PostFormValue("markdown"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not render markdown: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, rendered)
+}
+
 func (s *Server) PostAdminItem(w http.ResponseWriter, r *http.Request) {
 	// Get collection
 	collectionData, err := s.getCollection(r)
@@ -168,21 +266,23 @@ func (s *Server) PostAdminItem(w http.ResponseWriter, r *http.Request) {
 			// Do not overwrite field with user input
 			continue
 		}
+		if field.Name == blueprint.KeyPublishedAt {
+			// Field is only set when publishing
+			continue
+		}
 		if field.Type == blueprint.TypeReference {
 			// TODO: support this
 			continue
 		}
-		value := r.PostFormValue(field.Name)
-		// TODO: Parse value into type that is expected from the blueprint field
-		item[field.Name] = value
+		item[field.Name] = parseFormValue(field, r.PostFormValue(field.Name))
 	}
 	if len(item) == 0 {
 		http.Error(w, "item is empty", http.StatusBadRequest)
 		return
 	}
-	err = s.config.DatabaseInstance.CreateItem(collectionData, item)
+	err = s.createItem(r.Context(), collectionData, item)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("could not set item in database: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("could not create item: %v", err), itemErrorStatus(err))
 		return
 	}
 	w.Header().Set("HX-Redirect", admin.CollectionPath(collectionData.Blueprint.CollectionName))
@@ -198,7 +298,7 @@ func (s *Server) PutAdminItem(w http.ResponseWriter, r *http.Request) {
 	// Create item
 	item := blueprint.Item{}
 	for _, field := range collectionData.Blueprint.Fields {
-		if field.Name == blueprint.KeyUUID || field.Name == blueprint.KeyCollection {
+		if field.Name == blueprint.KeyUUID || field.Name == blueprint.KeyCollection || field.Name == blueprint.KeyPublishedAt {
 			// Do not update field in database
 			continue
 		}
@@ -206,9 +306,7 @@ func (s *Server) PutAdminItem(w http.ResponseWriter, r *http.Request) {
 			// TODO: support this
 			continue
 		}
-		value := r.PostFormValue(field.Name)
-		// TODO: Parse value into type that is expected from the blueprint field
-		item[field.Name] = value
+		item[field.Name] = parseFormValue(field, r.PostFormValue(field.Name))
 	}
 	if len(item) == 0 {
 		http.Error(w, "item is empty", http.StatusBadRequest)
@@ -218,9 +316,54 @@ func (s *Server) PutAdminItem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no id in item", http.StatusBadRequest)
 		return
 	}
-	err = s.config.DatabaseInstance.UpdateItem(collectionData, item)
+	locale, err := s.config.Locales.Parse(r.PostFormValue("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.updateItem(r.Context(), collectionData, item, locale)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not update item: %v", err), itemErrorStatus(err))
+		return
+	}
+	w.Header().Set("HX-Redirect", admin.CollectionPath(collectionData.Blueprint.CollectionName))
+}
+
+func (s *Server) PostAdminItemPublish(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing 'id' path parameter", http.StatusBadRequest)
+		return
+	}
+	// Get collection
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.publishItem(r.Context(), collectionData, id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("could not set item in database: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("could not publish item: %v", err), itemErrorStatus(err))
+		return
+	}
+	w.Header().Set("HX-Redirect", admin.EditPath(collectionData.Blueprint.CollectionName, id))
+}
+
+func (s *Server) DeleteAdminItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing 'id' path parameter", http.StatusBadRequest)
+		return
+	}
+	// Get collection
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.deleteItem(r.Context(), collectionData, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not delete item: %v", err), itemErrorStatus(err))
 		return
 	}
 	w.Header().Set("HX-Redirect", admin.CollectionPath(collectionData.Blueprint.CollectionName))
@@ -246,19 +389,30 @@ func (s *Server) GetAdminItems(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("invalid query parameters: %v", err), http.StatusBadRequest)
 		return
 	}
+	locale, err := s.config.Locales.Parse(queryParams.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	// Get items
 	items, err := s.config.DatabaseInstance.GetItems(collectionData, queryParams.Limit, queryParams.Offset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("could not get items: %v", err), http.StatusInternalServerError)
 		return
 	}
+	err = s.config.DatabaseInstance.LocalizeItems(collectionData, items, s.config.Locales.Chain(locale), s.config.Locales.Default())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not localize items: %v", err), http.StatusInternalServerError)
+		return
+	}
 	templateData := admin.TemplateData{
 		"collection": collectionData.Blueprint.CollectionName,
 		"items":      items,
 		"limit":      queryParams.Limit,
 		"offset":     queryParams.Offset,
+		"locale":     locale,
 	}
-	err = s.adminTemplates.Render(w, templateData, admin.TemplateCollection, s.collectionLoader, "list")
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateCollection, s.collectionLoader, "list")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error while rendering collection template: %v", err), http.StatusInternalServerError)
 		return
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/asset"
+)
+
+const (
+	assetsFormKey = "files"
+	// Uploaded files that do not fit into memory are written to temporary files
+	assetsMaxMemory = 32 << 20
+)
+
+type getAssetsQueryParams struct {
+	Limit  int   `schema:"limit,required" validate:"gte=1,lte=200"`
+	Offset int64 `schema:"offset,required"`
+}
+
+// GetAsset
+// serves the file of an asset to the public
+func (s *Server) GetAsset(w http.ResponseWriter, r *http.Request) {
+	a, err := s.config.DatabaseInstance.GetAsset(chi.URLParam(r, "uuid"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("could not get asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+	disposition := "attachment"
+	if a.IsInline() {
+		disposition = "inline"
+	}
+	contentDisposition := mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})
+	if presigner, ok := s.config.AssetStorage.(asset.Presigner); ok && s.config.S3RedirectToPresignedURL {
+		presignedURL, err := presigner.PresignedURL(a.StorageKey, s.config.S3PresignedURLExpiry, map[string]string{
+			"Content-Type":        a.MimeType,
+			"Content-Disposition": contentDisposition,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not presign asset url: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// Presigned URLs expire, so the redirect must not be cached for long
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		http.Redirect(w, r, presignedURL, http.StatusFound)
+		return
+	}
+	file, err := s.config.AssetStorage.Get(a.StorageKey)
+	if errors.Is(err, asset.ErrorNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("could not get asset file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", a.MimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", contentDisposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// The content of an asset never changes
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, err = io.Copy(w, file)
+	if err != nil {
+		s.config.Logger.Error("Could not write asset", "uuid", a.UUID, "error", err)
+	}
+}
+
+func (s *Server) GetAdminMedia(w http.ResponseWriter, r *http.Request) {
+	assets, err := s.config.DatabaseInstance.GetAssets(s.config.AdminItemsLimit, 0)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get assets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	templateData := admin.TemplateData{
+		"assets": assets,
+		"limit":  s.config.AdminItemsLimit,
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateMedia, s.collectionLoader, "")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering media template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) GetAdminAssets(w http.ResponseWriter, r *http.Request) {
+	// Read query parameters
+	var queryParams getAssetsQueryParams
+	err := s.schemaDecoder.Decode(&queryParams, r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not decode query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Check if valid parameters
+	err = s.config.Validate.Struct(&queryParams)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Get assets
+	assets, err := s.config.DatabaseInstance.GetAssets(queryParams.Limit, queryParams.Offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get assets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	templateData := admin.TemplateData{
+		"assets": assets,
+		"limit":  queryParams.Limit,
+		"offset": queryParams.Offset,
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateMedia, s.collectionLoader, "list")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering media template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) PostAdminAssets(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.AssetsMaxUploadSize)
+	err := r.ParseMultipartForm(assetsMaxMemory)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not parse upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	fileHeaders := r.MultipartForm.File[assetsFormKey]
+	if len(fileHeaders) == 0 {
+		http.Error(w, fmt.Sprintf("missing value '%s'", assetsFormKey), http.StatusBadRequest)
+		return
+	}
+	for _, fileHeader := range fileHeaders {
+		err = s.storeAsset(fileHeader)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not store %s: %v", fileHeader.Filename, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("HX-Redirect", admin.MediaPath)
+}
+
+func (s *Server) storeAsset(fileHeader *multipart.FileHeader) error {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("could not open file: %v", err)
+	}
+	defer file.Close()
+	a, err := asset.New(fileHeader.Filename, file)
+	if err != nil {
+		return err
+	}
+	err = s.config.AssetStorage.Put(a.StorageKey, file, a.Size)
+	if err != nil {
+		return fmt.Errorf("could not put file into storage: %v", err)
+	}
+	err = s.config.DatabaseInstance.CreateAsset(a)
+	if err != nil {
+		// Do not leave orphaned files behind
+		_ = s.config.AssetStorage.Delete(a.StorageKey)
+		return fmt.Errorf("could not create asset in database: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) PutAdminAsset(w http.ResponseWriter, r *http.Request) {
+	err := s.config.DatabaseInstance.UpdateAssetAltText(chi.URLParam(r, "uuid"), r.PostFormValue("alt_text"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not update asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) DeleteAdminAsset(w http.ResponseWriter, r *http.Request) {
+	a, err := s.config.DatabaseInstance.GetAsset(chi.URLParam(r, "uuid"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("could not get asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.config.DatabaseInstance.DeleteAsset(a.UUID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not delete asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.config.AssetStorage.Delete(a.StorageKey)
+	if err != nil {
+		s.config.Logger.Error("Could not delete asset file", "uuid", a.UUID, "error", err)
+	}
+	err = s.imageCache.Purge(a.UUID)
+	if err != nil {
+		s.config.Logger.Error("Could not purge image cache", "uuid", a.UUID, "error", err)
+	}
+}
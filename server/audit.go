@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	auditDateLayout = "2006-01-02"
+)
+
+type getAuditQueryParams struct {
+	Action     string `schema:"action"`
+	Actor      string `schema:"actor"`
+	Collection string `schema:"collection"`
+	Since      string `schema:"since" validate:"omitempty,datetime=2006-01-02"` // First day, inclusive
+	Until      string `schema:"until" validate:"omitempty,datetime=2006-01-02"` // Last day, inclusive
+	Offset     int64  `schema:"offset"`                                         // If set, only the next rows of the list are rendered
+}
+
+func (q *getAuditQueryParams) filter() database.AuditFilter {
+	filter := database.AuditFilter{
+		Action:     q.Action,
+		Actor:      strings.TrimSpace(q.Actor),
+		Collection: q.Collection,
+	}
+	if since, err := time.Parse(auditDateLayout, q.Since); err == nil {
+		filter.Since = since.Unix()
+	}
+	if until, err := time.Parse(auditDateLayout, q.Until); err == nil {
+		filter.Until = until.AddDate(0, 0, 1).Unix()
+	}
+	return filter
+}
+
+// query
+// returns the filter as URL query, without offset
+func (q *getAuditQueryParams) query() string {
+	values := url.Values{}
+	for key, value := range map[string]string{"action": q.Action, "actor": q.Actor, "collection": q.Collection, "since": q.Since, "until": q.Until} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values.Encode()
+}
+
+func (s *Server) decodeAuditQueryParams(r *http.Request) (*getAuditQueryParams, error) {
+	var queryParams getAuditQueryParams
+	err := s.schemaDecoder.Decode(&queryParams, r.URL.Query())
+	if err != nil {
+		return nil, fmt.Errorf("could not decode query parameters: %v", err)
+	}
+	err = s.config.Validate.Struct(&queryParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query parameters: %v", err)
+	}
+	return &queryParams, nil
+}
+
+// GetAdminAudit
+// shows the filtered audit log
+func (s *Server) GetAdminAudit(w http.ResponseWriter, r *http.Request) {
+	queryParams, err := s.decodeAuditQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := s.config.DatabaseInstance.GetAuditEntries(queryParams.filter(), s.config.AdminItemsLimit, queryParams.Offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get audit entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	templateData := admin.TemplateData{
+		"entries": entries,
+		"filter":  queryParams,
+		"query":   queryParams.query(),
+		"actions": audit.AllActions,
+		"limit":   s.config.AdminItemsLimit,
+		"offset":  queryParams.Offset,
+	}
+	subTemplate := ""
+	if queryParams.Offset > 0 {
+		subTemplate = "list"
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateAudit, s.collectionLoader, subTemplate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering audit template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetAdminAuditExport
+// downloads the filtered audit log as JSON lines
+func (s *Server) GetAdminAuditExport(w http.ResponseWriter, r *http.Request) {
+	queryParams, err := s.decodeAuditQueryParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"rangi-audit-%s.jsonl\"", time.Now().Format(auditDateLayout)))
+	err = s.auditLog.Export(w, queryParams.filter())
+	if err != nil {
+		// The header has already been sent
+		s.config.Logger.Error("Could not export audit log", "error", err)
+	}
+}
+
+// PostAdminBlueprintsReload
+// reads the blueprints again and adds missing tables and columns
+func (s *Server) PostAdminBlueprintsReload(w http.ResponseWriter, r *http.Request) {
+	problems := s.collectionLoader.Validate()
+	if len(problems) > 0 {
+		http.Error(w, fmt.Sprintf("invalid blueprints:\n%v", problems), http.StatusUnprocessableEntity)
+		return
+	}
+	collections, err := s.collectionLoader.GetAll()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get collections: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	problems = s.computedFunctionProblems(collections)
+	if len(problems) > 0 {
+		http.Error(w, fmt.Sprintf("invalid blueprints:\n%v", problems), http.StatusUnprocessableEntity)
+		return
+	}
+	err = s.config.DatabaseInstance.CreateTables(collections, s.collectionLoader)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not create tables: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var names []string
+	for _, collection := range collections {
+		names = append(names, collection.Blueprint.CollectionName)
+	}
+	s.auditLog.Add(r.Context(), audit.Record{Action: audit.ActionBlueprintsReload, Target: strings.Join(names, ",")})
+	w.Header().Set("HX-Redirect", admin.SettingsPath)
+}
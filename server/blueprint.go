@@ -0,0 +1,393 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/blueprint"
+)
+
+// blueprintForm
+// is sent by the blueprint editor. Labels are sent as one value per locale.
+type blueprintForm struct {
+	New            bool                 `schema:"new"`
+	CollectionName string               `schema:"collection_name"`
+	DisplayNames   []labelForm          `schema:"display_names"`
+	Singleton      bool                 `schema:"singleton"`
+	LocalizedTitle bool                 `schema:"localized_title"`
+	Fields         []blueprintFieldForm `schema:"fields"`
+	Feed           bool                 `schema:"feed"`
+	FeedTitle      string               `schema:"feed_title"`
+	FeedSummary    string               `schema:"feed_summary"`
+	FeedDate       string               `schema:"feed_date"`
+	FeedAuthor     string               `schema:"feed_author"`
+	PreviewURL     string               `schema:"preview_url"`
+}
+
+type blueprintFieldForm struct {
+	Name                string      `schema:"name"`
+	DisplayNames        []labelForm `schema:"display_names"`
+	Type                string      `schema:"type"`
+	Required            bool        `schema:"required"`
+	Hidden              bool        `schema:"hidden"`
+	Localized           bool        `schema:"localized"`
+	ReferenceCollection string      `schema:"reference_collection"`
+	MaxReferences       int         `schema:"max_references"`
+	SlugSource          string      `schema:"slug_source"`
+	LockAfterPublish    bool        `schema:"lock_after_publish"`
+	Component           string      `schema:"component"`
+	Components          []string    `schema:"components"`
+	ComputedExpression  string      `schema:"computed_expression"`
+	ComputedFunction    string      `schema:"computed_function"`
+	VisibleIf           string      `schema:"visible_if"`  // JSON list of conditions
+	RequiredIf          string      `schema:"required_if"` // JSON list of conditions
+}
+
+type labelForm struct {
+	Locale string `schema:"locale"`
+	Value  string `schema:"value"`
+}
+
+// blueprintFieldView
+// is a field in the blueprint editor
+type blueprintFieldView struct {
+	Field        blueprint.BlueprintField
+	DisplayNames []labelForm
+}
+
+// GetAdminBlueprints
+// lists all collections with links to the blueprint editor
+func (s *Server) GetAdminBlueprints(w http.ResponseWriter, r *http.Request) {
+	err := s.adminTemplates.Render(w, r, nil, admin.TemplateBlueprints, s.collectionLoader, "")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering blueprints template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetAdminBlueprint
+// shows the blueprint editor for a collection, or an empty editor if there is no collection path parameter
+func (s *Server) GetAdminBlueprint(w http.ResponseWriter, r *http.Request) {
+	bp := &blueprint.Blueprint{}
+	isNew := chi.URLParam(r, "collection") == ""
+	if !isNew {
+		collection, err := s.getCollection(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		bp = collection.Blueprint
+	}
+	names, err := s.collectionLoader.Names()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get collection names: %v", err), http.StatusInternalServerError)
+		return
+	}
+	components, err := s.collectionLoader.ComponentNames()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	locales := s.adminTemplates.Catalogs().Locales()
+	var fields []blueprintFieldView
+	for _, field := range bp.CustomFields() {
+		fields = append(fields, blueprintFieldView{Field: field, DisplayNames: labelValues(field.DisplayName, locales)})
+	}
+	var types []blueprint.Type
+	for _, typ := range blueprint.AllTypes {
+		// Reserved for default fields
+		if typ != blueprint.TypeID && typ != blueprint.TypeUUID {
+			types = append(types, typ)
+		}
+	}
+	templateData := admin.TemplateData{
+		"new":          isNew,
+		"blueprint":    bp,
+		"displayNames": labelValues(bp.CollectionDisplayName, locales),
+		"fields":       fields,
+		// Template for fields that are added in the editor
+		"newField": blueprintFieldView{
+			Field:        blueprint.BlueprintField{Type: blueprint.TypeString, Reference: blueprint.BlueprintReference{MaxReferences: -1}},
+			DisplayNames: labelValues(nil, locales),
+		},
+		"types":       types,
+		"collections": names,
+		"components":  components,
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateBlueprint, s.collectionLoader, "")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering blueprint template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// PostAdminBlueprintPreview
+// validates the blueprint from the editor and renders the problems or the resulting blueprint file and migration
+func (s *Server) PostAdminBlueprintPreview(w http.ResponseWriter, r *http.Request) {
+	form, err := s.decodeBlueprintForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bp, err := form.blueprint()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	templateData := admin.TemplateData{}
+	parsed, problems := s.collectionLoader.Check(bp)
+	if err := s.checkBlueprintName(form); err != nil {
+		problems = append(problems, blueprint.Problem{Message: err.Error()})
+	}
+	problems = append(problems, s.computedFields.Problems(bp, s.collectionLoader.File(bp.CollectionName))...)
+	if len(problems) > 0 {
+		templateData["problems"] = problems
+	} else {
+		plan, err := s.config.DatabaseInstance.PlanMigration(&blueprint.Collection{Blueprint: parsed})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not plan migration: %v", err), http.StatusInternalServerError)
+			return
+		}
+		file, err := bp.MarshalFile()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not marshal blueprint: %v", err), http.StatusInternalServerError)
+			return
+		}
+		templateData["plan"] = plan
+		templateData["file"] = string(file)
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateBlueprint, s.collectionLoader, "preview")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering blueprint template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// PostAdminBlueprintApply
+// migrates the table of the collection and writes the blueprint from the editor to the blueprints directory.
+// The blueprint is only saved if the migration succeeded, so that the blueprint and the table never disagree.
+func (s *Server) PostAdminBlueprintApply(w http.ResponseWriter, r *http.Request) {
+	form, err := s.decodeBlueprintForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkBlueprintName(form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var oldBlueprint *blueprint.Blueprint
+	if !form.New {
+		collection, err := s.collectionLoader.Get(form.CollectionName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get collection: %v", err), http.StatusInternalServerError)
+			return
+		}
+		oldBlueprint = collection.Blueprint
+	}
+	bp, err := form.blueprint()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	parsed, problems := s.collectionLoader.Check(bp)
+	problems = append(problems, s.computedFields.Problems(bp, s.collectionLoader.File(bp.CollectionName))...)
+	if len(problems) > 0 {
+		http.Error(w, fmt.Sprintf("invalid blueprint:\n%v", problems), http.StatusUnprocessableEntity)
+		return
+	}
+	err = s.config.DatabaseInstance.CreateTable(&blueprint.Collection{Blueprint: parsed}, s.collectionLoader)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not migrate table, the blueprint has not been saved: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.collectionLoader.Save(bp)
+	if err != nil {
+		var problems blueprint.Problems
+		if errors.As(err, &problems) {
+			http.Error(w, fmt.Sprintf("invalid blueprint:\n%v", problems), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, fmt.Sprintf("could not save blueprint: %v", err), http.StatusInternalServerError)
+		return
+	}
+	collection, err := s.collectionLoader.Get(bp.CollectionName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get collection: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.auditLog.Add(r.Context(), audit.Record{
+		Action:     audit.ActionBlueprintSave,
+		Collection: bp.CollectionName,
+		Target:     bp.CollectionName,
+		Diff:       audit.BlueprintDiff(oldBlueprint, collection.Blueprint),
+	})
+	s.warnMissingPreviewSecret([]blueprint.Collection{*collection})
+	w.Header().Set("HX-Redirect", admin.BlueprintPath(bp.CollectionName))
+}
+
+func (s *Server) decodeBlueprintForm(r *http.Request) (*blueprintForm, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse form: %v", err)
+	}
+	var form blueprintForm
+	err = s.schemaDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode form: %v", err)
+	}
+	return &form, nil
+}
+
+// checkBlueprintName
+// prevents that a new collection overwrites an existing one and that an existing collection is renamed
+func (s *Server) checkBlueprintName(form *blueprintForm) error {
+	names, err := s.collectionLoader.Names()
+	if err != nil {
+		return fmt.Errorf("could not get collection names: %v", err)
+	}
+	exists := slices.Contains(names, form.CollectionName)
+	if form.New && exists {
+		return fmt.Errorf("collection %s already exists", form.CollectionName)
+	}
+	if !form.New && !exists {
+		return fmt.Errorf("collection %s does not exist", form.CollectionName)
+	}
+	return nil
+}
+
+// blueprint
+// returns the blueprint that has been entered in the editor. It has not been validated yet,
+// an error is only returned if the conditions are not valid JSON.
+func (f *blueprintForm) blueprint() (*blueprint.Blueprint, error) {
+	bp := &blueprint.Blueprint{
+		CollectionName:        f.CollectionName,
+		CollectionDisplayName: formLabel(f.DisplayNames),
+		Singleton:             f.Singleton,
+		LocalizedTitle:        f.LocalizedTitle,
+		PreviewURL:            strings.TrimSpace(f.PreviewURL),
+	}
+	if bp.CollectionDisplayName == nil {
+		bp.CollectionDisplayName = blueprint.NewLabel(f.CollectionName)
+	}
+	if f.Feed {
+		bp.Feed = &blueprint.BlueprintFeed{Title: f.FeedTitle, Summary: f.FeedSummary, Date: f.FeedDate, Author: f.FeedAuthor}
+	}
+	for _, fieldForm := range f.Fields {
+		if fieldForm.Name == "" && fieldForm.Type == "" {
+			// Gap in the indices of the form
+			continue
+		}
+		field := blueprint.BlueprintField{
+			Name:        fieldForm.Name,
+			DisplayName: formLabel(fieldForm.DisplayNames),
+			Type:        blueprint.Type(fieldForm.Type),
+			Required:    fieldForm.Required,
+			Hidden:      fieldForm.Hidden,
+			Localized:   fieldForm.Localized && blueprint.Type(fieldForm.Type).IsLocalizable(),
+		}
+		if field.DisplayName == nil {
+			field.DisplayName = blueprint.NewLabel(fieldForm.Name)
+		}
+		if fieldForm.ComputedExpression != "" || fieldForm.ComputedFunction != "" {
+			field.Computed = &blueprint.BlueprintComputed{Expression: fieldForm.ComputedExpression, Function: fieldForm.ComputedFunction}
+		}
+		var err error
+		field.VisibleIf, err = formConditions(fieldForm.VisibleIf)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid visible if conditions: %v", fieldForm.Name, err)
+		}
+		field.RequiredIf, err = formConditions(fieldForm.RequiredIf)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid required if conditions: %v", fieldForm.Name, err)
+		}
+		switch field.Type {
+		case blueprint.TypeReference:
+			field.Reference = blueprint.BlueprintReference{Collection: fieldForm.ReferenceCollection, MaxReferences: fieldForm.MaxReferences}
+		case blueprint.TypeSlug:
+			field.Slug = blueprint.BlueprintSlug{Source: fieldForm.SlugSource, LockAfterPublish: fieldForm.LockAfterPublish}
+		case blueprint.TypeObject:
+			field.Component = fieldForm.Component
+		case blueprint.TypeArray:
+			field.Component = fieldForm.Component
+			if len(fieldForm.Components) > 0 {
+				field.Components = fieldForm.Components
+			}
+		}
+		bp.Fields = append(bp.Fields, field)
+	}
+	return bp, nil
+}
+
+// formConditions
+// decodes a JSON list of conditions. Empty values mean no conditions.
+func formConditions(value string) ([]blueprint.BlueprintCondition, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var conditions []blueprint.BlueprintCondition
+	err := json.Unmarshal([]byte(value), &conditions)
+	if err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+// formLabel
+// returns a plain label if all locales have the same value and nil if no value has been entered
+func formLabel(values []labelForm) blueprint.Label {
+	label := blueprint.Label{}
+	for _, value := range values {
+		if value.Value != "" {
+			label[value.Locale] = value.Value
+		}
+	}
+	if len(label) == 0 {
+		return nil
+	}
+	var first string
+	for _, value := range label {
+		first = value
+		break
+	}
+	for _, value := range label {
+		if value != first {
+			return label
+		}
+	}
+	return blueprint.NewLabel(first)
+}
+
+// labelValues
+// returns the values of the label for the locales of the admin interface and for all other locales of the label
+func labelValues(label blueprint.Label, locales []string) []labelForm {
+	var values []labelForm
+	for _, locale := range locales {
+		value, ok := label[locale]
+		if !ok {
+			// Plain labels are used for all locales
+			value = label[""]
+		}
+		values = append(values, labelForm{Locale: locale, Value: value})
+	}
+	var others []string
+	for locale := range label {
+		if locale != "" && !slices.Contains(locales, locale) {
+			others = append(others, locale)
+		}
+	}
+	sort.Strings(others)
+	for _, locale := range others {
+		values = append(values, labelForm{Locale: locale, Value: label[locale]})
+	}
+	return values
+}
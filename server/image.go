@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/imaging"
+)
+
+type getAssetImageQueryParams struct {
+	Preset  string `schema:"preset"`
+	Width   int    `schema:"w"`
+	Height  int    `schema:"h"`
+	Fit     string `schema:"fit"`
+	Format  string `schema:"format"`
+	Quality int    `schema:"q"`
+}
+
+type putAdminAssetFocalPointFormParams struct {
+	FocalX float64 `schema:"focal_x,required" validate:"gte=0,lte=1"`
+	FocalY float64 `schema:"focal_y,required" validate:"gte=0,lte=1"`
+}
+
+// GetAssetImage
+// serves a resized and converted variant of an image asset.
+// Only variants that match one of the configured presets can be requested.
+func (s *Server) GetAssetImage(w http.ResponseWriter, r *http.Request) {
+	// Read query parameters
+	var queryParams getAssetImageQueryParams
+	err := s.schemaDecoder.Decode(&queryParams, r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not decode query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+	var options imaging.Options
+	if queryParams.Preset != "" {
+		var ok bool
+		options, ok = s.imagePresets[queryParams.Preset]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown preset %s", queryParams.Preset), http.StatusBadRequest)
+			return
+		}
+	} else {
+		options = imaging.Options{
+			Width:   queryParams.Width,
+			Height:  queryParams.Height,
+			Fit:     queryParams.Fit,
+			Format:  queryParams.Format,
+			Quality: queryParams.Quality,
+		}.Normalize()
+		if !s.imagePresets.Match(options) {
+			http.Error(w, "requested image options do not match any preset", http.StatusBadRequest)
+			return
+		}
+	}
+	// Get asset
+	a, err := s.config.DatabaseInstance.GetAsset(chi.URLParam(r, "uuid"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("could not get asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !a.IsImage() {
+		http.Error(w, "asset is not an image", http.StatusBadRequest)
+		return
+	}
+	options.FocalX = a.FocalX
+	options.FocalY = a.FocalY
+	filename, err := s.imageCache.Filename(a.UUID, options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Generate variant if it is not cached yet
+	if _, err = os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		err = s.generateImage(a.StorageKey, filename, options)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not generate image: %v", err), http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("could not check image cache: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", options.MimeType())
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// The URL of a variant does not change when the focal point is moved, so it may only be cached for a limited time
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFile(w, r, filename)
+}
+
+func (s *Server) generateImage(storageKey string, filename string, options imaging.Options) error {
+	source, err := s.config.AssetStorage.Get(storageKey)
+	if err != nil {
+		return fmt.Errorf("could not get asset file: %v", err)
+	}
+	defer source.Close()
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return fmt.Errorf("could not read asset file: %v", err)
+	}
+	file, err := s.imageCache.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create cache file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	err = imaging.Process(bytes.NewReader(data), file, options)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return s.imageCache.Commit(file, filename)
+}
+
+func (s *Server) PutAdminAssetFocalPoint(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not parse form: %v", err), http.StatusBadRequest)
+		return
+	}
+	var formParams putAdminAssetFocalPointFormParams
+	err = s.schemaDecoder.Decode(&formParams, r.PostForm)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not decode form: %v", err), http.StatusBadRequest)
+		return
+	}
+	err = s.config.Validate.Struct(&formParams)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+		return
+	}
+	uuid := chi.URLParam(r, "uuid")
+	err = s.config.DatabaseInstance.UpdateAssetFocalPoint(uuid, formParams.FocalX, formParams.FocalY)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not update asset: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// Variants for the old focal point are not needed anymore
+	err = s.imageCache.Purge(uuid)
+	if err != nil {
+		s.config.Logger.Error("Could not purge image cache", "uuid", uuid, "error", err)
+	}
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/compute"
+	"github.com/rangidev/rangi/database"
+	"github.com/rangidev/rangi/hook"
+)
+
+var (
+	errorSingletonExists = hook.NewValidationError("", "the collection is a singleton and already has an item")
+	errorSingletonDelete = hook.NewValidationError("", "the item of a singleton collection can not be deleted")
+)
+
+// Hooks
+// returns the registry for hooks that run before and after items are written.
+// Register hooks before calling Start.
+func (s *Server) Hooks() *hook.Registry {
+	return s.hooks
+}
+
+// ComputedFields
+// returns the registry for the Go functions of computed fields.
+// Register functions before calling Start.
+func (s *Server) ComputedFields() *compute.Registry {
+	return s.computedFields
+}
+
+// computedFunctionProblems
+// reports computed fields of the collections whose function has not been registered
+func (s *Server) computedFunctionProblems(collections []blueprint.Collection) blueprint.Problems {
+	var problems blueprint.Problems
+	for _, collection := range collections {
+		problems = append(problems, s.computedFields.Problems(collection.Blueprint, s.collectionLoader.File(collection.Blueprint.CollectionName))...)
+	}
+	return problems
+}
+
+// createItem
+// runs the hooks around storing a new item
+func (s *Server) createItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error {
+	// Inside an import, items are written in the transaction of the row
+	db := database.FromContext(ctx, s.config.DatabaseInstance)
+	event := &hook.Event{Operation: hook.OperationCreate, Collection: collection, Item: item}
+	err := s.hooks.RunBefore(ctx, event)
+	if err != nil {
+		return err
+	}
+	err = validateItem(collection, event.Item, nil)
+	if err != nil {
+		return err
+	}
+	err = s.markdownRenderer.RenderItem(collection.Blueprint, event.Item)
+	if err != nil {
+		return fmt.Errorf("could not render markdown: %v", err)
+	}
+	computed, err := s.computedFields.Compute(collection.Blueprint, event.Item)
+	if err != nil {
+		return err
+	}
+	maps.Copy(event.Item, computed)
+	err = db.Transaction(func(tx *database.DB) error {
+		err := tx.CreateItem(collection, event.Item)
+		if errors.Is(err, database.ErrorSingletonExists) {
+			return errorSingletonExists
+		}
+		if err != nil {
+			return fmt.Errorf("could not set item in database: %v", err)
+		}
+		txCtx := database.ContextWithTransaction(ctx, tx)
+		s.auditLog.Add(txCtx, audit.Record{
+			Action:     audit.ActionItemCreate,
+			Collection: collection.Blueprint.CollectionName,
+			Target:     fmt.Sprintf("%v", event.Item[blueprint.KeyID]),
+			Diff:       audit.ItemDiff(collection.Blueprint, nil, event.Item),
+		})
+		return s.enqueueWebhooks(txCtx, database.EventItemCreated, collection, event.Item)
+	})
+	if err != nil {
+		return err
+	}
+	s.hooks.RunAfter(ctx, event)
+	return nil
+}
+
+// updateItem
+// runs the hooks around updating an item. Localized fields are stored as translations if locale is not the default locale.
+func (s *Server) updateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item, locale string) error {
+	db := database.FromContext(ctx, s.config.DatabaseInstance)
+	event := &hook.Event{Operation: hook.OperationUpdate, Collection: collection, Item: item}
+	if !s.config.Locales.IsDefault(locale) {
+		event.Locale = locale
+	}
+	err := s.hooks.RunBefore(ctx, event)
+	if err != nil {
+		return err
+	}
+	item = event.Item
+	if _, ok := item[blueprint.KeyID]; !ok {
+		return errors.New("no id in item")
+	}
+	id := fmt.Sprintf("%v", item[blueprint.KeyID])
+	// Previous values for the conditions of fields and the audit log
+	existingItem, err := db.GetItem(collection, blueprint.KeyID, id)
+	if err != nil {
+		return fmt.Errorf("could not get existing item: %v", err)
+	}
+	checked := item
+	if event.Locale != "" {
+		// Empty translations fall back to the values of the default locale
+		checked = maps.Clone(item)
+		for _, field := range collection.Blueprint.LocalizedFields() {
+			if blueprint.IsEmptyValue(checked[field.Name]) {
+				delete(checked, field.Name)
+			}
+		}
+	}
+	err = validateItem(collection, checked, existingItem)
+	if err != nil {
+		return err
+	}
+	err = s.markdownRenderer.RenderItem(collection.Blueprint, item)
+	if err != nil {
+		return fmt.Errorf("could not render markdown: %v", err)
+	}
+	// Computed fields are not localized, they are computed from all values of the item in the default locale
+	values := maps.Clone(existingItem)
+	maps.Copy(values, item)
+	if event.Locale != "" {
+		for _, field := range collection.Blueprint.LocalizedFields() {
+			values[field.Name] = existingItem[field.Name]
+		}
+	}
+	computed, err := s.computedFields.Compute(collection.Blueprint, values)
+	if err != nil {
+		return err
+	}
+	maps.Copy(item, computed)
+	target := id
+	if event.Locale != "" {
+		translations, err := db.GetTranslations(collection, id, event.Locale)
+		if err != nil {
+			return fmt.Errorf("could not get existing translations: %v", err)
+		}
+		for _, field := range collection.Blueprint.LocalizedFields() {
+			existingItem[field.Name] = translations[field.Name]
+		}
+		target = fmt.Sprintf("%s (%s)", id, event.Locale)
+	}
+	// Localized fields are removed from item below
+	written := maps.Clone(item)
+	// Translations, the item, the audit log and webhook deliveries are written together, so that a failed write leaves no changes
+	err = db.Transaction(func(tx *database.DB) error {
+		if event.Locale != "" {
+			// Localized fields are stored as translations, the values of the default locale are kept
+			translations := blueprint.Item{}
+			for _, field := range collection.Blueprint.LocalizedFields() {
+				if value, ok := item[field.Name]; ok {
+					translations[field.Name] = value
+					delete(item, field.Name)
+				}
+			}
+			err := tx.SetTranslations(collection, id, event.Locale, translations)
+			if err != nil {
+				return fmt.Errorf("could not set translations in database: %v", err)
+			}
+		}
+		err := tx.UpdateItem(collection, item)
+		if err != nil {
+			return fmt.Errorf("could not set item in database: %v", err)
+		}
+		// Values set by the database, e. g. slugs
+		maps.Copy(written, item)
+		txCtx := database.ContextWithTransaction(ctx, tx)
+		s.auditLog.Add(txCtx, audit.Record{
+			Action:     audit.ActionItemUpdate,
+			Collection: collection.Blueprint.CollectionName,
+			Target:     target,
+			Diff:       audit.ItemDiff(collection.Blueprint, existingItem, written),
+		})
+		return s.enqueueWebhooks(txCtx, database.EventItemUpdated, collection, written)
+	})
+	if err != nil {
+		return err
+	}
+	event.Item = written
+	s.hooks.RunAfter(ctx, event)
+	return nil
+}
+
+// publishItem
+// runs the hooks around publishing an item
+func (s *Server) publishItem(ctx context.Context, collection *blueprint.Collection, id string) error {
+	db := database.FromContext(ctx, s.config.DatabaseInstance)
+	item, err := db.GetItem(collection, blueprint.KeyID, id)
+	if err != nil {
+		return fmt.Errorf("could not get item: %v", err)
+	}
+	event := &hook.Event{Operation: hook.OperationPublish, Collection: collection, Item: item}
+	err = s.hooks.RunBefore(ctx, event)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func(tx *database.DB) error {
+		err := tx.PublishItem(collection, id)
+		if err != nil {
+			return fmt.Errorf("could not publish item: %v", err)
+		}
+		event.Item, err = tx.GetItem(collection, blueprint.KeyID, id)
+		if err != nil {
+			return fmt.Errorf("could not get published item: %v", err)
+		}
+		txCtx := database.ContextWithTransaction(ctx, tx)
+		s.auditLog.Add(txCtx, audit.Record{
+			Action:     audit.ActionItemPublish,
+			Collection: collection.Blueprint.CollectionName,
+			Target:     id,
+			Diff:       audit.ItemDiff(collection.Blueprint, item, event.Item),
+		})
+		return s.enqueueWebhooks(txCtx, database.EventItemPublished, collection, event.Item)
+	})
+	if err != nil {
+		return err
+	}
+	s.hooks.RunAfter(ctx, event)
+	return nil
+}
+
+// deleteItem
+// runs the hooks around deleting an item
+func (s *Server) deleteItem(ctx context.Context, collection *blueprint.Collection, id string) error {
+	if collection.Blueprint.Singleton {
+		return errorSingletonDelete
+	}
+	db := database.FromContext(ctx, s.config.DatabaseInstance)
+	item, err := db.GetItem(collection, blueprint.KeyID, id)
+	if err != nil {
+		return fmt.Errorf("could not get item: %v", err)
+	}
+	event := &hook.Event{Operation: hook.OperationDelete, Collection: collection, Item: item}
+	err = s.hooks.RunBefore(ctx, event)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func(tx *database.DB) error {
+		err := tx.DeleteItem(collection, id)
+		if err != nil {
+			return fmt.Errorf("could not delete item: %v", err)
+		}
+		txCtx := database.ContextWithTransaction(ctx, tx)
+		s.auditLog.Add(txCtx, audit.Record{
+			Action:     audit.ActionItemDelete,
+			Collection: collection.Blueprint.CollectionName,
+			Target:     id,
+			Diff:       audit.ItemDiff(collection.Blueprint, item, nil),
+		})
+		return s.enqueueWebhooks(txCtx, database.EventItemDeleted, collection, item)
+	})
+	if err != nil {
+		return err
+	}
+	s.hooks.RunAfter(ctx, event)
+	return nil
+}
+
+// enqueueWebhooks
+// queues the webhook deliveries of a change in the transaction of the context
+func (s *Server) enqueueWebhooks(ctx context.Context, eventType database.EventType, collection *blueprint.Collection, item blueprint.Item) error {
+	err := s.webhookDispatcher.Enqueue(ctx, database.Event{
+		Type:       eventType,
+		Collection: collection.Blueprint.CollectionName,
+		Item:       item,
+		Time:       time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not enqueue webhooks: %v", err)
+	}
+	return nil
+}
+
+// itemErrorStatus
+// returns the HTTP status code for errors of item operations
+func itemErrorStatus(err error) int {
+	var validationError *hook.ValidationError
+	if errors.As(err, &validationError) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+)
+
+// Migrate
+// creates the tables of all collections and of the system and adds columns for fields that have been added to blueprints.
+// It is safe to run multiple times and returns the migrated collections.
+func Migrate(config *config.Config) ([]blueprint.Collection, error) {
+	collectionLoader := blueprint.NewCollectionLoader(config.BlueprintsPath)
+	problems := collectionLoader.Validate()
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid blueprints:\n%v", problems)
+	}
+	collections, err := collectionLoader.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections: %v", err)
+	}
+	err = config.DatabaseInstance.CreateTables(collections, collectionLoader)
+	if err != nil {
+		return nil, fmt.Errorf("could not create tables: %v", err)
+	}
+	err = config.DatabaseInstance.CreateAssetTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create asset table: %v", err)
+	}
+	err = config.DatabaseInstance.CreateTranslationTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create translation table: %v", err)
+	}
+	err = config.DatabaseInstance.CreateWebhookDeliveryTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create webhook delivery table: %v", err)
+	}
+	err = config.DatabaseInstance.CreateUserTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create user table: %v", err)
+	}
+	err = config.DatabaseInstance.CreateAuditLogTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not create audit log table: %v", err)
+	}
+	return collections, nil
+}
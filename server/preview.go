@@ -0,0 +1,118 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/preview"
+)
+
+const (
+	// Public endpoint that returns the item of a preview token, for frontends that fetch content themselves
+	PreviewPath = "/api/preview"
+)
+
+// warnMissingPreviewSecret
+// logs a warning if a collection has a preview URL, but preview tokens are signed with a random secret
+func (s *Server) warnMissingPreviewSecret(collections []blueprint.Collection) {
+	if s.config.PreviewSecret != "" {
+		return
+	}
+	for _, collection := range collections {
+		if collection.Blueprint.PreviewURL != "" {
+			s.config.Logger.Warn("Collection has a preview URL, but RANGI_PREVIEW_SECRET is not set. Preview links become invalid on restart and are not accepted by other instances.", "collection", collection.Blueprint.CollectionName)
+			return
+		}
+	}
+}
+
+// GetAdminPreview
+// creates a preview token for the item and redirects to the preview URL of its collection
+func (s *Server) GetAdminPreview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "missing 'id' path parameter", http.StatusBadRequest)
+		return
+	}
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if collectionData.Blueprint.PreviewURL == "" {
+		http.Error(w, fmt.Sprintf("collection %s has no preview URL", collectionData.Blueprint.CollectionName), http.StatusBadRequest)
+		return
+	}
+	item, err := s.config.DatabaseInstance.GetItem(collectionData, blueprint.KeyID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	token, _, err := s.config.PreviewSigner.Token(collectionData.Blueprint.CollectionName, fmt.Sprintf("%v", item[blueprint.KeyUUID]))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not create preview token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, collectionData.Blueprint.ExpandPreviewURL(item, token), http.StatusFound)
+}
+
+// GetPreview
+// returns the item of a preview token as JSON, with the same values as the export, even if it has not been published.
+// Query parameters: preview (the token), locale (optional).
+func (s *Server) GetPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	claims, err := s.config.PreviewSigner.Verify(r.URL.Query().Get(preview.QueryParam))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	locale, err := s.config.Locales.Parse(r.URL.Query().Get("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	collectionData, err := s.collectionLoader.Get(claims.Collection)
+	if err != nil {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+	item, err := s.config.DatabaseInstance.GetItem(collectionData, blueprint.KeyUUID, claims.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.config.DatabaseInstance.LocalizeItems(collectionData, []blueprint.Item{item}, s.config.Locales.Chain(locale), s.config.Locales.Default())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not localize item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	row, err := s.Exporter().Row(collectionData, item)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not export item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(map[string]any{
+		"collection": collectionData.Blueprint.CollectionName,
+		"item":       row,
+	})
+	if err != nil {
+		s.config.Logger.Error("Could not encode item", "collection", collectionData.Blueprint.CollectionName, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/hook"
+	"github.com/rangidev/rangi/jsonschema"
+)
+
+// GetBlueprintSchema
+// returns the JSON Schema of blueprint files. It is served without access check, so that editors can download it.
+func (s *Server) GetBlueprintSchema(w http.ResponseWriter, r *http.Request) {
+	writeSchema(w, jsonschema.Blueprint())
+}
+
+// GetComponentSchema
+// returns the JSON Schema of component files. It is served without access check like GetBlueprintSchema.
+func (s *Server) GetComponentSchema(w http.ResponseWriter, r *http.Request) {
+	writeSchema(w, jsonschema.Component())
+}
+
+// GetAdminCollectionSchema
+// returns the JSON Schema of the items of a collection
+func (s *Server) GetAdminCollectionSchema(w http.ResponseWriter, r *http.Request) {
+	collection, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	catalogs := s.adminTemplates.Catalogs()
+	writeSchema(w, jsonschema.Collection(collection, catalogs.Locale(r), catalogs.Translator()))
+}
+
+func writeSchema(w http.ResponseWriter, schema *jsonschema.Schema) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(schema)
+}
+
+// validateItem
+// checks the values of the item against the JSON Schema of the collection and the requirements of the fields.
+// Only values in the item are checked against the schema, values that are set by Rangi are skipped.
+// existingItem is the stored item for updates and nil for new items. Requirements are checked against the stored values
+// with the written values applied, so that required fields can not be emptied, and missing values count as empty.
+func validateItem(collection *blueprint.Collection, item blueprint.Item, existingItem blueprint.Item) error {
+	schema := jsonschema.Collection(collection, "", nil)
+	for _, field := range collection.Blueprint.Fields {
+		value, ok := item[field.Name]
+		if !ok || schema.Properties[field.Name].ReadOnly {
+			continue
+		}
+		if field.Type == blueprint.TypeArray || field.Type == blueprint.TypeObject {
+			// Arrays and objects are stored as JSON text
+			if s, ok := value.(string); ok {
+				err := json.Unmarshal([]byte(s), &value)
+				if err != nil {
+					return hook.NewValidationError(field.Name, fmt.Sprintf("invalid JSON: %v", err))
+				}
+			}
+		}
+		errors := schema.Properties[field.Name].Validate(value)
+		if len(errors) > 0 {
+			return hook.NewValidationError(field.Name, errors[0].Error())
+		}
+	}
+	values := maps.Clone(existingItem)
+	if values == nil {
+		values = blueprint.Item{}
+	}
+	maps.Copy(values, item)
+	if names := collection.Blueprint.MissingRequiredValues(values); len(names) > 0 {
+		return hook.NewValidationError(names[0], "value is required")
+	}
+	return nil
+}
+
+// parseFormValue
+// converts a submitted form value into the type of the field. Values that can not be converted are returned unchanged,
+// so that validateItem reports them.
+func parseFormValue(field blueprint.BlueprintField, value string) any {
+	switch field.Type {
+	case blueprint.TypeID, blueprint.TypeInt:
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return value
+		}
+		return i
+	case blueprint.TypeBoolean:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "":
+			return nil
+		case "true", "on", "1":
+			return true
+		case "false", "off", "0":
+			return false
+		}
+		return value
+	case blueprint.TypeArray, blueprint.TypeObject:
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		return value
+	}
+	return value
+}
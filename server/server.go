@@ -10,8 +10,16 @@ import (
 	"github.com/gorilla/schema"
 
 	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/asset"
+	"github.com/rangidev/rangi/audit"
 	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/compute"
 	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/hook"
+	"github.com/rangidev/rangi/imaging"
+	"github.com/rangidev/rangi/markdown"
+	"github.com/rangidev/rangi/site"
+	"github.com/rangidev/rangi/webhook"
 )
 
 type Server struct {
@@ -21,6 +29,14 @@ type Server struct {
 	adminTemplates    *admin.Templates
 	adminStaticServer http.Handler
 	collectionLoader  *blueprint.CollectionLoader
+	markdownRenderer  *markdown.Renderer
+	imageCache        *imaging.Cache
+	imagePresets      imaging.Presets
+	webhookDispatcher *webhook.Dispatcher
+	hooks             *hook.Registry
+	computedFields    *compute.Registry
+	auditLog          *audit.Log
+	site              *site.Site // nil if no theme has been configured
 }
 
 func New(config *config.Config) (*Server, error) {
@@ -37,39 +53,93 @@ func New(config *config.Config) (*Server, error) {
 	}
 	// Collections
 	collectionLoader := blueprint.NewCollectionLoader(config.BlueprintsPath)
-	collections, err := collectionLoader.GetAll()
+	// Create tables
+	_, err = Migrate(config)
 	if err != nil {
-		return nil, fmt.Errorf("could not get collections: %v", err)
+		return nil, err
 	}
-	// Create tables
-	err = config.DatabaseInstance.CreateTables(collections, collectionLoader)
+	// Images
+	imageCache, err := imaging.NewCache(config.ImageCachePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not create image cache: %v", err)
+	}
+	imagePresets, err := imaging.LoadPresets(config.ImagePresetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load image presets: %v", err)
+	}
+	// Webhooks
+	webhooks, err := webhook.Load(config.WebhooksFile)
 	if err != nil {
-		return nil, fmt.Errorf("could not create tables: %v", err)
+		return nil, fmt.Errorf("could not load webhooks: %v", err)
 	}
-	return &Server{
+	webhookDispatcher := webhook.NewDispatcher(config.DatabaseInstance, webhooks, config.WebhookMaxAttempts, config.Logger)
+	config.DatabaseInstance.AddListener(webhookDispatcher.Wake)
+	s := &Server{
 		config:            config,
 		schemaDecoder:     schemaDecoder,
 		adminTemplates:    adminTemplates,
 		adminStaticServer: adminStaticServer,
 		collectionLoader:  collectionLoader,
-	}, nil
+		markdownRenderer:  markdown.NewRenderer(config.MarkdownAllowedTags),
+		imageCache:        imageCache,
+		imagePresets:      imagePresets,
+		webhookDispatcher: webhookDispatcher,
+		hooks:             hook.NewRegistry(),
+		computedFields:    compute.NewRegistry(),
+		auditLog:          audit.New(config.DatabaseInstance, config.Logger),
+	}
+	// Public site
+	if config.Theme != "" {
+		s.site, err = site.New(config, collectionLoader)
+		if err != nil {
+			return nil, fmt.Errorf("could not create site: %v", err)
+		}
+	}
+	// Users
+	err = s.createInitialUser()
+	if err != nil {
+		return nil, fmt.Errorf("could not create initial user: %v", err)
+	}
+	return s, nil
 }
 
 func (s *Server) Start() error {
+	// Functions of computed fields are registered after New
+	collections, err := s.collectionLoader.GetAll()
+	if err != nil {
+		return fmt.Errorf("could not get collections: %v", err)
+	}
+	problems := s.computedFunctionProblems(collections)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid blueprints:\n%v", problems)
+	}
+	s.warnMissingPreviewSecret(collections)
 	// Create router
 	router := chi.NewRouter()
 	router.Use(middleware.Recoverer)
 	// Add Admin
 	// Static admin files without access check
 	router.Get("/admin/static/*", s.GetAdminStatic)
+	router.Get("/admin/schema/blueprint.json", s.GetBlueprintSchema)
+	router.Get("/admin/schema/component.json", s.GetComponentSchema)
 	router.Mount("/admin", createAdminRouter(s))
+	router.Get(PreviewPath, s.GetPreview) // For possible query parameters see GetPreview
+	// Assets
+	router.Get(asset.PublicPathPrefix+"{uuid}", s.GetAsset)
+	router.Get(asset.PublicPathPrefix+"{uuid}/image", s.GetAssetImage) // For possible query parameters see getAssetImageQueryParams
+	// Public site, for all paths that are not used by Rangi itself
+	if s.site != nil {
+		router.Mount("/", s.site)
+	}
+	// Send queued webhook deliveries in the background
+	s.webhookDispatcher.Start()
 	// Create server
 	s.server = &http.Server{
 		Addr:    s.config.HostAndPort,
 		Handler: router,
 	}
 	// Start server
-	err := s.server.ListenAndServe()
+	err = s.server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		return err
 	}
@@ -77,5 +147,15 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		return err
+	}
+	return s.webhookDispatcher.Stop(ctx)
+}
+
+// Site
+// returns the public site, or nil if no theme has been configured
+func (s *Server) Site() *site.Site {
+	return s.site
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+// GetAdminSingleton
+// returns the item of a singleton collection as JSON, with the same values as the export
+func (s *Server) GetAdminSingleton(w http.ResponseWriter, r *http.Request) {
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !collectionData.Blueprint.Singleton {
+		http.Error(w, fmt.Sprintf("collection %s is not a singleton", collectionData.Blueprint.CollectionName), http.StatusBadRequest)
+		return
+	}
+	locale, err := s.config.Locales.Parse(r.URL.Query().Get("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item, err := s.getOrCreateSingleton(r.Context(), collectionData)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = s.config.DatabaseInstance.LocalizeItems(collectionData, []blueprint.Item{item}, s.config.Locales.Chain(locale), s.config.Locales.Default())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not localize item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	row, err := s.Exporter().Row(collectionData, item)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not export item: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(row)
+	if err != nil {
+		s.config.Logger.Error("Could not encode item", "collection", collectionData.Blueprint.CollectionName, "error", err)
+	}
+}
+
+// getOrCreateSingleton
+// returns the item of a singleton collection and creates it with the values of blueprint.NewSingletonItem on first access.
+// The new item is not validated and hooks are not run, because its required fields are empty until it is edited.
+func (s *Server) getOrCreateSingleton(ctx context.Context, collection *blueprint.Collection) (blueprint.Item, error) {
+	var item blueprint.Item
+	err := s.config.DatabaseInstance.Transaction(func(tx *database.DB) error {
+		var err error
+		item, err = tx.GetSingleton(collection)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		newItem, err := blueprint.NewSingletonItem(collection)
+		if err != nil {
+			return fmt.Errorf("could not create new item: %v", err)
+		}
+		err = s.markdownRenderer.RenderItem(collection.Blueprint, newItem)
+		if err != nil {
+			return fmt.Errorf("could not render markdown: %v", err)
+		}
+		computed, err := s.computedFields.Compute(collection.Blueprint, newItem)
+		if err != nil {
+			return err
+		}
+		maps.Copy(newItem, computed)
+		err = tx.CreateItem(collection, newItem)
+		if err != nil {
+			return fmt.Errorf("could not set item in database: %v", err)
+		}
+		txCtx := database.ContextWithTransaction(ctx, tx)
+		s.auditLog.Add(txCtx, audit.Record{
+			Action:     audit.ActionItemCreate,
+			Collection: collection.Blueprint.CollectionName,
+			Target:     fmt.Sprintf("%v", newItem[blueprint.KeyID]),
+			Diff:       audit.ItemDiff(collection.Blueprint, nil, newItem),
+		})
+		// Read the item back to get the values that are set by the database
+		item, err = tx.GetSingleton(collection)
+		if err != nil {
+			return err
+		}
+		return s.enqueueWebhooks(txCtx, database.EventItemCreated, collection, item)
+	})
+	if err != nil {
+		// Another request may have created the item at the same time
+		existing, getErr := s.config.DatabaseInstance.GetSingleton(collection)
+		if getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return item, nil
+}
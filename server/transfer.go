@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/transfer"
+)
+
+const (
+	importFormKey = "file"
+)
+
+type getExportQueryParams struct {
+	Format string `schema:"format,required" validate:"oneof=jsonl csv"`
+}
+
+// itemWriter
+// writes imported items like items that are saved in the admin interface
+type itemWriter struct {
+	s *Server
+}
+
+func (w itemWriter) CreateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error {
+	return w.s.createItem(ctx, collection, item)
+}
+
+func (w itemWriter) UpdateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error {
+	return w.s.updateItem(ctx, collection, item, w.s.config.Locales.Default())
+}
+
+// Importer
+// returns the importer for collection items. Imported items are written with hooks and recorded in the audit log.
+func (s *Server) Importer() *transfer.Importer {
+	return transfer.NewImporter(s.config.DatabaseInstance, s.collectionLoader, itemWriter{s: s})
+}
+
+// Exporter
+// returns the exporter for collection items
+func (s *Server) Exporter() *transfer.Exporter {
+	return transfer.NewExporter(s.config.DatabaseInstance, s.collectionLoader)
+}
+
+func (s *Server) decodeExportFormat(r *http.Request) (transfer.Format, error) {
+	var queryParams getExportQueryParams
+	err := s.schemaDecoder.Decode(&queryParams, r.URL.Query())
+	if err != nil {
+		return "", fmt.Errorf("could not decode query parameters: %v", err)
+	}
+	err = s.config.Validate.Struct(&queryParams)
+	if err != nil {
+		return "", fmt.Errorf("invalid query parameters: %v", err)
+	}
+	return transfer.ParseFormat(queryParams.Format)
+}
+
+// GetAdminExport
+// downloads the items of all collections
+func (s *Server) GetAdminExport(w http.ResponseWriter, r *http.Request) {
+	format, err := s.decodeExportFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filename := fmt.Sprintf("rangi-export-%s.%s", time.Now().Format(time.DateOnly), format)
+	contentType := format.ContentType()
+	if format == transfer.FormatCSV {
+		filename = fmt.Sprintf("rangi-export-%s.zip", time.Now().Format(time.DateOnly))
+		contentType = "application/zip"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	err = s.Exporter().ExportAll(w, format)
+	if err != nil {
+		// The header has already been sent
+		s.config.Logger.Error("Could not export collections", "error", err)
+	}
+}
+
+// GetAdminCollectionExport
+// downloads the items of a collection
+func (s *Server) GetAdminCollectionExport(w http.ResponseWriter, r *http.Request) {
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	format, err := s.decodeExportFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filename := fmt.Sprintf("%s-%s.%s", collectionData.Blueprint.CollectionName, time.Now().Format(time.DateOnly), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	err = s.Exporter().Export(w, collectionData, format)
+	if err != nil {
+		// The header has already been sent
+		s.config.Logger.Error("Could not export collection", "collection", collectionData.Blueprint.CollectionName, "error", err)
+	}
+}
+
+// PostAdminCollectionImport
+// imports an uploaded file into the collection and renders the report
+func (s *Server) PostAdminCollectionImport(w http.ResponseWriter, r *http.Request) {
+	collectionData, err := s.getCollection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	file, header, err := r.FormFile(importFormKey)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not read file: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	format, err := transfer.ParseFormat(header.Filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options := transfer.Options{DryRun: r.PostFormValue("dry_run") == "true"}
+	report, err := s.Importer().Import(r.Context(), file, collectionData, format, options)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not import file: %v", err), http.StatusBadRequest)
+		return
+	}
+	templateData := admin.TemplateData{
+		"collection": collectionData.Blueprint.CollectionName,
+		"report":     report,
+	}
+	err = s.adminTemplates.Render(w, r, templateData, admin.TemplateCollection, s.collectionLoader, "import-report")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error while rendering collection template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/admin"
+	"github.com/rangidev/rangi/audit"
+	"github.com/rangidev/rangi/auth"
+	"github.com/rangidev/rangi/database"
+)
+
+func (s *Server) PostAdminLogin(w http.ResponseWriter, r *http.Request) {
+	// Read login data
+	email := r.PostFormValue("email")
+	if email == "" {
+		http.Error(w, "missing value 'email'", http.StatusBadRequest)
+		return
+	}
+	password := r.PostFormValue("password")
+	if password == "" {
+		http.Error(w, "missing value 'password'", http.StatusBadRequest)
+		return
+	}
+	actor, _ := audit.ActorFromContext(r.Context())
+	user, err := auth.Authenticate(s.config.DatabaseInstance, email, password)
+	if err != nil {
+		actor.Email = email
+		s.auditLog.Add(audit.WithActor(r.Context(), actor), audit.Record{Action: audit.ActionLoginFailed})
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.config.DatabaseInstance.CreateSession(token, user.ID, admin.SessionCookieDuration)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not create session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	actor.UserID = user.ID
+	actor.Email = user.Email
+	s.auditLog.Add(audit.WithActor(r.Context(), actor), audit.Record{Action: audit.ActionLogin})
+	c := &http.Cookie{
+		Name:     admin.SessionCookieName,
+		Value:    token,
+		HttpOnly: true,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/admin/",
+		Expires:  time.Now().Add(admin.SessionCookieDuration),
+	}
+	http.SetCookie(w, c)
+	w.Header().Set("HX-Redirect", admin.DashboardPath)
+}
+
+func (s *Server) PostAdminLogout(w http.ResponseWriter, r *http.Request) {
+	sessionCookie, err := r.Cookie(admin.SessionCookieName)
+	if err == nil {
+		err = s.config.DatabaseInstance.DeleteSession(sessionCookie.Value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not delete session: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	s.auditLog.Add(r.Context(), audit.Record{Action: audit.ActionLogout})
+	c := &http.Cookie{
+		Name:     admin.SessionCookieName,
+		Value:    "",
+		HttpOnly: true,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/admin/",
+		MaxAge:   -1,
+	}
+	http.SetCookie(w, c)
+	w.Header().Set("HX-Redirect", admin.LoginPath)
+}
+
+// isSecureRequest
+// returns true if the client uses HTTPS, either directly or through a trusted reverse proxy
+func (s *Server) isSecureRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return s.config.TrustProxyHeaders && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// PutAdminUserRole
+// changes the permissions of a user
+func (s *Server) PutAdminUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid 'id' path parameter", http.StatusBadRequest)
+		return
+	}
+	role := database.UserRole(r.PostFormValue("role"))
+	if !slices.Contains(database.AllUserRoles, role) {
+		http.Error(w, fmt.Sprintf("unknown role %s", role), http.StatusBadRequest)
+		return
+	}
+	user, err := s.config.DatabaseInstance.GetUser(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not get user: %v", err), http.StatusNotFound)
+		return
+	}
+	if user.Role == role {
+		return
+	}
+	if user.Role == database.UserRoleAdmin {
+		// Make sure that the settings stay accessible
+		users, err := s.config.DatabaseInstance.GetUsers()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not get users: %v", err), http.StatusInternalServerError)
+			return
+		}
+		admins := slices.DeleteFunc(users, func(u database.User) bool { return u.Role != database.UserRoleAdmin })
+		if len(admins) <= 1 {
+			http.Error(w, "the last admin can not be demoted", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	err = s.config.DatabaseInstance.UpdateUserRole(id, role)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not update role: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.auditLog.Add(r.Context(), audit.Record{
+		Action: audit.ActionPermissionChange,
+		Target: user.Email,
+		Diff:   audit.Diff{"role": {user.Role, role}},
+	})
+	w.Header().Set("HX-Redirect", admin.SettingsPath)
+}
+
+// createInitialUser
+// creates an admin user from the configuration if there are no users yet
+func (s *Server) createInitialUser() error {
+	count, err := s.config.DatabaseInstance.CountUsers()
+	if err != nil {
+		return fmt.Errorf("could not count users: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if s.config.AdminEmail == "" || s.config.AdminPassword == "" {
+		s.config.Logger.Warn("No users exist, set RANGI_ADMIN_EMAIL and RANGI_ADMIN_PASSWORD to create the first admin user")
+		return nil
+	}
+	user, err := auth.CreateUser(s.config.DatabaseInstance, s.config.AdminEmail, s.config.AdminPassword, database.UserRoleAdmin)
+	if err != nil {
+		return err
+	}
+	s.auditLog.Add(context.Background(), audit.Record{
+		Action: audit.ActionPermissionChange,
+		Target: user.Email,
+		Diff:   audit.Diff{"role": {nil, user.Role}},
+	})
+	s.config.Logger.Info("Created first admin user", "email", user.Email)
+	return nil
+}
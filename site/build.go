@@ -0,0 +1,502 @@
+package site
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rangidev/rangi/asset"
+	"github.com/rangidev/rangi/blueprint"
+)
+
+const (
+	// Written to the output directory, used to find out which pages have to be rendered again
+	ManifestFileName = "manifest.json"
+	// Number of assets that are read from the database at once
+	buildAssetsLimit = 200
+)
+
+// Manifest
+// describes a static build of the site
+type Manifest struct {
+	BuiltAt   int64                   `json:"built_at"`
+	ThemeHash string                  `json:"theme_hash"` // All pages are rendered again if the templates or routes change
+	Pages     map[string]ManifestPage `json:"pages"`      // By path
+	Assets    []string                `json:"assets"`     // UUIDs of the copied assets
+	Static    []string                `json:"static"`     // Copied static files of the theme, relative to the output directory
+}
+
+// ManifestPage
+// is a rendered page. The page is rendered again if its content or the content of the collections it depends on has been updated since.
+type ManifestPage struct {
+	File         string `json:"file"`                   // Relative to the output directory
+	UpdatedAt    int64  `json:"updated_at"`             // Latest updated_at of the item or the items of the page, 0 for pages without content
+	Items        int64  `json:"items,omitempty"`        // Number of published items, only for list pages
+	Dependencies string `json:"dependencies,omitempty"` // Hash of the published items of the other collections, which layouts and references can show
+}
+
+// collectionState
+// summarizes the published items of a collection, so that changes can be detected without reading all items
+type collectionState struct {
+	total  int64
+	latest int64 // Latest updated_at
+}
+
+// BuildResult
+// summarizes a static build
+type BuildResult struct {
+	Rendered []string `json:"rendered"` // Paths of the pages that have been rendered
+	Skipped  int      `json:"skipped"`  // Number of pages that have not changed since the last build
+	Removed  []string `json:"removed"`  // Paths of pages whose items have been deleted or unpublished
+	Assets   int      `json:"assets"`   // Number of copied assets
+}
+
+// buildPage
+// is a page that is part of the static build
+type buildPage struct {
+	path                  string
+	dependenciesUpdatedAt int64 // Latest updated_at of the collections the page depends on
+	ManifestPage
+}
+
+// Build
+// renders all pages into static HTML files in outputDir and copies the assets and the static files of the theme.
+// Pages and assets of a previous build are only written again if they have changed, unless full is set.
+// Images are copied in their original size, the query parameters of asset images can not be used on static sites.
+func (s *Site) Build(outputDir string, full bool) (*BuildResult, error) {
+	err := s.reload()
+	if err != nil {
+		return nil, err
+	}
+	themeHash, err := s.themeHash()
+	if err != nil {
+		return nil, err
+	}
+	// The previous manifest is also needed for full builds, to remove pages and assets that do not exist anymore
+	previous, err := readManifest(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	full = full || previous.ThemeHash != themeHash
+	pages, err := s.buildPages()
+	if err != nil {
+		return nil, err
+	}
+	result := &BuildResult{Rendered: []string{}, Removed: []string{}}
+	manifest := Manifest{
+		BuiltAt:   time.Now().Unix(),
+		ThemeHash: themeHash,
+		Pages:     make(map[string]ManifestPage),
+	}
+	for _, page := range pages {
+		manifest.Pages[page.path] = page.ManifestPage
+		filename := filepath.Join(outputDir, filepath.FromSlash(page.File))
+		// Timestamps have a resolution of one second, so content updated in the second of the previous build may have been missed
+		unchanged := previous.Pages[page.path] == page.ManifestPage && max(page.UpdatedAt, page.dependenciesUpdatedAt) < previous.BuiltAt
+		if _, err := os.Stat(filename); err == nil && !full && unchanged {
+			result.Skipped++
+			continue
+		}
+		err = s.buildFile(filename, page.path)
+		if err != nil {
+			return nil, err
+		}
+		result.Rendered = append(result.Rendered, page.path)
+	}
+	for pagePath, page := range previous.Pages {
+		if _, ok := manifest.Pages[pagePath]; ok {
+			continue
+		}
+		err = os.Remove(filepath.Join(outputDir, filepath.FromSlash(page.File)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not remove page %s: %v", pagePath, err)
+		}
+		result.Removed = append(result.Removed, pagePath)
+	}
+	slices.Sort(result.Removed)
+	manifest.Assets, result.Assets, err = s.buildAssets(outputDir, previous.Assets, full)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Static, err = s.buildStatic(outputDir, previous.Static)
+	if err != nil {
+		return nil, err
+	}
+	err = writeManifest(outputDir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// buildPages
+// returns the pages of all routes, the 404 page, the sitemap and the feeds
+func (s *Site) buildPages() ([]buildPage, error) {
+	collections, err := s.collectionLoader.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not get all collections: %v", err)
+	}
+	states, err := s.collectionStates(collections)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.contentPages(states)
+	if err != nil {
+		return nil, err
+	}
+	// The sitemap changes whenever a page is added, updated or removed
+	var latest int64
+	for _, page := range pages {
+		latest = max(latest, page.UpdatedAt)
+	}
+	urls := len(pages)
+	// Static hosts usually serve 404.html for missing files
+	if _, err := fs.Stat(s.themeFS, path.Join(templatesDirectory, notFoundTemplateName)); err == nil {
+		dependencies, latest := dependencyHash(states, nil)
+		pages = append(pages, buildPage{path: notFoundTemplateName, dependenciesUpdatedAt: latest, ManifestPage: ManifestPage{File: notFoundTemplateName, Dependencies: dependencies}})
+	}
+	if s.config.SiteURL == "" {
+		return pages, nil
+	}
+	for _, sitemapPath := range sitemapPaths(urls) {
+		pages = append(pages, buildPage{path: sitemapPath, ManifestPage: ManifestPage{File: pageFile(sitemapPath), UpdatedAt: latest, Items: int64(urls)}})
+	}
+	for _, collection := range collections {
+		if collection.Blueprint.Feed == nil {
+			continue
+		}
+		state := states[collection.Blueprint.CollectionName]
+		for _, format := range allFeedFormats {
+			feedPath := FeedPath(collection.Blueprint.CollectionName, format)
+			pages = append(pages, buildPage{path: feedPath, ManifestPage: ManifestPage{File: pageFile(feedPath), UpdatedAt: state.latest, Items: state.total}})
+		}
+	}
+	return pages, nil
+}
+
+// contentPages
+// returns the pages of all routes with the published items they show and the collections they depend on
+func (s *Site) contentPages(states map[string]collectionState) ([]buildPage, error) {
+	db := s.config.DatabaseInstance
+	var pages []buildPage
+	for _, route := range s.routes {
+		if route.Collection == "" {
+			// Pages without content can still show items in their layouts
+			dependencies, latest := dependencyHash(states, nil)
+			pages = append(pages, buildPage{path: route.Pattern, dependenciesUpdatedAt: latest, ManifestPage: ManifestPage{File: pageFile(route.Pattern), Dependencies: dependencies}})
+			continue
+		}
+		collection, err := s.collectionLoader.Get(route.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("could not get collection: %v", err)
+		}
+		dependencies, dependenciesLatest := dependencyHash(states, collection)
+		add := func(pagePath string, updatedAt int64, items int64) {
+			pages = append(pages, buildPage{path: pagePath, dependenciesUpdatedAt: dependenciesLatest, ManifestPage: ManifestPage{
+				File:         pageFile(pagePath),
+				UpdatedAt:    updatedAt,
+				Items:        items,
+				Dependencies: dependencies,
+			}})
+		}
+		switch {
+		case route.List:
+			state := states[route.Collection]
+			pages := pageCount(state.total, s.pageSize(route))
+			for page := 1; page <= pages; page++ {
+				add(route.PagePath(page), state.latest, state.total)
+			}
+		case collection.Blueprint.Singleton:
+			item, err := db.GetSingleton(collection)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not get item: %v", err)
+			}
+			if item.IsPublished() {
+				add(route.Pattern, itemUpdatedAt(item), 0)
+			}
+		default:
+			key := route.Placeholder()
+			err = db.EachItem(collection, func(item blueprint.Item) error {
+				if item.IsPublished() {
+					add(route.Path(fmt.Sprint(item[key])), itemUpdatedAt(item), 0)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("could not get items: %v", err)
+			}
+		}
+	}
+	return pages, nil
+}
+
+// collectionStates
+// returns the states of the collections by name
+func (s *Site) collectionStates(collections []blueprint.Collection) (map[string]collectionState, error) {
+	states := make(map[string]collectionState)
+	for index := range collections {
+		collection := &collections[index]
+		total, err := s.config.DatabaseInstance.CountPublishedItems(collection)
+		if err != nil {
+			return nil, err
+		}
+		latest, err := s.config.DatabaseInstance.GetLatestPublished(collection)
+		if err != nil {
+			return nil, err
+		}
+		states[collection.Blueprint.CollectionName] = collectionState{total: total, latest: latest}
+	}
+	return states, nil
+}
+
+// dependencyHash
+// returns a hash of the states of all collections except the one of the page, and their latest updated_at.
+// The collection of the page is included if its items reference each other.
+func dependencyHash(states map[string]collectionState, own *blueprint.Collection) (string, int64) {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		if own != nil && name == own.Blueprint.CollectionName && !referencesItself(own) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	hash := sha256.New()
+	var latest int64
+	for _, name := range names {
+		state := states[name]
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\x00", name, state.total, state.latest)
+		latest = max(latest, state.latest)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), latest
+}
+
+func referencesItself(collection *blueprint.Collection) bool {
+	for _, field := range collection.Blueprint.Fields {
+		if field.Type == blueprint.TypeReference && field.Reference.Collection == collection.Blueprint.CollectionName {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFile
+// renders the page at the path into the file. Paths of routes start with a slash, 404.html is the 404 page.
+func (s *Site) buildFile(filename string, pagePath string) error {
+	recorder := httptest.NewRecorder()
+	expectedStatus := http.StatusOK
+	if pagePath == notFoundTemplateName {
+		expectedStatus = http.StatusNotFound
+		s.serveNotFound(recorder, httptest.NewRequest(http.MethodGet, "/"+notFoundTemplateName, nil))
+	} else {
+		s.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, pagePath, nil))
+	}
+	if recorder.Code != expectedStatus {
+		return fmt.Errorf("could not render page %s: %s", pagePath, strings.TrimSpace(recorder.Body.String()))
+	}
+	err := os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not make directory: %v", err)
+	}
+	err = os.WriteFile(filename, recorder.Body.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("could not write page %s: %v", pagePath, err)
+	}
+	return nil
+}
+
+// buildAssets
+// copies the assets that are not part of the previous build, or all assets if full is set, and removes deleted ones.
+// Returns the UUIDs of all assets and the number of copied assets.
+func (s *Site) buildAssets(outputDir string, previous []string, full bool) ([]string, int, error) {
+	assetsDir := filepath.Join(outputDir, filepath.FromSlash(strings.Trim(asset.PublicPathPrefix, "/")))
+	err := os.MkdirAll(assetsDir, os.ModePerm)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not make directory: %v", err)
+	}
+	uuids := []string{}
+	copied := 0
+	for offset := int64(0); ; offset += buildAssetsLimit {
+		assets, err := s.config.DatabaseInstance.GetAssets(buildAssetsLimit, offset)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not get assets: %v", err)
+		}
+		for _, a := range assets {
+			uuids = append(uuids, a.UUID)
+			filename := filepath.Join(assetsDir, a.UUID)
+			// Files of assets never change
+			if _, err := os.Stat(filename); err == nil && !full && slices.Contains(previous, a.UUID) {
+				continue
+			}
+			err = s.copyAsset(a, filename)
+			if err != nil {
+				return nil, 0, err
+			}
+			copied++
+		}
+		if len(assets) < buildAssetsLimit {
+			break
+		}
+	}
+	for _, uuid := range previous {
+		if slices.Contains(uuids, uuid) {
+			continue
+		}
+		err = os.Remove(filepath.Join(assetsDir, uuid))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, fmt.Errorf("could not remove asset %s: %v", uuid, err)
+		}
+	}
+	return uuids, copied, nil
+}
+
+func (s *Site) copyAsset(a asset.Asset, filename string) error {
+	reader, err := s.config.AssetStorage.Get(a.StorageKey)
+	if err != nil {
+		return fmt.Errorf("could not get asset %s: %v", a.UUID, err)
+	}
+	defer reader.Close()
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create asset file: %v", err)
+	}
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not copy asset %s: %v", a.UUID, err)
+	}
+	return file.Close()
+}
+
+// buildStatic
+// copies the static files of the theme and removes the files of the previous build that have been deleted from it.
+// Returns the copied files relative to the output directory.
+func (s *Site) buildStatic(outputDir string, previous []string) ([]string, error) {
+	staticPrefix := strings.Trim(StaticPathPrefix, "/")
+	staticDir := filepath.Join(outputDir, filepath.FromSlash(staticPrefix))
+	files := []string{}
+	err := fs.WalkDir(s.themeFS, staticDirectory, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && name == staticDirectory {
+			// The theme has no static files
+			return fs.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(staticDir, filepath.FromSlash(strings.TrimPrefix(name, staticDirectory)))
+		if entry.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		data, err := fs.ReadFile(s.themeFS, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, path.Join(staticPrefix, strings.TrimPrefix(name, staticDirectory)))
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not copy static files: %v", err)
+	}
+	for _, file := range previous {
+		if slices.Contains(files, file) {
+			continue
+		}
+		err = os.Remove(filepath.Join(outputDir, filepath.FromSlash(file)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not remove static file %s: %v", file, err)
+		}
+	}
+	return files, nil
+}
+
+// themeHash
+// returns a hash of the routes and templates of the theme
+func (s *Site) themeHash() (string, error) {
+	hash := sha256.New()
+	err := fs.WalkDir(s.themeFS, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name == staticDirectory {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		data, err := fs.ReadFile(s.themeFS, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not read theme: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pageFile
+// returns the file of a page relative to the output directory. Paths without file extension become directories with an index.html.
+func pageFile(pagePath string) string {
+	name := strings.Trim(pagePath, "/")
+	if path.Ext(name) != "" {
+		return name
+	}
+	return path.Join(name, "index.html")
+}
+
+func itemUpdatedAt(item blueprint.Item) int64 {
+	updatedAt, _ := item[blueprint.KeyUpdatedAt].(int64)
+	return updatedAt
+}
+
+// readManifest
+// returns the manifest of the previous build or an empty manifest if there is none
+func readManifest(outputDir string) (Manifest, error) {
+	manifest := Manifest{Pages: make(map[string]ManifestPage)}
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("could not read manifest: %v", err)
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("could not unmarshal json data: %v", err)
+	}
+	if manifest.Pages == nil {
+		manifest.Pages = make(map[string]ManifestPage)
+	}
+	return manifest, nil
+}
+
+func writeManifest(outputDir string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal manifest: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(outputDir, ManifestFileName), data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write manifest: %v", err)
+	}
+	return nil
+}
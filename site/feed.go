@@ -0,0 +1,263 @@
+package site
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+type FeedFormat string
+
+const (
+	FeedFormatRSS  = FeedFormat("rss")
+	FeedFormatAtom = FeedFormat("atom")
+
+	// Feeds are at /feeds/<collection>/<format>.xml
+	feedsPathPrefix = "/feeds/"
+	// Number of the most recently published items in feeds
+	feedItemsLimit  = 50
+	atomXMLNS       = "http://www.w3.org/2005/Atom"
+	dublinCoreXMLNS = "http://purl.org/dc/elements/1.1/"
+)
+
+var (
+	allFeedFormats = []FeedFormat{FeedFormatRSS, FeedFormatAtom}
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	XMLNSDC string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Creator     string  `xml:"dc:creator,omitempty"` // The author element of RSS is reserved for email addresses
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Link      *atomLink   `xml:"link"`
+	Summary   *atomText   `xml:"summary"`
+	Author    *atomPerson `xml:"author"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// feedEntry
+// contains the values of an item that are mapped by blueprint.BlueprintFeed
+type feedEntry struct {
+	Title       string
+	Link        string // Empty if the collection has no route for single items
+	UUID        string
+	Summary     string
+	SummaryHTML bool
+	Author      string
+	Date        int64
+	UpdatedAt   int64
+}
+
+// FeedPath
+// returns the path of the feed of the collection
+func FeedPath(collectionName string, format FeedFormat) string {
+	return fmt.Sprintf("%s%s/%s.xml", feedsPathPrefix, collectionName, format)
+}
+
+// feedHandler
+// returns the handler that writes the feeds of collections in the format
+func (s *Site) feedHandler(format FeedFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, err := s.collectionLoader.Get(chi.URLParam(r, "collection"))
+		if err != nil || collection.Blueprint.Feed == nil {
+			s.serveNotFound(w, r)
+			return
+		}
+		entries, err := s.feedEntries(collection)
+		if err != nil {
+			s.serveError(w, err)
+			return
+		}
+		latest, err := s.config.DatabaseInstance.GetLatestPublished(collection)
+		if err != nil {
+			s.serveError(w, err)
+			return
+		}
+		title := collection.Blueprint.CollectionDisplayName.Resolve(s.config.Locales.Default())
+		feedURL := s.config.SiteURL + FeedPath(collection.Blueprint.CollectionName, format)
+		switch format {
+		case FeedFormatRSS:
+			feed := rssFeed{Version: "2.0", XMLNSDC: dublinCoreXMLNS, Channel: rssChannel{
+				Title:         title,
+				Link:          s.config.SiteURL + "/",
+				Description:   title,
+				LastBuildDate: rssDate(latest),
+			}}
+			for _, entry := range entries {
+				feed.Channel.Items = append(feed.Channel.Items, rssItem{
+					Title:       entry.Title,
+					Link:        entry.Link,
+					GUID:        rssGUID{Value: "urn:uuid:" + entry.UUID},
+					Description: entry.Summary,
+					PubDate:     rssDate(entry.Date),
+					Creator:     entry.Author,
+				})
+			}
+			s.writeXML(w, feed)
+		case FeedFormatAtom:
+			feed := atomFeed{
+				XMLNS:   atomXMLNS,
+				Title:   title,
+				ID:      feedURL,
+				Updated: atomDate(latest),
+				Links:   []atomLink{{Href: s.config.SiteURL + "/"}, {Href: feedURL, Rel: "self"}},
+			}
+			for _, entry := range entries {
+				atom := atomEntry{
+					Title:     entry.Title,
+					ID:        "urn:uuid:" + entry.UUID,
+					Updated:   atomDate(entry.UpdatedAt),
+					Published: atomDate(entry.Date),
+				}
+				if entry.Link != "" {
+					atom.Link = &atomLink{Href: entry.Link}
+				}
+				if entry.Summary != "" {
+					atom.Summary = &atomText{Type: "text", Value: entry.Summary}
+					if entry.SummaryHTML {
+						atom.Summary.Type = "html"
+					}
+				}
+				if entry.Author != "" {
+					atom.Author = &atomPerson{Name: entry.Author}
+				}
+				feed.Entries = append(feed.Entries, atom)
+			}
+			s.writeXML(w, feed)
+		}
+	}
+}
+
+// feedEntries
+// returns the most recently published items of the collection with the values that are mapped by the feed of the blueprint
+func (s *Site) feedEntries(collection *blueprint.Collection) ([]feedEntry, error) {
+	db := s.config.DatabaseInstance
+	items, err := db.GetPublishedItems(collection, feedItemsLimit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not get items: %v", err)
+	}
+	// Items are linked to the first route of single items of the collection and use its locale
+	var itemRoute *Route
+	locale := s.config.Locales.Default()
+	for index, route := range s.routes {
+		if route.Collection == collection.Blueprint.CollectionName && !route.List && route.Placeholder() != "" {
+			itemRoute = &s.routes[index]
+			locale = route.Locale
+			break
+		}
+	}
+	err = db.LocalizeItems(collection, items, s.config.Locales.Chain(locale), s.config.Locales.Default())
+	if err != nil {
+		return nil, fmt.Errorf("could not localize items: %v", err)
+	}
+	feed := collection.Blueprint.Feed
+	entries := make([]feedEntry, len(items))
+	for index, item := range items {
+		entry := feedEntry{
+			Title:     text(item[feed.TitleField()]),
+			UUID:      text(item[blueprint.KeyUUID]),
+			Author:    text(item[feed.Author]),
+			UpdatedAt: itemUpdatedAt(item),
+		}
+		if itemRoute != nil {
+			entry.Link = s.config.SiteURL + itemRoute.Path(text(item[itemRoute.Placeholder()]))
+		}
+		entry.Date, _ = item[feed.DateField()].(int64)
+		if entry.Date <= 0 {
+			entry.Date, _ = item[blueprint.KeyPublishedAt].(int64)
+		}
+		if feed.Summary != "" {
+			entry.Summary = text(item[feed.Summary])
+			if rendered, ok := item[blueprint.MarkdownHTMLFieldName(feed.Summary)]; ok {
+				entry.Summary = text(rendered)
+				entry.SummaryHTML = true
+			}
+		}
+		entries[index] = entry
+	}
+	return entries, nil
+}
+
+// text
+// returns the value as string, which is empty for NULL values
+func text(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func rssDate(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC1123Z)
+}
+
+func atomDate(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
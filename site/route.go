@@ -0,0 +1,136 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+const (
+	routesFileName = "routes.json"
+	// Appended to the pattern of list routes for the pages after the first one
+	pagePathSegment = "page"
+)
+
+var (
+	placeholderRegex = regexp.MustCompile(`\{([^}]*)\}`)
+)
+
+// Route
+// maps a URL pattern to a template. Patterns use the syntax of chi, e. g. /blog/{slug}.
+//
+// A route renders
+//   - a page without content, if it has no collection,
+//   - the item of a singleton collection,
+//   - a list of the published items of a collection, if list is set (further pages are at <pattern>/page/<n>),
+//   - or a published item that is looked up by the only placeholder of the pattern, which must be id, uuid or a slug field.
+type Route struct {
+	Pattern    string `json:"pattern"`
+	Collection string `json:"collection"` // Empty for pages without content
+	Template   string `json:"template"`   // File in the templates directory of the theme
+	List       bool   `json:"list"`
+	PageSize   int    `json:"page_size"` // Only for list routes, defaults to RANGI_SITE_PAGE_SIZE
+	Locale     string `json:"locale"`    // Defaults to the default locale
+}
+
+// Placeholder
+// returns the name of the placeholder of the pattern or an empty string if there is none
+func (r *Route) Placeholder() string {
+	matches := placeholderRegex.FindStringSubmatch(r.Pattern)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// Path
+// returns the path of the route with the placeholder replaced by value
+func (r *Route) Path(value string) string {
+	return placeholderRegex.ReplaceAllLiteralString(r.Pattern, value)
+}
+
+// PagePath
+// returns the path of a page of a list route. The first page is at the pattern itself.
+func (r *Route) PagePath(page int) string {
+	if page <= 1 {
+		return r.Pattern
+	}
+	return fmt.Sprintf("%s/%s/%d", strings.TrimSuffix(r.Pattern, "/"), pagePathSegment, page)
+}
+
+// pagePattern
+// returns the pattern of the pages after the first one of a list route
+func (r *Route) pagePattern() string {
+	return fmt.Sprintf("%s/%s/{page:[0-9]+}", strings.TrimSuffix(r.Pattern, "/"), pagePathSegment)
+}
+
+// loadRoutes
+// reads the routes of the theme and checks them against the collections
+func loadRoutes(themeFS fs.FS, collectionLoader *blueprint.CollectionLoader) ([]Route, error) {
+	data, err := fs.ReadFile(themeFS, routesFileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read routes: %v", err)
+	}
+	var routes []Route
+	err = json.Unmarshal(data, &routes)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal json data: %v", err)
+	}
+	var patterns []string
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Pattern, "/") {
+			return nil, fmt.Errorf("pattern %q must start with /", route.Pattern)
+		}
+		if slices.Contains(patterns, route.Pattern) {
+			return nil, fmt.Errorf("duplicate pattern %s", route.Pattern)
+		}
+		patterns = append(patterns, route.Pattern)
+		if route.Template == "" {
+			return nil, fmt.Errorf("missing template for pattern %s", route.Pattern)
+		}
+		if route.PageSize < 0 {
+			return nil, fmt.Errorf("invalid page size for pattern %s", route.Pattern)
+		}
+		err = checkRoute(&route, collectionLoader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %s: %v", route.Pattern, err)
+		}
+	}
+	return routes, nil
+}
+
+func checkRoute(route *Route, collectionLoader *blueprint.CollectionLoader) error {
+	placeholders := placeholderRegex.FindAllStringSubmatch(route.Pattern, -1)
+	if route.Collection == "" {
+		if len(placeholders) > 0 || route.List {
+			return fmt.Errorf("placeholders and lists need a collection")
+		}
+		return nil
+	}
+	collection, err := collectionLoader.Get(route.Collection)
+	if err != nil {
+		return fmt.Errorf("could not get collection %s: %v", route.Collection, err)
+	}
+	if collection.Blueprint.Singleton || route.List {
+		if len(placeholders) > 0 {
+			return fmt.Errorf("placeholders are only allowed for routes of single items")
+		}
+		if route.List && collection.Blueprint.Singleton {
+			return fmt.Errorf("singleton collections have no list")
+		}
+		return nil
+	}
+	if len(placeholders) != 1 {
+		return fmt.Errorf("pattern must contain exactly one placeholder")
+	}
+	key := placeholders[0][1]
+	if key != blueprint.KeyID && key != blueprint.KeyUUID && !slices.ContainsFunc(collection.Blueprint.SlugFields(), func(f blueprint.BlueprintField) bool { return f.Name == key }) {
+		return fmt.Errorf("placeholder %q must be id, uuid or a slug field", key)
+	}
+	return nil
+}
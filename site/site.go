@@ -0,0 +1,385 @@
+package site
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rangidev/rangi/asset"
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/config"
+	"github.com/rangidev/rangi/markdown"
+	"github.com/rangidev/rangi/preview"
+)
+
+const (
+	layoutsDirectory   = "layouts"
+	partialsDirectory  = "partials"
+	templatesDirectory = "templates"
+	// Files of this directory of the theme are served at StaticPathPrefix
+	staticDirectory = "static"
+	// Rendered if no route matches or the item does not exist. Plain text is sent if the theme has no such template.
+	notFoundTemplateName = "404.html"
+
+	StaticPathPrefix = "/static/"
+)
+
+var (
+	templateFuncs = template.FuncMap{
+		"markdown": markdown.ItemHTML,
+		"assetURL": func(uuid string) string { return asset.PublicPathPrefix + uuid },
+	}
+)
+
+// Site
+// renders the public website with the templates of a theme. The theme directory contains
+//   - routes.json, see Route,
+//   - layouts/*.html and partials/*.html, which are available in all templates,
+//   - templates/*.html, the templates of the routes and the optional 404.html,
+//   - static/, files that are served unchanged.
+//
+// Templates and routes are read once, or on every request if template development is enabled.
+type Site struct {
+	config           *config.Config
+	collectionLoader *blueprint.CollectionLoader
+	themeFS          fs.FS
+	// Guards the cached routes, router and templates
+	mutex     sync.Mutex
+	routes    []Route
+	router    http.Handler
+	templates map[string]*template.Template
+}
+
+// PageData
+// is passed to the templates
+type PageData struct {
+	Path       string
+	Locale     string
+	Collection string           // Empty for pages without content
+	Item       blueprint.Item   // Routes of single items and singletons
+	Items      []blueprint.Item // List routes
+	Pagination *Pagination      // List routes
+	Preview    bool             // True if the item is shown with a preview token, it might not have been published
+}
+
+// Pagination
+// describes the current page of a list route
+type Pagination struct {
+	Page        int // Starts at 1
+	Pages       int
+	Total       int64 // Number of all published items
+	PreviousURL string
+	NextURL     string
+}
+
+// New
+// creates the site of the configured theme and checks its routes and templates
+func New(config *config.Config, collectionLoader *blueprint.CollectionLoader) (*Site, error) {
+	themePath := filepath.Join(config.ThemesPath, config.Theme)
+	info, err := os.Stat(themePath)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("theme %s does not exist in %s", config.Theme, config.ThemesPath)
+	}
+	s := &Site{
+		config:           config,
+		collectionLoader: collectionLoader,
+		themeFS:          os.DirFS(themePath),
+	}
+	err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	// Parse all templates once, so that mistakes are found on start
+	for _, route := range s.routes {
+		_, err = s.template(route.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Routes
+// returns the routes of the theme
+func (s *Site) Routes() ([]Route, error) {
+	err := s.reload()
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.routes, nil
+}
+
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := s.reload()
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	s.mutex.Lock()
+	router := s.router
+	s.mutex.Unlock()
+	router.ServeHTTP(w, r)
+}
+
+// reload
+// reads routes and templates again in development mode
+func (s *Site) reload() error {
+	if !s.config.EnableTemplateDevelopment {
+		return nil
+	}
+	return s.load()
+}
+
+// load
+// reads the routes and creates the router. Cached templates are dropped.
+func (s *Site) load() error {
+	routes, err := loadRoutes(s.themeFS, s.collectionLoader)
+	if err != nil {
+		return err
+	}
+	router := chi.NewRouter()
+	for index := range routes {
+		route := &routes[index]
+		route.Locale, err = s.config.Locales.Parse(route.Locale)
+		if err != nil {
+			return fmt.Errorf("invalid route %s: %v", route.Pattern, err)
+		}
+		router.Get(route.Pattern, s.routeHandler(*route))
+		if route.List {
+			router.Get(route.pagePattern(), s.routeHandler(*route))
+		}
+	}
+	// Absolute URLs are needed for the sitemap and the feeds
+	if s.config.SiteURL != "" {
+		router.Get(SitemapPath, s.serveSitemap)
+		router.Get(sitemapPartPattern, s.serveSitemapPart)
+		for _, format := range allFeedFormats {
+			router.Get(FeedPath("{collection}", format), s.feedHandler(format))
+		}
+	}
+	staticFS, err := fs.Sub(s.themeFS, staticDirectory)
+	if err != nil {
+		return fmt.Errorf("could not get filesystem sub directory: %v", err)
+	}
+	router.Handle(StaticPathPrefix+"*", http.StripPrefix(StaticPathPrefix, http.FileServerFS(staticFS)))
+	router.NotFound(s.serveNotFound)
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.routes = routes
+	s.router = router
+	s.templates = make(map[string]*template.Template)
+	return nil
+}
+
+// template
+// returns the named template of the templates directory together with all layouts and partials
+func (s *Site) template(name string) (*template.Template, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if tmpl, ok := s.templates[name]; ok {
+		return tmpl, nil
+	}
+	var files []string
+	for _, pattern := range []string{path.Join(layoutsDirectory, "*.html"), path.Join(partialsDirectory, "*.html")} {
+		matches, err := fs.Glob(s.themeFS, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not find templates: %v", err)
+		}
+		files = append(files, matches...)
+	}
+	// Parsed last, so that its definitions replace the default blocks of the layouts
+	files = append(files, path.Join(templatesDirectory, name))
+	tmpl, err := template.New(path.Base(name)).Funcs(sprig.FuncMap()).Funcs(templateFuncs).ParseFS(s.themeFS, files...)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %v", name, err)
+	}
+	s.templates[name] = tmpl
+	return tmpl, nil
+}
+
+// routeHandler
+// returns the handler that renders the route
+func (s *Site) routeHandler(route Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := PageData{
+			Path:       r.URL.Path,
+			Locale:     route.Locale,
+			Collection: route.Collection,
+		}
+		if route.Collection != "" {
+			found, err := s.loadContent(r, route, &data)
+			if err != nil {
+				s.serveError(w, err)
+				return
+			}
+			if !found {
+				s.serveNotFound(w, r)
+				return
+			}
+		}
+		if data.Preview {
+			// Previews must neither be cached nor indexed
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("X-Robots-Tag", "noindex")
+		}
+		s.render(w, http.StatusOK, route.Template, data)
+	}
+}
+
+// loadContent
+// sets the item or the items of the route. Returns false if they do not exist or have not been published.
+func (s *Site) loadContent(r *http.Request, route Route, data *PageData) (bool, error) {
+	db := s.config.DatabaseInstance
+	collection, err := s.collectionLoader.Get(route.Collection)
+	if err != nil {
+		return false, fmt.Errorf("could not get collection: %v", err)
+	}
+	var items []blueprint.Item
+	switch {
+	case route.List:
+		page := 1
+		if param := chi.URLParam(r, "page"); param != "" {
+			page, err = strconv.Atoi(param)
+			if err != nil || page < 2 {
+				// The first page is only at the pattern itself
+				return false, nil
+			}
+		}
+		pageSize := s.pageSize(route)
+		total, err := db.CountPublishedItems(collection)
+		if err != nil {
+			return false, err
+		}
+		pages := pageCount(total, pageSize)
+		if page > pages {
+			return false, nil
+		}
+		items, err = db.GetPublishedItems(collection, pageSize, int64((page-1)*pageSize))
+		if err != nil {
+			return false, fmt.Errorf("could not get items: %v", err)
+		}
+		data.Items = items
+		data.Pagination = &Pagination{Page: page, Pages: pages, Total: total}
+		if page > 1 {
+			data.Pagination.PreviousURL = route.PagePath(page - 1)
+		}
+		if page < pages {
+			data.Pagination.NextURL = route.PagePath(page + 1)
+		}
+	case collection.Blueprint.Singleton:
+		item, err := db.GetSingleton(collection)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get item: %v", err)
+		}
+		items = []blueprint.Item{item}
+	default:
+		key := route.Placeholder()
+		item, err := db.GetItem(collection, key, chi.URLParam(r, key))
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get item: %v", err)
+		}
+		items = []blueprint.Item{item}
+	}
+	if !route.List {
+		data.Preview = s.isPreview(r, collection, items[0])
+		if !items[0].IsPublished() && !data.Preview {
+			return false, nil
+		}
+		data.Item = items[0]
+	}
+	err = db.LocalizeItems(collection, items, s.config.Locales.Chain(route.Locale), s.config.Locales.Default())
+	if err != nil {
+		return false, fmt.Errorf("could not localize items: %v", err)
+	}
+	return true, nil
+}
+
+// isPreview
+// returns true if the request has a valid preview token for the item
+func (s *Site) isPreview(r *http.Request, collection *blueprint.Collection, item blueprint.Item) bool {
+	token := r.URL.Query().Get(preview.QueryParam)
+	if token == "" {
+		return false
+	}
+	claims, err := s.config.PreviewSigner.Verify(token)
+	if err != nil {
+		return false
+	}
+	return claims.Allows(collection.Blueprint.CollectionName, fmt.Sprintf("%v", item[blueprint.KeyUUID]))
+}
+
+func (s *Site) pageSize(route Route) int {
+	if route.PageSize > 0 {
+		return route.PageSize
+	}
+	return s.config.SitePageSize
+}
+
+// pageCount
+// returns the number of pages of a list. Empty lists have one page.
+func pageCount(total int64, pageSize int) int {
+	return max(1, int(math.Ceil(float64(total)/float64(pageSize))))
+}
+
+func (s *Site) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	_, err := fs.Stat(s.themeFS, path.Join(templatesDirectory, notFoundTemplateName))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, http.StatusNotFound, notFoundTemplateName, PageData{Path: r.URL.Path, Locale: s.config.Locales.Default()})
+}
+
+// render
+// executes the template into a buffer first, so that errors do not result in half-rendered pages
+func (s *Site) render(w http.ResponseWriter, status int, name string, data PageData) {
+	tmpl, err := s.template(name)
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		s.serveError(w, fmt.Errorf("could not execute template %s: %v", name, err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buffer.WriteTo(w)
+}
+
+// serveError
+// logs the error. Details are only shown to visitors in development mode.
+func (s *Site) serveError(w http.ResponseWriter, err error) {
+	s.config.Logger.Error("Could not render page", "error", err)
+	message := http.StatusText(http.StatusInternalServerError)
+	if s.config.EnableTemplateDevelopment {
+		message = err.Error()
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
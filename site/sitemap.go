@@ -0,0 +1,137 @@
+package site
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	SitemapPath = "/sitemap.xml"
+	// Maximum number of URLs of a sitemap file. Larger sitemaps are split and SitemapPath becomes the index of the parts.
+	sitemapMaxURLs     = 50000
+	sitemapXMLNS       = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapPartPath    = "/sitemap-%d.xml"
+	sitemapPartPattern = "/sitemap-{part:[0-9]+}.xml"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapPaths
+// returns the paths of the sitemap files for the number of URLs
+func sitemapPaths(urls int) []string {
+	paths := []string{SitemapPath}
+	if urls > sitemapMaxURLs {
+		for part := 1; part <= partCount(urls); part++ {
+			paths = append(paths, fmt.Sprintf(sitemapPartPath, part))
+		}
+	}
+	return paths
+}
+
+// sitemapPages
+// returns the pages of all routes
+func (s *Site) sitemapPages() ([]buildPage, error) {
+	collections, err := s.collectionLoader.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not get all collections: %v", err)
+	}
+	states, err := s.collectionStates(collections)
+	if err != nil {
+		return nil, err
+	}
+	return s.contentPages(states)
+}
+
+// serveSitemap
+// writes the sitemap of all pages, or the index of the parts if there are too many pages for one file
+func (s *Site) serveSitemap(w http.ResponseWriter, r *http.Request) {
+	pages, err := s.sitemapPages()
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	if len(pages) <= sitemapMaxURLs {
+		s.writeXML(w, sitemapURLSet{XMLNS: sitemapXMLNS, URLs: s.sitemapURLs(pages)})
+		return
+	}
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	for part := 1; part <= partCount(len(pages)); part++ {
+		var latest int64
+		for _, page := range pages[(part-1)*sitemapMaxURLs : min(part*sitemapMaxURLs, len(pages))] {
+			latest = max(latest, page.UpdatedAt)
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: s.config.SiteURL + fmt.Sprintf(sitemapPartPath, part), LastMod: lastMod(latest)})
+	}
+	s.writeXML(w, index)
+}
+
+// serveSitemapPart
+// writes a part of a sitemap that has been split
+func (s *Site) serveSitemapPart(w http.ResponseWriter, r *http.Request) {
+	pages, err := s.sitemapPages()
+	if err != nil {
+		s.serveError(w, err)
+		return
+	}
+	part, err := strconv.Atoi(chi.URLParam(r, "part"))
+	if err != nil || len(pages) <= sitemapMaxURLs || part < 1 || part > partCount(len(pages)) {
+		s.serveNotFound(w, r)
+		return
+	}
+	pages = pages[(part-1)*sitemapMaxURLs : min(part*sitemapMaxURLs, len(pages))]
+	s.writeXML(w, sitemapURLSet{XMLNS: sitemapXMLNS, URLs: s.sitemapURLs(pages)})
+}
+
+func (s *Site) sitemapURLs(pages []buildPage) []sitemapURL {
+	urls := make([]sitemapURL, len(pages))
+	for index, page := range pages {
+		urls[index] = sitemapURL{Loc: s.config.SiteURL + page.path, LastMod: lastMod(page.UpdatedAt)}
+	}
+	return urls
+}
+
+// writeXML
+// writes the XML declaration and the value
+func (s *Site) writeXML(w http.ResponseWriter, v any) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		s.serveError(w, fmt.Errorf("could not marshal xml: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+func partCount(urls int) int {
+	return (urls + sitemapMaxURLs - 1) / sitemapMaxURLs
+}
+
+// lastMod
+// formats a Unix timestamp for sitemaps. Pages without content have no date.
+func lastMod(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
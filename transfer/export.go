@@ -0,0 +1,224 @@
+package transfer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+// Exporter
+// writes the items of collections as JSON lines or CSV
+type Exporter struct {
+	db               *database.DB
+	collectionLoader *blueprint.CollectionLoader
+}
+
+func NewExporter(db *database.DB, collectionLoader *blueprint.CollectionLoader) *Exporter {
+	return &Exporter{db: db, collectionLoader: collectionLoader}
+}
+
+// Export
+// writes all items of the collection, ordered by id
+func (e *Exporter) Export(w io.Writer, collection *blueprint.Collection, format Format) error {
+	switch format {
+	case FormatJSONLines:
+		encoder := json.NewEncoder(w)
+		return e.eachRow(collection, func(fields []blueprint.BlueprintField, values []any) error {
+			row := make(map[string]any, len(fields))
+			for index, field := range fields {
+				row[field.Name] = values[index]
+			}
+			return encoder.Encode(row)
+		})
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		header := true
+		err := e.eachRow(collection, func(fields []blueprint.BlueprintField, values []any) error {
+			if header {
+				var names []string
+				for _, field := range fields {
+					names = append(names, field.Name)
+				}
+				err := writer.Write(names)
+				if err != nil {
+					return err
+				}
+				header = false
+			}
+			record := make([]string, len(values))
+			for index, value := range values {
+				record[index] = csvValue(value)
+			}
+			return writer.Write(record)
+		})
+		if err != nil {
+			return err
+		}
+		if header {
+			// Write the header for empty collections, too
+			var names []string
+			for _, field := range exportedFields(collection.Blueprint) {
+				names = append(names, field.Name)
+			}
+			err = writer.Write(names)
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
+
+// ExportAll
+// writes the items of all collections. JSON lines of all collections are concatenated, they can be told apart by the "collection" field.
+// CSV files are written into a ZIP archive with one file per collection.
+func (e *Exporter) ExportAll(w io.Writer, format Format) error {
+	collections, err := e.collectionLoader.GetAll()
+	if err != nil {
+		return fmt.Errorf("could not get collections: %v", err)
+	}
+	if format == FormatCSV {
+		archive := zip.NewWriter(w)
+		for index := range collections {
+			file, err := archive.Create(collections[index].Blueprint.CollectionName + ".csv")
+			if err != nil {
+				return fmt.Errorf("could not create archive file: %v", err)
+			}
+			err = e.Export(file, &collections[index], format)
+			if err != nil {
+				return fmt.Errorf("could not export collection %s: %v", collections[index].Blueprint.CollectionName, err)
+			}
+		}
+		return archive.Close()
+	}
+	for index := range collections {
+		err = e.Export(w, &collections[index], format)
+		if err != nil {
+			return fmt.Errorf("could not export collection %s: %v", collections[index].Blueprint.CollectionName, err)
+		}
+	}
+	return nil
+}
+
+// Row
+// returns the exported values of a single item by field name, e. g. for API responses
+func (e *Exporter) Row(collection *blueprint.Collection, item blueprint.Item) (map[string]any, error) {
+	fields := exportedFields(collection.Blueprint)
+	refCollections, err := e.refCollections(fields)
+	if err != nil {
+		return nil, err
+	}
+	values, err := e.values(collection, fields, refCollections, item)
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(fields))
+	for index, field := range fields {
+		row[field.Name] = values[index]
+	}
+	return row, nil
+}
+
+// eachRow
+// calls fn with the exported values of every item
+func (e *Exporter) eachRow(collection *blueprint.Collection, fn func(fields []blueprint.BlueprintField, values []any) error) error {
+	fields := exportedFields(collection.Blueprint)
+	refCollections, err := e.refCollections(fields)
+	if err != nil {
+		return err
+	}
+	return e.db.EachItem(collection, func(item blueprint.Item) error {
+		values, err := e.values(collection, fields, refCollections, item)
+		if err != nil {
+			return err
+		}
+		return fn(fields, values)
+	})
+}
+
+// refCollections
+// returns the referenced collections of the reference fields by field name
+func (e *Exporter) refCollections(fields []blueprint.BlueprintField) (map[string]*blueprint.Collection, error) {
+	refCollections := map[string]*blueprint.Collection{}
+	for _, field := range fields {
+		if field.Type != blueprint.TypeReference {
+			continue
+		}
+		refCollection, err := e.collectionLoader.Get(field.Reference.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("could not get referenced collection %s: %v", field.Reference.Collection, err)
+		}
+		refCollections[field.Name] = refCollection
+	}
+	return refCollections, nil
+}
+
+// values
+// returns the exported values of the item in the order of fields
+func (e *Exporter) values(collection *blueprint.Collection, fields []blueprint.BlueprintField, refCollections map[string]*blueprint.Collection, item blueprint.Item) ([]any, error) {
+	values := make([]any, len(fields))
+	for index, field := range fields {
+		if field.Type == blueprint.TypeReference {
+			id, _ := item[blueprint.KeyID].(int64)
+			uuids, err := e.db.GetReferencedUUIDs(collection, refCollections[field.Name], id)
+			if err != nil {
+				return nil, fmt.Errorf("could not get references of item %d: %v", id, err)
+			}
+			if uuids == nil {
+				uuids = []string{}
+			}
+			values[index] = uuids
+			continue
+		}
+		values[index] = exportValue(field, item[field.Name])
+	}
+	return values, nil
+}
+
+// exportValue
+// converts a value read from the database into the value that is exported
+func exportValue(field blueprint.BlueprintField, value any) any {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	switch field.Type {
+	case blueprint.TypeBoolean:
+		if i, ok := value.(int64); ok {
+			return i != 0
+		}
+	case blueprint.TypeArray, blueprint.TypeObject:
+		if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	}
+	return value
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.RawMessage:
+		return string(v)
+	case []string:
+		return strings.Join(v, referenceSeparator)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,235 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/rangidev/rangi/blueprint"
+)
+
+type Format string
+
+const (
+	FormatJSONLines = Format("jsonl")
+	FormatCSV       = Format("csv")
+
+	// Separates the referenced items in CSV cells
+	referenceSeparator = "|"
+)
+
+var (
+	AllFormats = []Format{
+		FormatJSONLines,
+		FormatCSV,
+	}
+
+	errorSkipField = errors.New("field is not imported")
+)
+
+// ParseFormat
+// accepts a format name or a file name with a known extension
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(s)
+	if ext := filepath.Ext(s); ext != "" {
+		s = ext[1:]
+	}
+	switch s {
+	case "jsonl", "ndjson", "json":
+		return FormatJSONLines, nil
+	case "csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unknown format %s", s)
+	}
+}
+
+// ContentType
+// returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// exportedFields
+// returns the fields that are written when exporting, derived fields are left out
+func exportedFields(bp *blueprint.Blueprint) []blueprint.BlueprintField {
+	var fields []blueprint.BlueprintField
+	for _, field := range bp.Fields {
+		if bp.IsDerivedField(field.Name) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// mapColumn
+// finds the field for a column of an imported file. Columns are matched against field names and display names, ignoring case.
+func mapColumn(bp *blueprint.Blueprint, column string) (blueprint.BlueprintField, bool) {
+	column = strings.TrimSpace(column)
+	for _, field := range bp.Fields {
+		if strings.EqualFold(field.Name, column) {
+			return field, true
+		}
+	}
+	for _, field := range bp.Fields {
+		for _, displayName := range field.DisplayName {
+			if strings.EqualFold(displayName, column) {
+				return field, true
+			}
+		}
+	}
+	return blueprint.BlueprintField{}, false
+}
+
+// convertValue
+// converts an imported value into the value that is stored for the field.
+// Values from CSV files are strings, values from JSON lines are decoded with json.Decoder.UseNumber.
+// References are returned as list of UUIDs or slugs that are resolved by the importer.
+func convertValue(field blueprint.BlueprintField, value any) (any, error) {
+	if s, ok := value.(string); ok && field.Type != blueprint.TypeString && field.Type != blueprint.TypeMarkdown && field.Type != blueprint.TypeSlug {
+		value = strings.TrimSpace(s)
+	}
+	switch field.Type {
+	case blueprint.TypeID:
+		// IDs are assigned by the database
+		return nil, errorSkipField
+	case blueprint.TypeString, blueprint.TypeMarkdown, blueprint.TypeSlug:
+		switch v := value.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		default:
+			return nil, fmt.Errorf("expected string, got %v", value)
+		}
+	case blueprint.TypeUUID, blueprint.TypeAsset:
+		switch v := value.(type) {
+		case nil:
+			return "", nil
+		case string:
+			if v == "" {
+				return "", nil
+			}
+			parsed, err := uuid.Parse(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid uuid %q", v)
+			}
+			return parsed.String(), nil
+		default:
+			return nil, fmt.Errorf("expected uuid, got %v", value)
+		}
+	case blueprint.TypeInt:
+		switch v := value.(type) {
+		case nil:
+			return nil, nil
+		case json.Number:
+			i, err := v.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("expected integer, got %v", v)
+			}
+			return i, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected integer, got %q", v)
+			}
+			return i, nil
+		default:
+			return nil, fmt.Errorf("expected integer, got %v", value)
+		}
+	case blueprint.TypeBoolean:
+		switch v := value.(type) {
+		case nil:
+			return nil, nil
+		case bool:
+			return v, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("expected boolean, got %q", v)
+			}
+			return b, nil
+		default:
+			return nil, fmt.Errorf("expected boolean, got %v", value)
+		}
+	case blueprint.TypeArray, blueprint.TypeObject:
+		return convertJSONValue(field.Type, value)
+	case blueprint.TypeReference:
+		switch v := value.(type) {
+		case nil:
+			return []string{}, nil
+		case string:
+			var refs []string
+			for _, ref := range strings.Split(v, referenceSeparator) {
+				if ref = strings.TrimSpace(ref); ref != "" {
+					refs = append(refs, ref)
+				}
+			}
+			return refs, nil
+		case []any:
+			var refs []string
+			for _, ref := range v {
+				s, ok := ref.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected uuid or slug, got %v", ref)
+				}
+				refs = append(refs, s)
+			}
+			return refs, nil
+		default:
+			return nil, fmt.Errorf("expected list of references, got %v", value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", field.Type)
+	}
+}
+
+// convertJSONValue
+// returns arrays and objects as JSON text
+func convertJSONValue(typ blueprint.Type, value any) (any, error) {
+	if s, ok := value.(string); ok {
+		if s == "" {
+			return nil, nil
+		}
+		err := json.Unmarshal([]byte(s), &value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json: %v", err)
+		}
+	}
+	switch value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		if typ != blueprint.TypeArray {
+			return nil, fmt.Errorf("expected object, got array")
+		}
+	case map[string]any:
+		if typ != blueprint.TypeObject {
+			return nil, fmt.Errorf("expected array, got object")
+		}
+	default:
+		return nil, fmt.Errorf("expected %s, got %v", typ, value)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
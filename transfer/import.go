@@ -0,0 +1,414 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	maxLineSize = 16 << 20
+)
+
+// ItemWriter
+// stores imported items, e. g. with hooks and the audit log. Items are written in the default locale.
+// The context carries the transaction of the row, writers have to use it, see database.FromContext.
+type ItemWriter interface {
+	CreateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error
+	UpdateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error
+}
+
+type Options struct {
+	// Validate all rows without writing anything. Hooks are not called.
+	DryRun bool
+}
+
+// RowError
+// describes why a row could not be imported. Row is the line number in the file.
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Field, e.Message)
+}
+
+type Report struct {
+	DryRun         bool       `json:"dry_run"`
+	Rows           int        `json:"rows"`
+	Created        int        `json:"created"` // Would have been created in a dry run
+	Updated        int        `json:"updated"` // Would have been updated in a dry run
+	Failed         int        `json:"failed"`
+	IgnoredColumns []string   `json:"ignored_columns,omitempty"` // Columns that do not match any field
+	Errors         []RowError `json:"errors,omitempty"`
+}
+
+// Importer
+// reads items from JSON lines or CSV files. Items are matched by their uuid: existing items are updated, all others are created.
+type Importer struct {
+	db               *database.DB
+	collectionLoader *blueprint.CollectionLoader
+	writer           ItemWriter
+}
+
+func NewImporter(db *database.DB, collectionLoader *blueprint.CollectionLoader, writer ItemWriter) *Importer {
+	return &Importer{db: db, collectionLoader: collectionLoader, writer: writer}
+}
+
+// row
+// contains the values of a line by column name
+type row struct {
+	number int
+	values map[string]any
+	err    string // Set if the line could not be parsed
+}
+
+// Import
+// imports all rows of the file into the collection. If collection is nil, the collection of each row is taken from its "collection" column.
+// Rows with errors are skipped and reported, the remaining rows are imported.
+func (i *Importer) Import(ctx context.Context, r io.Reader, collection *blueprint.Collection, format Format, options Options) (*Report, error) {
+	report := &Report{DryRun: options.DryRun}
+	collections := map[string]*blueprint.Collection{}
+	if collection != nil {
+		collections[collection.Blueprint.CollectionName] = collection
+	}
+	ignoredColumns := map[string]bool{}
+	err := readRows(r, format, func(row row) error {
+		report.Rows++
+		rowErrors := i.importRow(ctx, row, collection, collections, ignoredColumns, options, report)
+		if len(rowErrors) > 0 {
+			report.Failed++
+			report.Errors = append(report.Errors, rowErrors...)
+		}
+		return ctx.Err()
+	})
+	for column := range ignoredColumns {
+		report.IgnoredColumns = append(report.IgnoredColumns, column)
+	}
+	sort.Strings(report.IgnoredColumns)
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (i *Importer) importRow(ctx context.Context, row row, collection *blueprint.Collection, collections map[string]*blueprint.Collection, ignoredColumns map[string]bool, options Options, report *Report) []RowError {
+	newError := func(field string, format string, args ...any) []RowError {
+		return []RowError{{Row: row.number, Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+	if row.err != "" {
+		return newError("", "%s", row.err)
+	}
+	// Determine collection
+	if name, ok := row.values[blueprint.KeyCollection].(string); ok && name != "" {
+		if collection != nil && name != collection.Blueprint.CollectionName {
+			return newError(blueprint.KeyCollection, "row belongs to collection %s", name)
+		}
+		if _, ok := collections[name]; !ok {
+			c, err := i.collectionLoader.Get(name)
+			if err != nil {
+				return newError(blueprint.KeyCollection, "unknown collection %s", name)
+			}
+			collections[name] = c
+		}
+		collection = collections[name]
+	}
+	if collection == nil {
+		return newError(blueprint.KeyCollection, "missing collection")
+	}
+	bp := collection.Blueprint
+	// Map columns to fields
+	columns := map[string]any{}
+	for column, value := range row.values {
+		field, ok := mapColumn(bp, column)
+		if !ok {
+			ignoredColumns[column] = true
+			continue
+		}
+		columns[field.Name] = value
+	}
+	// Convert values in the order of the fields
+	var rowErrors []RowError
+	values := blueprint.Item{}
+	references := map[string][]string{}
+	for _, field := range bp.Fields {
+		value, ok := columns[field.Name]
+		if !ok {
+			continue
+		}
+		if isWrittenField(bp, field) {
+			// Set when writing the item
+			continue
+		}
+		converted, err := convertValue(field, value)
+		if errors.Is(err, errorSkipField) {
+			continue
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row.number, Field: field.Name, Message: err.Error()})
+			continue
+		}
+		if field.Type == blueprint.TypeReference {
+			references[field.Name] = converted.([]string)
+			continue
+		}
+		values[field.Name] = converted
+	}
+	// Find existing item
+	var existingItem blueprint.Item
+	if id, _ := values[blueprint.KeyUUID].(string); id != "" {
+		item, err := i.db.GetItem(collection, blueprint.KeyUUID, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return newError(blueprint.KeyUUID, "could not get item: %v", err)
+		}
+		existingItem = item
+	} else {
+		delete(values, blueprint.KeyUUID)
+	}
+	// Check values
+	for _, field := range bp.Fields {
+		value := values[field.Name]
+		if field.Type == blueprint.TypeAsset && value != nil && value != "" {
+			_, err := i.db.GetAsset(value.(string))
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row.number, Field: field.Name, Message: fmt.Sprintf("unknown asset %s", value)})
+			}
+		}
+	}
+	// Requirements depend on the stored values of updated items, missing columns keep them
+	requiredValues := maps.Clone(existingItem)
+	if requiredValues == nil {
+		requiredValues = blueprint.Item{}
+	}
+	maps.Copy(requiredValues, values)
+	for _, name := range bp.MissingRequiredValues(requiredValues) {
+		rowErrors = append(rowErrors, RowError{Row: row.number, Field: name, Message: "value is required"})
+	}
+	refIDs := map[string][]int64{}
+	for _, field := range bp.Fields {
+		refs, ok := references[field.Name]
+		if !ok {
+			continue
+		}
+		ids, err := i.resolveReferences(field, refs)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row.number, Field: field.Name, Message: err.Error()})
+			continue
+		}
+		refIDs[field.Name] = ids
+	}
+	if len(rowErrors) > 0 {
+		return rowErrors
+	}
+	if options.DryRun {
+		if existingItem == nil {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		return nil
+	}
+	// Write the item and its references in one transaction, so that a failed row leaves no changes
+	created := existingItem == nil
+	err := i.db.Transaction(func(tx *database.DB) error {
+		return i.writeRow(database.ContextWithTransaction(ctx, tx), tx, row.number, collection, existingItem, values, refIDs)
+	})
+	var rowError RowError
+	if errors.As(err, &rowError) {
+		return []RowError{rowError}
+	}
+	if err != nil {
+		return newError("", "%v", err)
+	}
+	if created {
+		report.Created++
+	} else {
+		report.Updated++
+	}
+	return nil
+}
+
+// writeRow
+// creates or updates the item of a row and sets its references. Errors of a field are returned as RowError.
+func (i *Importer) writeRow(ctx context.Context, tx *database.DB, rowNumber int, collection *blueprint.Collection, existingItem blueprint.Item, values blueprint.Item, refIDs map[string][]int64) error {
+	var item blueprint.Item
+	if existingItem == nil {
+		newItem, err := blueprint.NewItem(collection)
+		if err != nil {
+			return fmt.Errorf("could not create new item: %v", err)
+		}
+		for key, value := range values {
+			newItem[key] = value
+		}
+		err = i.writer.CreateItem(ctx, collection, newItem)
+		if err != nil {
+			return err
+		}
+		item = newItem
+	} else {
+		values[blueprint.KeyID] = existingItem[blueprint.KeyID]
+		err := i.writer.UpdateItem(ctx, collection, values)
+		if err != nil {
+			return err
+		}
+		item = values
+	}
+	// Write references
+	id, ok := item[blueprint.KeyID].(int64)
+	if !ok && len(refIDs) > 0 {
+		return errors.New("unknown id of written item")
+	}
+	for fieldName, ids := range refIDs {
+		field, _ := mapColumn(collection.Blueprint, fieldName)
+		refCollection, err := i.collectionLoader.Get(field.Reference.Collection)
+		if err != nil {
+			return RowError{Row: rowNumber, Field: fieldName, Message: fmt.Sprintf("could not get referenced collection: %v", err)}
+		}
+		err = tx.SetReferences(collection, refCollection, id, ids)
+		if err != nil {
+			return RowError{Row: rowNumber, Field: fieldName, Message: fmt.Sprintf("could not set references: %v", err)}
+		}
+	}
+	return nil
+}
+
+// isWrittenField
+// returns true if the value of the field is set when the item is written and can not be imported
+func isWrittenField(bp *blueprint.Blueprint, field blueprint.BlueprintField) bool {
+	return field.Name == blueprint.KeyCollection || field.Name == blueprint.KeyUpdatedAt || bp.IsDerivedField(field.Name) || field.IsComputed()
+}
+
+// resolveReferences
+// returns the IDs of the referenced items, which are given by uuid or slug
+func (i *Importer) resolveReferences(field blueprint.BlueprintField, refs []string) ([]int64, error) {
+	if field.Reference.MaxReferences >= 0 && len(refs) > field.Reference.MaxReferences {
+		return nil, fmt.Errorf("at most %d references are allowed", field.Reference.MaxReferences)
+	}
+	refCollection, err := i.collectionLoader.Get(field.Reference.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("could not get referenced collection: %v", err)
+	}
+	var ids []int64
+	for _, ref := range refs {
+		keys := []string{}
+		if _, err := uuid.Parse(ref); err == nil {
+			keys = append(keys, blueprint.KeyUUID)
+		}
+		for _, slugField := range refCollection.Blueprint.SlugFields() {
+			keys = append(keys, slugField.Name)
+		}
+		var item blueprint.Item
+		for _, key := range keys {
+			item, err = i.db.GetItem(refCollection, key, ref)
+			if err == nil {
+				break
+			}
+		}
+		if item == nil {
+			return nil, fmt.Errorf("unknown %s item %s", refCollection.Blueprint.CollectionName, ref)
+		}
+		id, _ := item[blueprint.KeyID].(int64)
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// readRows
+// calls fn for every row of the file
+func readRows(r io.Reader, format Format, fn func(row row) error) error {
+	switch format {
+	case FormatJSONLines:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+		number := 0
+		for scanner.Scan() {
+			number++
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			values := map[string]any{}
+			err := decoder.Decode(&values)
+			if err != nil {
+				// Report invalid lines like other row errors
+				err = fn(row{number: number, err: fmt.Sprintf("invalid json: %v", err)})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err = fn(row{number: number, values: values})
+			if err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("could not read csv header: %v", err)
+		}
+		if len(header) > 0 {
+			// Remove byte order mark that is written by some spreadsheet applications
+			header[0] = string(bytes.TrimPrefix([]byte(header[0]), []byte("\uFEFF")))
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				var parseError *csv.ParseError
+				if !errors.As(err, &parseError) {
+					return fmt.Errorf("could not read csv: %v", err)
+				}
+				err = fn(row{number: parseError.StartLine, err: parseError.Err.Error()})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			// Only valid after a successful read
+			number, _ := reader.FieldPos(0)
+			if len(record) != len(header) {
+				err = fn(row{number: number, err: fmt.Sprintf("expected %d columns, got %d", len(header), len(record))})
+			} else {
+				values := make(map[string]any, len(header))
+				for index, column := range header {
+					values[column] = record[index]
+				}
+				err = fn(row{number: number, values: values})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
@@ -0,0 +1,145 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rangidev/rangi/blueprint"
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	testBlueprint = `{
+	"collection_display_name": {"en": "Books"},
+	"collection_name": "books",
+	"fields": [
+		{"name": "pages", "display_name": "pages", "type": "int", "required": true}
+	]
+}`
+)
+
+// testWriter
+// stores items in the transaction of the row and fails for the title failTitle after the item has been stored
+type testWriter struct {
+	db        *database.DB
+	failTitle string
+}
+
+func (w *testWriter) CreateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error {
+	err := database.FromContext(ctx, w.db).CreateItem(collection, item)
+	if err != nil {
+		return err
+	}
+	if item[blueprint.KeyTitle] == w.failTitle {
+		return errors.New("writer failed")
+	}
+	return nil
+}
+
+func (w *testWriter) UpdateItem(ctx context.Context, collection *blueprint.Collection, item blueprint.Item) error {
+	return database.FromContext(ctx, w.db).UpdateItem(collection, item)
+}
+
+func newTestImporter(t *testing.T, failTitle string) (*Importer, *database.DB, *blueprint.Collection) {
+	t.Helper()
+	dir := t.TempDir()
+	blueprintsPath := filepath.Join(dir, "blueprints")
+	err := os.Mkdir(blueprintsPath, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(blueprintsPath, "books.json"), []byte(testBlueprint), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.NewSqlite3Instance(filepath.Join(dir, "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	collectionLoader := blueprint.NewCollectionLoader(blueprintsPath)
+	collections, err := collectionLoader.GetAll()
+	if err != nil {
+		t.Fatalf("could not load collections: %v", err)
+	}
+	err = db.CreateTables(collections, collectionLoader)
+	if err != nil {
+		t.Fatalf("could not create tables: %v", err)
+	}
+	collection, err := collectionLoader.Get("books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewImporter(db, collectionLoader, &testWriter{db: db, failTitle: failTitle}), db, collection
+}
+
+func storedTitles(t *testing.T, db *database.DB, collection *blueprint.Collection) []string {
+	t.Helper()
+	items, err := db.GetItems(collection, 100, 0)
+	if err != nil {
+		t.Fatalf("could not get items: %v", err)
+	}
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item[blueprint.KeyTitle].(string))
+	}
+	return titles
+}
+
+func TestImportMalformedCSVRow(t *testing.T) {
+	importer, db, collection := newTestImporter(t, "")
+	// The quote in the first field of the second row is invalid
+	input := "title,pages\nDune,412\n\"x\"y,1\nEmma,474\n"
+	report, err := importer.Import(context.Background(), strings.NewReader(input), collection, FormatCSV, Options{})
+	if err != nil {
+		t.Fatalf("could not import: %v", err)
+	}
+	if report.Created != 2 || report.Failed != 1 {
+		t.Fatalf("got %d created and %d failed rows, want 2 and 1: %v", report.Created, report.Failed, report.Errors)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 3 {
+		t.Fatalf("got errors %v, want one error in row 3", report.Errors)
+	}
+	if titles := storedTitles(t, db, collection); len(titles) != 2 {
+		t.Fatalf("got items %v, want Dune and Emma", titles)
+	}
+}
+
+func TestImportRollsBackFailedRow(t *testing.T) {
+	importer, db, collection := newTestImporter(t, "Emma")
+	input := `{"title": "Dune", "pages": 412}
+{"title": "Emma", "pages": 474}
+{"title": "Ulysses", "pages": 730}
+`
+	report, err := importer.Import(context.Background(), strings.NewReader(input), collection, FormatJSONLines, Options{})
+	if err != nil {
+		t.Fatalf("could not import: %v", err)
+	}
+	if report.Created != 2 || report.Failed != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 {
+		t.Fatalf("got report %+v, want row 2 to fail", report)
+	}
+	// The item of the failed row has been stored before the writer failed, it must have been rolled back
+	titles := storedTitles(t, db, collection)
+	if len(titles) != 2 || titles[0] == "Emma" || titles[1] == "Emma" {
+		t.Fatalf("got items %v, want Dune and Ulysses", titles)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	importer, db, collection := newTestImporter(t, "")
+	input := "title,pages\nDune,412\nEmma,\n"
+	report, err := importer.Import(context.Background(), strings.NewReader(input), collection, FormatCSV, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("could not import: %v", err)
+	}
+	if report.Created != 1 || report.Failed != 1 || report.Errors[0].Field != "pages" {
+		t.Fatalf("got report %+v, want one created row and a missing value for pages", report)
+	}
+	if titles := storedTitles(t, db, collection); len(titles) != 0 {
+		t.Fatalf("got items %v in a dry run", titles)
+	}
+}
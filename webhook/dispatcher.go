@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rangidev/rangi/database"
+)
+
+const (
+	HeaderEvent     = "X-Rangi-Event"
+	HeaderDelivery  = "X-Rangi-Delivery"
+	HeaderTimestamp = "X-Rangi-Timestamp"
+	HeaderSignature = "X-Rangi-Signature"
+
+	pollInterval   = 5 * time.Second
+	requestTimeout = 10 * time.Second
+	claimLease     = 6 * requestTimeout // Other dispatchers retry a claimed delivery after this
+	batchSize      = 20
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+)
+
+type Payload struct {
+	Webhook    string             `json:"webhook"`
+	Event      database.EventType `json:"event"`
+	Collection string             `json:"collection"`
+	Item       map[string]any     `json:"item"`
+	Time       int64              `json:"time"`
+}
+
+// Dispatcher
+// queues deliveries for content changes and sends them from the queue in the database in the background.
+// Failed deliveries are retried with exponential backoff.
+type Dispatcher struct {
+	db          *database.DB
+	webhooks    []Webhook
+	maxAttempts int
+	client      *http.Client
+	logger      *slog.Logger
+	wake        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	mutex       sync.Mutex
+	started     bool
+	stopped     bool
+}
+
+func NewDispatcher(db *database.DB, webhooks []Webhook, maxAttempts int, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		webhooks:    webhooks,
+		maxAttempts: maxAttempts,
+		client:      &http.Client{Timeout: requestTimeout},
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Webhooks
+// returns the configured webhooks
+func (d *Dispatcher) Webhooks() []Webhook {
+	return d.webhooks
+}
+
+// Enqueue
+// queues a delivery for every webhook that matches the event. The deliveries are written in the transaction of the context,
+// see database.FromContext, so that they are only sent if the change is committed.
+func (d *Dispatcher) Enqueue(ctx context.Context, event database.Event) error {
+	db := database.FromContext(ctx, d.db)
+	for _, webhook := range d.webhooks {
+		if !webhook.Matches(event) {
+			continue
+		}
+		payload, err := json.Marshal(Payload{
+			Webhook:    webhook.Name,
+			Event:      event.Type,
+			Collection: event.Collection,
+			Item:       jsonItem(event.Item),
+			Time:       event.Time,
+		})
+		if err != nil {
+			return fmt.Errorf("could not marshal payload of webhook %s: %v", webhook.Name, err)
+		}
+		err = db.EnqueueWebhookDelivery(&database.WebhookDelivery{
+			Webhook:    webhook.Name,
+			Event:      string(event.Type),
+			Collection: event.Collection,
+			Payload:    string(payload),
+		})
+		if err != nil {
+			return fmt.Errorf("could not enqueue delivery of webhook %s: %v", webhook.Name, err)
+		}
+	}
+	return nil
+}
+
+// Wake
+// processes the queue now instead of at the next poll. Register it with database.DB.AddListener, which calls it after changes have been committed.
+func (d *Dispatcher) Wake(event database.Event) {
+	// Do not block if the dispatcher is already busy
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start
+// processes the delivery queue until Stop is called. Calling it again has no effect.
+func (d *Dispatcher) Start() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.started || d.stopped {
+		return
+	}
+	d.started = true
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			d.processDue()
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// Stop
+// waits until the current delivery has finished. It returns immediately if the dispatcher has not been started
+// and can be called more than once.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mutex.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.stop)
+	}
+	started := d.started
+	d.mutex.Unlock()
+	if !started {
+		return nil
+	}
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) processDue() {
+	for {
+		deliveries, err := d.db.GetDueWebhookDeliveries(batchSize)
+		if err != nil {
+			d.logger.Error("Could not get due webhook deliveries", "error", err)
+			return
+		}
+		for index := range deliveries {
+			// Claim the delivery first, so that it is not sent twice by several instances sharing the database
+			claimed, err := d.db.ClaimWebhookDelivery(&deliveries[index], claimLease)
+			if err != nil {
+				d.logger.Error("Could not claim webhook delivery", "delivery", deliveries[index].ID, "error", err)
+				return
+			}
+			if !claimed {
+				continue
+			}
+			d.deliver(&deliveries[index])
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(delivery *database.WebhookDelivery) {
+	delivery.Attempts++
+	statusCode, err := d.send(delivery)
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	if err != nil {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.maxAttempts {
+			delivery.Status = database.WebhookDeliveryFailed
+		} else {
+			delivery.Status = database.WebhookDeliveryPending
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts)).Unix()
+		}
+		d.logger.Warn("Webhook delivery failed", "webhook", delivery.Webhook, "delivery", delivery.ID, "attempt", delivery.Attempts, "error", err)
+	} else {
+		delivery.Status = database.WebhookDeliverySucceeded
+	}
+	err = d.db.UpdateWebhookDelivery(delivery)
+	if err != nil {
+		d.logger.Error("Could not update webhook delivery", "delivery", delivery.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) send(delivery *database.WebhookDelivery) (int, error) {
+	var webhook *Webhook
+	for index := range d.webhooks {
+		if d.webhooks[index].Name == delivery.Webhook {
+			webhook = &d.webhooks[index]
+		}
+	}
+	if webhook == nil {
+		// The webhook has been removed from the configuration
+		delivery.Attempts = d.maxAttempts
+		return 0, fmt.Errorf("webhook %s is not configured anymore", delivery.Webhook)
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Rangi-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, []byte(delivery.Payload)))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign
+// returns the hex encoded HMAC-SHA256 of "<timestamp>.<payload>".
+// Including the timestamp allows receivers to reject replayed requests.
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoff(attempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// jsonItem
+// converts values that are not represented well in JSON, e. g. byte slices returned by the database driver
+func jsonItem(item map[string]any) map[string]any {
+	result := make(map[string]any, len(item))
+	for key, value := range item {
+		if b, ok := value.([]byte); ok {
+			result[key] = string(b)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
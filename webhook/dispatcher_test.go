@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rangidev/rangi/database"
+)
+
+// testReceiver
+// records the requests of webhook deliveries and answers with status
+type testReceiver struct {
+	mutex    sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	w.WriteHeader(r.status)
+}
+
+func newTestDispatcher(t *testing.T, status int, maxAttempts int) (*Dispatcher, *database.DB, *testReceiver) {
+	t.Helper()
+	db, err := database.NewSqlite3Instance(filepath.Join(t.TempDir(), "rangi.db"))
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = db.CreateWebhookDeliveryTable()
+	if err != nil {
+		t.Fatalf("could not create table: %v", err)
+	}
+	receiver := &testReceiver{status: status}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	webhooks := []Webhook{
+		{Name: "articles", URL: server.URL, Secret: "secret", Collections: []string{"articles"}},
+		{Name: "deletions", URL: server.URL, Events: []database.EventType{database.EventItemDeleted}},
+	}
+	return NewDispatcher(db, webhooks, maxAttempts, slog.New(slog.NewTextHandler(io.Discard, nil))), db, receiver
+}
+
+func getDeliveries(t *testing.T, db *database.DB) []database.WebhookDelivery {
+	t.Helper()
+	deliveries, err := db.GetWebhookDeliveries(100, 0)
+	if err != nil {
+		t.Fatalf("could not get deliveries: %v", err)
+	}
+	return deliveries
+}
+
+func TestEnqueueUsesTransaction(t *testing.T) {
+	dispatcher, db, _ := newTestDispatcher(t, http.StatusOK, 3)
+	event := database.Event{Type: database.EventItemCreated, Collection: "articles", Item: map[string]any{"id": int64(1)}, Time: 1}
+	errorRollback := errors.New("rollback")
+	err := db.Transaction(func(tx *database.DB) error {
+		err := dispatcher.Enqueue(database.ContextWithTransaction(context.Background(), tx), event)
+		if err != nil {
+			return err
+		}
+		return errorRollback
+	})
+	if !errors.Is(err, errorRollback) {
+		t.Fatalf("got error %v, want rollback", err)
+	}
+	if deliveries := getDeliveries(t, db); len(deliveries) != 0 {
+		t.Fatalf("got %d deliveries of a rolled back change, want none", len(deliveries))
+	}
+	err = db.Transaction(func(tx *database.DB) error {
+		return dispatcher.Enqueue(database.ContextWithTransaction(context.Background(), tx), event)
+	})
+	if err != nil {
+		t.Fatalf("could not enqueue: %v", err)
+	}
+	// Only the webhook of the collection matches
+	deliveries := getDeliveries(t, db)
+	if len(deliveries) != 1 || deliveries[0].Webhook != "articles" || deliveries[0].Status != database.WebhookDeliveryPending {
+		t.Fatalf("got deliveries %+v, want one pending delivery of webhook articles", deliveries)
+	}
+}
+
+func TestDeliverySigned(t *testing.T) {
+	dispatcher, db, receiver := newTestDispatcher(t, http.StatusNoContent, 3)
+	err := dispatcher.Enqueue(context.Background(), database.Event{Type: database.EventItemDeleted, Collection: "articles", Item: map[string]any{"body": []byte("text")}, Time: 1})
+	if err != nil {
+		t.Fatalf("could not enqueue: %v", err)
+	}
+	dispatcher.processDue()
+	if len(receiver.requests) != 2 {
+		t.Fatalf("got %d requests, want one for each webhook", len(receiver.requests))
+	}
+	for index, request := range receiver.requests {
+		if request.Header.Get(HeaderEvent) != string(database.EventItemDeleted) {
+			t.Errorf("got event header %q", request.Header.Get(HeaderEvent))
+		}
+		signature := request.Header.Get(HeaderSignature)
+		// Only the webhook with a secret signs its payloads
+		if signature != "" && signature != "sha256="+Sign("secret", request.Header.Get(HeaderTimestamp), []byte(receiver.bodies[index])) {
+			t.Errorf("got invalid signature %q", signature)
+		}
+	}
+	for _, delivery := range getDeliveries(t, db) {
+		if delivery.Status != database.WebhookDeliverySucceeded || delivery.Attempts != 1 {
+			t.Errorf("got delivery %+v, want one successful attempt", delivery)
+		}
+	}
+}
+
+func TestDeliveryRetried(t *testing.T) {
+	dispatcher, db, receiver := newTestDispatcher(t, http.StatusInternalServerError, 2)
+	err := dispatcher.Enqueue(context.Background(), database.Event{Type: database.EventItemUpdated, Collection: "articles", Time: 1})
+	if err != nil {
+		t.Fatalf("could not enqueue: %v", err)
+	}
+	dispatcher.processDue()
+	delivery := getDeliveries(t, db)[0]
+	if delivery.Status != database.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("got delivery %+v, want a pending delivery after one failed attempt", delivery)
+	}
+	if delay := time.Until(time.Unix(delivery.NextAttemptAt, 0)); delay < initialBackoff-time.Second {
+		t.Fatalf("next attempt in %v, want at least %v", delay, initialBackoff)
+	}
+	// The next attempt is not due yet
+	dispatcher.processDue()
+	if len(receiver.requests) != 1 {
+		t.Fatalf("got %d requests before the backoff expired, want 1", len(receiver.requests))
+	}
+	delivery.NextAttemptAt = time.Now().Unix()
+	err = db.UpdateWebhookDelivery(&delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.processDue()
+	delivery = getDeliveries(t, db)[0]
+	if delivery.Status != database.WebhookDeliveryFailed || delivery.Attempts != 2 {
+		t.Fatalf("got delivery %+v, want a failed delivery after the maximum number of attempts", delivery)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{3, 4 * initialBackoff},
+		{100, maxBackoff},
+	}
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
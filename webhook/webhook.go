@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/rangidev/rangi/database"
+)
+
+// Webhook
+// is an outgoing HTTP request that is sent whenever matching content changes
+type Webhook struct {
+	Name        string               `json:"name"`
+	URL         string               `json:"url"`
+	Secret      string               `json:"secret"`      // Used to sign payloads, so that receivers can verify their origin
+	Events      []database.EventType `json:"events"`      // Empty means all events
+	Collections []string             `json:"collections"` // Empty means all collections
+}
+
+// Load
+// reads the webhooks from a JSON file containing an array of webhooks. Returns no webhooks if filename is empty.
+func Load(filename string) ([]Webhook, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read webhooks: %v", err)
+	}
+	var webhooks []Webhook
+	err = json.Unmarshal(data, &webhooks)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal json data: %v", err)
+	}
+	var names []string
+	for _, webhook := range webhooks {
+		if webhook.Name == "" {
+			return nil, fmt.Errorf("missing webhook name")
+		}
+		if slices.Contains(names, webhook.Name) {
+			return nil, fmt.Errorf("duplicate webhook name %s", webhook.Name)
+		}
+		names = append(names, webhook.Name)
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid url for webhook %s", webhook.Name)
+		}
+		for _, event := range webhook.Events {
+			if !slices.Contains(database.AllEventTypes, event) {
+				return nil, fmt.Errorf("unknown event %s for webhook %s", event, webhook.Name)
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+// Matches
+// returns true if the webhook should be triggered by the event
+func (w *Webhook) Matches(event database.Event) bool {
+	if len(w.Events) > 0 && !slices.Contains(w.Events, event.Type) {
+		return false
+	}
+	if len(w.Collections) > 0 && !slices.Contains(w.Collections, event.Collection) {
+		return false
+	}
+	return true
+}